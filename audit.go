@@ -0,0 +1,320 @@
+package snapapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CallRecord describes one Client call, as reported to a Recorder.
+type CallRecord struct {
+	// Time is when the call started.
+	Time time.Time `json:"time"`
+	// Endpoint is the billable endpoint the call was charged to, named as in
+	// BudgetRule ("screenshot", "pdf", ...), or "" if it consumes no quota.
+	Endpoint string `json:"endpoint"`
+	// Method and Path are the HTTP request (e.g. "POST", "/v1/screenshot"),
+	// without the query string.
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Internal is set for calls the Client made on its own behalf, such as
+	// the GetUsage refreshes of a Budget or KeyPool.
+	Internal bool `json:"internal,omitempty"`
+	// ParamsHash is a hash of the request parameters with credentials,
+	// passwords, cookies and headers removed, so identical captures can be
+	// correlated without logging their content.
+	ParamsHash string `json:"params_hash,omitempty"`
+	// TargetHost is the host name of the page being captured, if any.
+	TargetHost string `json:"target_host,omitempty"`
+	// Status is the final HTTP status code, or 0 if no response was received.
+	Status int `json:"status"`
+	// Error is the error code (APIError.Code) or message of a failed call.
+	Error string `json:"error,omitempty"`
+	// Bytes is the size of the response body.
+	Bytes int `json:"bytes"`
+	// Latency is the wall-clock duration of the call, including retries.
+	Latency time.Duration `json:"latency_ns"`
+	// Attempts is the number of HTTP requests sent (0 if the call was
+	// refused locally, e.g. by a Budget).
+	Attempts int `json:"attempts"`
+	// Tags are the caller tags attached to the context with WithTag.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Recorder receives a CallRecord for every call made by a Client. Record is
+// called synchronously after each call completes, so implementations should
+// be fast and must be safe for concurrent use. Errors returned by Record are
+// ignored by the Client.
+type Recorder interface {
+	Record(ctx context.Context, rec CallRecord) error
+}
+
+// RecorderFunc adapts an ordinary function to the Recorder interface.
+type RecorderFunc func(ctx context.Context, rec CallRecord) error
+
+// Record calls f(ctx, rec).
+func (f RecorderFunc) Record(ctx context.Context, rec CallRecord) error {
+	return f(ctx, rec)
+}
+
+// internalCallCtx is the context key for withInternalCall.
+type internalCallCtx struct{}
+
+// withInternalCall returns a context whose calls are recorded as Internal.
+func withInternalCall(ctx context.Context) context.Context {
+	return context.WithValue(ctx, internalCallCtx{}, true)
+}
+
+// record builds a CallRecord for a completed call and hands it to the
+// client's Recorder.
+func (c *Client) record(ctx context.Context, endpoint, method, path string, body interface{}, data []byte, err error, stats callStats, start time.Time) {
+	internal, _ := ctx.Value(internalCallCtx{}).(bool)
+	rec := CallRecord{
+		Time:     start.UTC(),
+		Endpoint: endpoint,
+		Method:   method,
+		Path:     path,
+		Internal: internal,
+		Status:   stats.status,
+		Bytes:    len(data),
+		Latency:  time.Since(start),
+		Attempts: stats.attempts,
+		Tags:     TagsFromContext(ctx),
+	}
+	if i := strings.IndexByte(rec.Path, '?'); i >= 0 {
+		// Query strings may carry credentials (access_key) or storage keys.
+		rec.Path = rec.Path[:i]
+	}
+	if body != nil {
+		rec.ParamsHash, rec.TargetHost = sanitizedParams(body)
+	}
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			rec.Error = apiErr.Code
+		} else {
+			rec.Error = err.Error()
+		}
+	}
+	_ = c.recorder.Record(ctx, rec)
+}
+
+// sensitiveParams are request fields that never contribute to ParamsHash.
+var sensitiveParams = map[string]bool{
+	"access_key":   true,
+	"apiKey":       true,
+	"api_key":      true,
+	"password":     true,
+	"cookies":      true,
+	"headers":      true,
+	"httpAuth":     true,
+	"extraHeaders": true,
+	"secret":       true,
+}
+
+// sanitizedParams returns the hash of body with sensitive fields removed,
+// and the host of its "url" field.
+func sanitizedParams(body interface{}) (hash, host string) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return "", ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return "", ""
+	}
+	v = stripSensitive(v)
+	if m, ok := v.(map[string]interface{}); ok {
+		if s, ok := m["url"].(string); ok {
+			if u, err := url.Parse(s); err == nil {
+				host = u.Hostname()
+			}
+		}
+	}
+	return paramsHash(v), host
+}
+
+func stripSensitive(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if sensitiveParams[k] {
+				delete(t, k)
+				continue
+			}
+			t[k] = stripSensitive(child)
+		}
+	case []interface{}:
+		for i, child := range t {
+			t[i] = stripSensitive(child)
+		}
+	}
+	return v
+}
+
+// ReadCallRecords decodes CallRecords from JSONL, as written by
+// JSONLRecorder, e.g. to reconcile local usage against GetUsage.
+func ReadCallRecords(r io.Reader) ([]CallRecord, error) {
+	var out []CallRecord
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(strings.TrimSpace(sc.Text())) == 0 {
+			continue
+		}
+		var rec CallRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return out, fmt.Errorf("snapapi: decode call record on line %d: %w", line, err)
+		}
+		out = append(out, rec)
+	}
+	return out, sc.Err()
+}
+
+// ---------------------------------------------------------------------------
+// JSONLRecorder
+// ---------------------------------------------------------------------------
+
+// JSONLRecorderOptions configures a JSONLRecorder.
+type JSONLRecorderOptions struct {
+	// Dir is the directory log files are written to. Required.
+	Dir string
+	// Prefix is the file name prefix. Default: "snapapi-audit".
+	Prefix string
+	// MaxSize rotates to a new file once the current one reaches this many
+	// bytes. Zero means no size limit.
+	MaxSize int64
+	// Daily starts a new file each UTC day and puts the date in the file
+	// name (prefix-2026-03-23.jsonl).
+	Daily bool
+}
+
+// JSONLRecorder is a Recorder that appends one JSON object per call to a
+// log file, rotating by size and/or day. Rotated files are named
+// prefix[-DATE][.N].jsonl and are never deleted by the recorder.
+type JSONLRecorder struct {
+	opts JSONLRecorderOptions
+
+	mu   sync.Mutex
+	f    *os.File
+	day  string
+	seq  int
+	size int64
+	now  func() time.Time
+}
+
+// NewJSONLRecorder returns a JSONLRecorder. Files are opened lazily on the
+// first record; call Close when done.
+//
+//	rec, err := snapapi.NewJSONLRecorder(snapapi.JSONLRecorderOptions{
+//	    Dir:     "/var/log/snapapi",
+//	    MaxSize: 100 << 20,
+//	    Daily:   true,
+//	})
+//	client := snapapi.New("sk_...", snapapi.WithRecorder(rec))
+//	defer rec.Close()
+func NewJSONLRecorder(opts JSONLRecorderOptions) (*JSONLRecorder, error) {
+	if opts.Dir == "" {
+		return nil, &APIError{Code: ErrInvalidParams, Message: "Dir is required", StatusCode: 400}
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = "snapapi-audit"
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("snapapi: create audit dir: %w", err)
+	}
+	return &JSONLRecorder{opts: opts, now: time.Now}, nil
+}
+
+// Record appends rec to the current log file, rotating first if needed.
+func (j *JSONLRecorder) Record(_ context.Context, rec CallRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("snapapi: encode call record: %w", err)
+	}
+	line = append(line, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	day := ""
+	if j.opts.Daily {
+		day = j.now().UTC().Format("2006-01-02")
+	}
+	if j.f == nil || day != j.day {
+		if err := j.open(day, 0); err != nil {
+			return err
+		}
+	} else if j.opts.MaxSize > 0 && j.size > 0 && j.size+int64(len(line)) > j.opts.MaxSize {
+		if err := j.open(day, j.seq+1); err != nil {
+			return err
+		}
+	}
+	n, err := j.f.Write(line)
+	j.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("snapapi: write call record: %w", err)
+	}
+	return nil
+}
+
+// Close closes the current log file.
+func (j *JSONLRecorder) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return nil
+	}
+	err := j.f.Close()
+	j.f = nil
+	return err
+}
+
+// open switches to the first file for day at or after sequence seq that is
+// not already full. j.mu must be held.
+func (j *JSONLRecorder) open(day string, seq int) error {
+	if j.f != nil {
+		j.f.Close()
+		j.f = nil
+	}
+	for ; ; seq++ {
+		name := j.fileName(day, seq)
+		info, err := os.Stat(name)
+		if errors.Is(err, fs.ErrNotExist) || (err == nil && (j.opts.MaxSize <= 0 || info.Size() < j.opts.MaxSize)) {
+			f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return fmt.Errorf("snapapi: open audit log: %w", err)
+			}
+			if info != nil {
+				j.size = info.Size()
+			} else {
+				j.size = 0
+			}
+			j.f, j.day, j.seq = f, day, seq
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("snapapi: stat audit log: %w", err)
+		}
+	}
+}
+
+func (j *JSONLRecorder) fileName(day string, seq int) string {
+	name := j.opts.Prefix
+	if day != "" {
+		name += "-" + day
+	}
+	if seq > 0 {
+		name += fmt.Sprintf(".%d", seq)
+	}
+	return filepath.Join(j.opts.Dir, name+".jsonl")
+}
@@ -0,0 +1,298 @@
+package snapapi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BatchItem is one job in a batch. Params is a ScreenshotParams, PDFParams,
+// VideoParams, ScrapeParams or ExtractParams (or a pointer to one).
+type BatchItem struct {
+	// ID is an optional caller label echoed in the BatchResult.
+	ID string
+	// Params selects the endpoint and its parameters.
+	Params interface{}
+}
+
+// BatchResult is the outcome of one BatchItem.
+type BatchResult struct {
+	// Index is the item's position in the input slice.
+	Index int
+	// ID echoes BatchItem.ID.
+	ID string
+	// Kind is "screenshot", "pdf", "video", "scrape" or "extract".
+	Kind string
+	// Data holds the binary output of screenshot, PDF and video jobs.
+	Data []byte
+	// Scrape holds the result of scrape jobs.
+	Scrape *ScrapeResult
+	// Extract holds the result of extract jobs.
+	Extract *ExtractResult
+	// Err is the item's error, if it failed.
+	Err error
+	// Skipped reports that the item was not run (or was abandoned) because
+	// the batch stopped early, either after an error with StopOnError or
+	// because ctx was cancelled.
+	Skipped bool
+	// Duration is how long the item took.
+	Duration time.Duration
+}
+
+// BatchProgress is passed to BatchOptions.OnProgress after each item.
+type BatchProgress struct {
+	// Done is the number of items finished so far (including this one).
+	Done int
+	// Failed is the number of items that failed so far.
+	Failed int
+	// Total is the number of items in the batch.
+	Total int
+	// Last is the item that just finished.
+	Last BatchResult
+}
+
+// BatchOptions configures Batch and BatchStream.
+type BatchOptions struct {
+	// Concurrency is the maximum number of items in flight. Default: 4.
+	Concurrency int
+	// StopOnError cancels the remaining items after the first failure. They
+	// are reported with Skipped set.
+	StopOnError bool
+	// PerItemTimeout bounds each item, including retries. Zero means no
+	// per-item timeout.
+	PerItemTimeout time.Duration
+	// OnProgress, if set, is called after each item finishes. Calls are
+	// serialised, so the callback needn't be safe for concurrent use.
+	OnProgress func(BatchProgress)
+}
+
+// BatchError is returned by Batch when one or more items fail. It unwraps
+// to every item error, so errors.Is(err, snapapi.ErrRateLimit) reports
+// whether any item was rate limited.
+type BatchError struct {
+	// Failures holds the failed results, in input order.
+	Failures []BatchResult
+	// Total is the number of items in the batch.
+	Total int
+}
+
+// Error implements the error interface.
+func (e *BatchError) Error() string {
+	if len(e.Failures) == 0 {
+		return "snapapi: batch failed"
+	}
+	first := e.Failures[0]
+	return fmt.Sprintf("snapapi: %d of %d batch items failed; item %d: %v", len(e.Failures), e.Total, first.Index, first.Err)
+}
+
+// Unwrap returns the individual item errors.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// Batch runs items with bounded concurrency and returns one result per item,
+// in input order. If any item fails, the error is a *BatchError; results
+// for the other items are still returned.
+//
+//	results, err := client.Batch(ctx, []snapapi.BatchItem{
+//	    {ID: "home", Params: snapapi.ScreenshotParams{URL: "https://example.com"}},
+//	    {ID: "docs", Params: snapapi.PDFParams{URL: "https://example.com/docs"}},
+//	    {ID: "text", Params: snapapi.ScrapeParams{URL: "https://example.com", Format: "text"}},
+//	}, snapapi.BatchOptions{Concurrency: 8, PerItemTimeout: time.Minute})
+//	if errors.Is(err, snapapi.ErrRateLimit) {
+//	    // at least one item was rate limited
+//	}
+//	for _, r := range results {
+//	    if r.Err == nil {
+//	        fmt.Println(r.ID, len(r.Data))
+//	    }
+//	}
+func (c *Client) Batch(ctx context.Context, items []BatchItem, opts BatchOptions) ([]BatchResult, error) {
+	results := make([]BatchResult, len(items))
+	for r := range c.BatchStream(ctx, items, opts) {
+		results[r.Index] = r
+	}
+	var failures []BatchResult
+	for _, r := range results {
+		if r.Err != nil && !r.Skipped {
+			failures = append(failures, r)
+		}
+	}
+	if len(failures) > 0 {
+		return results, &BatchError{Failures: failures, Total: len(items)}
+	}
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// BatchStream runs items like Batch but delivers each result on the returned
+// channel as soon as it finishes, in completion order. The channel is
+// closed once every item has been reported; callers must drain it.
+//
+//	for r := range client.BatchStream(ctx, items, snapapi.BatchOptions{}) {
+//	    if r.Err != nil {
+//	        log.Printf("%s: %v", r.ID, r.Err)
+//	    }
+//	}
+func (c *Client) BatchStream(ctx context.Context, items []BatchItem, opts BatchOptions) <-chan BatchResult {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	out := make(chan BatchResult)
+	finished := make(chan BatchResult)
+	ctx, cancel := context.WithCancel(ctx)
+
+	indices := make(chan int)
+	go func() {
+		defer close(indices)
+		for i := range items {
+			select {
+			case indices <- i:
+			case <-ctx.Done():
+				for ; i < len(items); i++ {
+					finished <- BatchResult{Index: i, ID: items[i].ID, Kind: batchKind(items[i].Params), Err: ctx.Err(), Skipped: true}
+				}
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Concurrency && w < len(items); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				finished <- c.runBatchItem(ctx, i, items[i], opts.PerItemTimeout)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	go func() {
+		defer close(out)
+		defer cancel()
+		progress := BatchProgress{Total: len(items)}
+		stopped := false
+		for r := range finished {
+			if r.Err != nil && !r.Skipped {
+				progress.Failed++
+				if opts.StopOnError && !stopped {
+					stopped = true
+					cancel()
+				}
+			}
+			progress.Done++
+			progress.Last = r
+			if opts.OnProgress != nil {
+				opts.OnProgress(progress)
+			}
+			out <- r
+		}
+	}()
+	return out
+}
+
+// runBatchItem executes one batch item.
+func (c *Client) runBatchItem(ctx context.Context, i int, item BatchItem, timeout time.Duration) (r BatchResult) {
+	r = BatchResult{Index: i, ID: item.ID, Kind: batchKind(item.Params)}
+	if err := ctx.Err(); err != nil {
+		r.Err, r.Skipped = err, true
+		return r
+	}
+	batchCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	defer func() {
+		r.Duration = time.Since(start)
+		if r.Err != nil && batchCtx.Err() != nil {
+			// Abandoned mid-flight because the batch was stopped.
+			r.Err, r.Skipped = batchCtx.Err(), true
+		}
+	}()
+	switch p := item.Params.(type) {
+	case *ScreenshotParams:
+		if p == nil {
+			r.Err = nilBatchParams(item.Params)
+			break
+		}
+		r.Data, r.Err = c.Screenshot(ctx, *p)
+	case ScreenshotParams:
+		r.Data, r.Err = c.Screenshot(ctx, p)
+	case *PDFParams:
+		if p == nil {
+			r.Err = nilBatchParams(item.Params)
+			break
+		}
+		r.Data, r.Err = c.PDF(ctx, *p)
+	case PDFParams:
+		r.Data, r.Err = c.PDF(ctx, p)
+	case *VideoParams:
+		if p == nil {
+			r.Err = nilBatchParams(item.Params)
+			break
+		}
+		r.Data, r.Err = c.Video(ctx, *p)
+	case VideoParams:
+		r.Data, r.Err = c.Video(ctx, p)
+	case *ScrapeParams:
+		if p == nil {
+			r.Err = nilBatchParams(item.Params)
+			break
+		}
+		r.Scrape, r.Err = c.Scrape(ctx, *p)
+	case ScrapeParams:
+		r.Scrape, r.Err = c.Scrape(ctx, p)
+	case *ExtractParams:
+		if p == nil {
+			r.Err = nilBatchParams(item.Params)
+			break
+		}
+		r.Extract, r.Err = c.Extract(ctx, *p)
+	case ExtractParams:
+		r.Extract, r.Err = c.Extract(ctx, p)
+	default:
+		r.Err = &APIError{
+			Code:       ErrInvalidParams,
+			Message:    fmt.Sprintf("unsupported batch params type %T", item.Params),
+			StatusCode: 400,
+		}
+	}
+	return r
+}
+
+// nilBatchParams is the error for a nil params pointer.
+func nilBatchParams(params interface{}) error {
+	return &APIError{Code: ErrInvalidParams, Message: fmt.Sprintf("batch params %T is nil", params), StatusCode: 400}
+}
+
+// batchKind names the endpoint a batch params value targets.
+func batchKind(params interface{}) string {
+	switch params.(type) {
+	case ScreenshotParams, *ScreenshotParams:
+		return "screenshot"
+	case PDFParams, *PDFParams:
+		return "pdf"
+	case VideoParams, *VideoParams:
+		return "video"
+	case ScrapeParams, *ScrapeParams:
+		return "scrape"
+	case ExtractParams, *ExtractParams:
+		return "extract"
+	}
+	return ""
+}
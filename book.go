@@ -0,0 +1,436 @@
+package snapapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BookFormat is the output format of a Book.
+type BookFormat string
+
+// Book formats.
+const (
+	// BookEPUB is an EPUB 3 file with one XHTML document per chapter and
+	// the chapters' images embedded.
+	BookEPUB BookFormat = "epub"
+	// BookHTML is a single HTML page with images embedded as data URLs.
+	BookHTML BookFormat = "html"
+	// BookMarkdown is a single Markdown file. Images keep their remote
+	// URLs.
+	BookMarkdown BookFormat = "markdown"
+)
+
+// BookOptions configures BuildBook.
+type BookOptions struct {
+	// Format is the output format. Default: BookEPUB.
+	Format BookFormat
+	// Title is the book title. Default: the title of the first chapter.
+	Title string
+	// Author is recorded as the book's creator.
+	Author string
+	// Language is the book's language code. Default: the language detected
+	// in the first chapter, or "en".
+	Language string
+	// Identifier is the EPUB's unique identifier. Default: a UUID URN
+	// derived from the chapter URLs, so rebuilding the same book keeps it.
+	Identifier string
+	// Params is used for every Extract call, with URL and Format set per
+	// chapter. Use it to pass Selector, WaitForSelector or Headers.
+	Params ExtractParams
+	// Concurrency is the number of chapters extracted at once. Default: 4.
+	Concurrency int
+	// SkipImages leaves images out of EPUB and HTML books (their alt text
+	// is kept) instead of downloading them.
+	SkipImages bool
+	// MaxImageSize is the largest image downloaded, in bytes. Larger images
+	// are left out and listed in MissingImages. Default: 10 MB.
+	MaxImageSize int64
+}
+
+// Book is a set of extracted pages, ready to be written as an EPUB,
+// HTML or Markdown book with WriteTo.
+type Book struct {
+	Title      string
+	Author     string
+	Language   string
+	Identifier string
+	Format     BookFormat
+	// Modified is when the book was built.
+	Modified time.Time
+	// Chapters are in the order of the URLs passed to BuildBook.
+	Chapters []BookChapter
+	// MissingImages lists the image URLs that could not be downloaded.
+	// They are left out of the book.
+	MissingImages []string
+
+	images map[string]*bookImage // by absolute URL
+}
+
+// BookChapter is one extracted page.
+type BookChapter struct {
+	// Title is the page's first top-level heading, or its URL.
+	Title string
+	// URL is the final URL of the page.
+	URL string
+	// Content is the extracted page: HTML for EPUB and HTML books,
+	// Markdown (with absolute links) for Markdown books.
+	Content string
+}
+
+type bookImage struct {
+	name      string // file name inside the EPUB
+	mediaType string
+	data      []byte
+}
+
+// bookImageTypes are the image types EPUB readers must support.
+var bookImageTypes = map[string]string{
+	"image/png": ".png", "image/jpeg": ".jpg", "image/gif": ".gif", "image/webp": ".webp",
+}
+
+// BuildBook extracts each URL as a chapter and returns the book; write it
+// with WriteTo. Chapters are extracted concurrently but keep the order of
+// urls. Links from one chapter to another are rewritten to point inside
+// the book. BuildBook fails if any chapter cannot be extracted.
+//
+//	book, err := client.BuildBook(ctx, []string{
+//	    "https://docs.example.com/intro",
+//	    "https://docs.example.com/install",
+//	    "https://docs.example.com/faq",
+//	}, snapapi.BookOptions{Title: "Example Docs", Author: "Example Inc."})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	f, _ := os.Create("docs.epub")
+//	defer f.Close()
+//	_, err = book.WriteTo(f)
+func (c *Client) BuildBook(ctx context.Context, urls []string, opts BookOptions) (*Book, error) {
+	if len(urls) == 0 {
+		return nil, &APIError{Code: ErrInvalidParams, Message: "at least one URL is required", StatusCode: 400}
+	}
+	switch opts.Format {
+	case "":
+		opts.Format = BookEPUB
+	case BookEPUB, BookHTML, BookMarkdown:
+	default:
+		return nil, &APIError{Code: ErrInvalidParams, Message: fmt.Sprintf("unknown book format %q", opts.Format), StatusCode: 400}
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if opts.MaxImageSize <= 0 {
+		opts.MaxImageSize = defaultMaxImageSize
+	}
+	format := "html"
+	if opts.Format == BookMarkdown {
+		format = "markdown"
+	}
+
+	book := &Book{
+		Title:      opts.Title,
+		Author:     opts.Author,
+		Language:   opts.Language,
+		Identifier: opts.Identifier,
+		Format:     opts.Format,
+		Modified:   time.Now().UTC().Truncate(time.Second),
+		Chapters:   make([]BookChapter, len(urls)),
+		images:     make(map[string]*bookImage),
+	}
+	stats := make([]DocumentStats, len(urls))
+	errs := make([]error, len(urls))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.Concurrency)
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			p := opts.Params
+			p.URL, p.Format = u, format
+			res, err := c.Extract(ctx, p)
+			if err != nil {
+				errs[i] = fmt.Errorf("snapapi: chapter %d (%s): %w", i+1, u, err)
+				return
+			}
+			if res.URL == "" {
+				res.URL = u
+			}
+			ch := BookChapter{Title: chapterTitle(res.Stats.Headings), URL: res.URL, Content: res.Content}
+			if ch.Title == "" {
+				ch.Title = res.URL
+			}
+			if format == "markdown" {
+				ch.Content, _ = c.ProcessMarkdown(ctx, res, MarkdownOptions{AbsoluteLinks: true})
+			}
+			book.Chapters[i], stats[i] = ch, res.Stats
+		}(i, u)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if book.Title == "" {
+		book.Title = book.Chapters[0].Title
+	}
+	if book.Language == "" {
+		book.Language = stats[0].Language
+		if book.Language == "" {
+			book.Language = "en"
+		}
+	}
+	if book.Identifier == "" {
+		sum := sha256.Sum256([]byte(strings.Join(urls, "\n")))
+		sum[6] = sum[6]&0x0f | 0x50 // version 5 style
+		sum[8] = sum[8]&0x3f | 0x80
+		book.Identifier = fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+	}
+	if format == "html" && !opts.SkipImages {
+		c.downloadBookImages(ctx, book, opts.Concurrency, opts.MaxImageSize)
+	}
+	return book, nil
+}
+
+// chapterTitle returns the first level-1 heading, or the first heading.
+func chapterTitle(headings []DocumentHeading) string {
+	for _, h := range headings {
+		if h.Level == 1 {
+			return h.Text
+		}
+	}
+	if len(headings) > 0 {
+		return headings[0].Text
+	}
+	return ""
+}
+
+// downloadBookImages fetches the images of all chapters once each, up to
+// maxSize bytes per image.
+func (c *Client) downloadBookImages(ctx context.Context, book *Book, concurrency int, maxSize int64) {
+	var srcs []string
+	for _, ch := range book.Chapters {
+		base, err := url.Parse(ch.URL)
+		if err != nil {
+			continue
+		}
+		for _, img := range parseHTML(ch.Content).findAll("img") {
+			src := absoluteRef(base, strings.TrimSpace(img.attr("src")))
+			if _, ok := book.images[src]; ok || !(strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")) {
+				continue
+			}
+			book.images[src] = nil
+			srcs = append(srcs, src)
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i, src := range srcs {
+		wg.Add(1)
+		go func(i int, src string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			data, err := c.downloadLimited(ctx, src, maxSize)
+			var img *bookImage
+			if err == nil {
+				mediaType := http.DetectContentType(data)
+				if ext, ok := bookImageTypes[mediaType]; ok {
+					img = &bookImage{name: fmt.Sprintf("image-%03d%s", i+1, ext), mediaType: mediaType, data: data}
+				}
+			}
+			mu.Lock()
+			book.images[src] = img
+			mu.Unlock()
+		}(i, src)
+	}
+	wg.Wait()
+	for _, src := range srcs {
+		if book.images[src] == nil {
+			book.MissingImages = append(book.MissingImages, src)
+		}
+	}
+}
+
+// WriteTo writes the book in its Format.
+func (b *Book) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	var err error
+	switch b.Format {
+	case BookHTML:
+		err = b.writeHTML(cw)
+	case BookMarkdown:
+		err = b.writeMarkdown(cw)
+	default:
+		err = b.writeEPUB(cw)
+	}
+	return cw.n, err
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// chapterIndex maps the URLs of the chapters, without fragments, to their
+// index, for rewriting links between chapters.
+func (b *Book) chapterIndex() map[string]int {
+	m := make(map[string]int, len(b.Chapters))
+	for i, ch := range b.Chapters {
+		m[stripFragment(ch.URL)] = i
+	}
+	return m
+}
+
+func stripFragment(u string) string {
+	if i := strings.IndexByte(u, '#'); i >= 0 {
+		return u[:i]
+	}
+	return u
+}
+
+// writeMarkdown writes a title, a table of contents linking to each
+// chapter, and the chapters separated by rules.
+func (b *Book) writeMarkdown(w io.Writer) error {
+	// Give every chapter a top-level heading, then find the anchor each
+	// one gets in the combined document.
+	contents := make([]string, len(b.Chapters))
+	h1s := make([]int, len(b.Chapters))
+	for i, ch := range b.Chapters {
+		contents[i] = strings.TrimSpace(ch.Content)
+		for _, h := range ComputeDocumentStats(contents[i], "markdown").Headings {
+			if h.Level == 1 {
+				h1s[i]++
+			}
+		}
+		if h1s[i] == 0 {
+			contents[i] = "# " + ch.Title + "\n\n" + contents[i]
+			h1s[i] = 1
+		}
+	}
+	head := "# " + b.Title + "\n\n"
+	if b.Author != "" {
+		head += "*" + b.Author + "*\n\n"
+	}
+	head += "## Contents\n\n"
+	var h1Anchors []string
+	for _, h := range ComputeDocumentStats(head+strings.Join(contents, "\n\n"), "markdown").Headings {
+		if h.Level == 1 {
+			h1Anchors = append(h1Anchors, h.Anchor)
+		}
+	}
+	anchors := make([]string, len(b.Chapters))
+	next := 1 // h1Anchors[0] is the book title
+	for i := range b.Chapters {
+		if next < len(h1Anchors) {
+			anchors[i] = h1Anchors[next]
+		}
+		next += h1s[i]
+	}
+
+	index := b.chapterIndex()
+	var out strings.Builder
+	out.WriteString(head)
+	for i, ch := range b.Chapters {
+		fmt.Fprintf(&out, "%d. [%s](#%s)\n", i+1, strings.ReplaceAll(ch.Title, "]", `\]`), anchors[i])
+	}
+	for i, content := range contents {
+		out.WriteString("\n---\n\n")
+		out.WriteString(rewriteMarkdownURLs(content, func(ref string, image bool) string {
+			if j, ok := index[stripFragment(ref)]; ok && !image {
+				if k := strings.IndexByte(ref, '#'); k >= 0 && j == i {
+					return ref[k:]
+				}
+				return "#" + anchors[j]
+			}
+			return ref
+		}))
+		out.WriteString("\n")
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// bookPage is a chapter rendered as XHTML.
+type bookPage struct {
+	Title    string
+	ID       string // chapter-001
+	Body     template.HTML
+	Sections []DocumentHeading // second-level headings, for the contents
+}
+
+// renderChapters converts the chapters to XHTML. link resolves a link to
+// chapter j with the given fragment id (already prefixed), and image
+// returns the src for a downloaded image. idPrefix returns the prefix for
+// the ids of chapter i.
+func (b *Book) renderChapters(idPrefix func(i int) string, link func(j int, id string) string, image func(img *bookImage) string) []bookPage {
+	index := b.chapterIndex()
+	pages := make([]bookPage, len(b.Chapters))
+	for i, ch := range b.Chapters {
+		base, _ := url.Parse(ch.URL)
+		if base == nil {
+			base = &url.URL{}
+		}
+		x := &xhtmlWriter{
+			prefix: idPrefix(i),
+			href: func(href string) string {
+				if strings.HasPrefix(href, "#") {
+					return "#" + idPrefix(i) + xmlID(href[1:])
+				}
+				abs := absoluteRef(base, href)
+				if j, ok := index[stripFragment(abs)]; ok {
+					id := ""
+					if k := strings.IndexByte(abs, '#'); k >= 0 {
+						id = idPrefix(j) + xmlID(abs[k+1:])
+					}
+					return link(j, id)
+				}
+				return abs
+			},
+			src: func(src string) (string, bool) {
+				img := b.images[absoluteRef(base, strings.TrimSpace(src))]
+				if img == nil {
+					return "", false
+				}
+				return image(img), true
+			},
+		}
+		body, headings := x.render(parseHTML(ch.Content))
+		hasH1 := false
+		var sections []DocumentHeading
+		for _, h := range headings {
+			hasH1 = hasH1 || h.Level == 1
+			if h.Level == 2 {
+				sections = append(sections, h)
+			}
+		}
+		if !hasH1 {
+			body = "<h1>" + xmlEscape(ch.Title) + "</h1>\n" + body
+		}
+		pages[i] = bookPage{
+			Title:    ch.Title,
+			ID:       fmt.Sprintf("chapter-%03d", i+1),
+			Body:     template.HTML(body),
+			Sections: sections,
+		}
+	}
+	return pages
+}
@@ -0,0 +1,435 @@
+package snapapi
+
+import (
+	"archive/zip"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"html"
+	"html/template"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// xhtmlElements are the elements kept when converting extracted HTML to
+// XHTML. Other elements are unwrapped, except xhtmlDropped ones, which are
+// removed with their content.
+var xhtmlElements = map[string]bool{
+	"a": true, "abbr": true, "address": true, "article": true, "aside": true,
+	"b": true, "blockquote": true, "br": true, "caption": true, "cite": true,
+	"code": true, "col": true, "colgroup": true, "dd": true, "del": true,
+	"details": true, "dfn": true, "div": true, "dl": true, "dt": true,
+	"em": true, "figcaption": true, "figure": true, "footer": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "i": true, "img": true, "ins": true,
+	"kbd": true, "li": true, "main": true, "mark": true, "ol": true,
+	"p": true, "pre": true, "q": true, "s": true, "samp": true,
+	"section": true, "small": true, "span": true, "strong": true, "sub": true,
+	"summary": true, "sup": true, "table": true, "tbody": true, "td": true,
+	"tfoot": true, "th": true, "thead": true, "time": true, "tr": true,
+	"u": true, "ul": true, "var": true,
+}
+
+var xhtmlDropped = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true,
+	"template": true, "iframe": true, "object": true, "embed": true,
+	"svg": true, "math": true, "canvas": true, "video": true, "audio": true,
+	"form": true, "input": true, "button": true, "select": true,
+	"textarea": true, "title": true,
+}
+
+// xhtmlAttrs are the attributes kept, per element ("*" for all).
+var xhtmlAttrs = map[string]map[string]bool{
+	"*":    {"title": true, "lang": true},
+	"a":    {"href": true},
+	"img":  {"alt": true},
+	"td":   {"colspan": true, "rowspan": true},
+	"th":   {"colspan": true, "rowspan": true},
+	"ol":   {"start": true, "reversed": true},
+	"time": {"datetime": true},
+}
+
+// xhtmlWriter converts parsed HTML to well-formed XHTML, keeping a safe
+// subset of elements and attributes. Links other than http, https, mailto
+// and relative ones are unwrapped, and images other than http and https
+// are dropped, before href and src are called.
+type xhtmlWriter struct {
+	b        strings.Builder
+	prefix   string                      // prepended to every id
+	href     func(string) string         // rewrites safe link targets
+	src      func(string) (string, bool) // safe image sources; false drops the image
+	ids      map[string]bool
+	headings []DocumentHeading
+}
+
+// render returns the XHTML of doc's body and its h1-h3 headings, each of
+// which is given an id.
+func (x *xhtmlWriter) render(doc *htmlNode) (string, []DocumentHeading) {
+	x.ids = make(map[string]bool)
+	root := doc
+	if body := doc.find("body"); body != nil {
+		root = body
+	}
+	for _, c := range root.children {
+		x.node(c)
+	}
+	return strings.TrimSpace(x.b.String()), x.headings
+}
+
+func (x *xhtmlWriter) node(n *htmlNode) {
+	switch {
+	case n.tag == "":
+		x.b.WriteString(xmlEscape(n.text))
+		return
+	case xhtmlDropped[n.tag]:
+		return
+	case !xhtmlElements[n.tag],
+		n.tag == "a" && n.hasAttr("href") && !safeURL(n.attr("href"), "http", "https", "mailto"):
+		for _, c := range n.children {
+			x.node(c)
+		}
+		return
+	case n.tag == "img":
+		src, ok := "", safeURL(n.attr("src"), "http", "https")
+		if ok {
+			src, ok = x.src(n.attr("src"))
+		}
+		if !ok {
+			x.b.WriteString(xmlEscape(n.attr("alt")))
+			return
+		}
+		fmt.Fprintf(&x.b, `<img src="%s" alt="%s"`, xmlEscape(src), xmlEscape(n.attr("alt")))
+		x.attrs(n, "alt")
+		x.b.WriteString("/>")
+		return
+	}
+
+	x.b.WriteString("<" + n.tag)
+	id := n.attr("id")
+	level := 0
+	if len(n.tag) == 2 && n.tag[0] == 'h' && n.tag[1] >= '1' && n.tag[1] <= '3' {
+		level = int(n.tag[1] - '0')
+		if id == "" {
+			id = slugify(n.innerText())
+		}
+	}
+	if id != "" {
+		id = x.id(id)
+		fmt.Fprintf(&x.b, ` id="%s"`, id)
+	}
+	if level > 0 {
+		x.headings = append(x.headings, DocumentHeading{Level: level, Text: n.innerText(), Anchor: id})
+	}
+	if n.tag == "a" && n.hasAttr("href") {
+		fmt.Fprintf(&x.b, ` href="%s"`, xmlEscape(x.href(strings.TrimSpace(n.attr("href")))))
+	}
+	x.attrs(n, "href")
+	if htmlVoid[n.tag] {
+		x.b.WriteString("/>")
+		return
+	}
+	x.b.WriteString(">")
+	for _, c := range n.children {
+		x.node(c)
+	}
+	x.b.WriteString("</" + n.tag + ">")
+}
+
+// safeURL reports whether ref is relative or uses one of schemes.
+// References that do not parse are not safe: browsers ignore the control
+// characters that make url.Parse fail, as in "java\tscript:".
+func safeURL(ref string, schemes ...string) bool {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" {
+		return true
+	}
+	for _, s := range schemes {
+		if u.Scheme == s {
+			return true
+		}
+	}
+	return false
+}
+
+// attrs writes n's allowed attributes other than those handled by the
+// caller.
+func (x *xhtmlWriter) attrs(n *htmlNode, skip string) {
+	for _, a := range n.attrs {
+		if a.name == skip || !(xhtmlAttrs["*"][a.name] || xhtmlAttrs[n.tag][a.name]) {
+			continue
+		}
+		fmt.Fprintf(&x.b, ` %s="%s"`, a.name, xmlEscape(a.value))
+	}
+}
+
+// id returns a unique, prefixed XML id for name.
+func (x *xhtmlWriter) id(name string) string {
+	id := x.prefix + xmlID(name)
+	for n := 1; x.ids[id]; n++ {
+		id = x.prefix + xmlID(name) + "-" + strconv.Itoa(n)
+	}
+	x.ids[id] = true
+	return id
+}
+
+// xmlID turns s into a valid XML id (an NCName).
+func xmlID(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('-')
+		}
+	}
+	id := b.String()
+	if id == "" || !unicode.IsLetter([]rune(id)[0]) && id[0] != '_' {
+		id = "id-" + id
+	}
+	return id
+}
+
+// xmlEscape escapes s for XML text and attributes, dropping characters
+// XML does not allow.
+func xmlEscape(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || r >= 0x20 && r != 0xfffe && r != 0xffff {
+			return r
+		}
+		return -1
+	}, s)
+	return html.EscapeString(s)
+}
+
+// writeEPUB writes an EPUB 3 container: the mimetype entry, the container
+// document, the package document (OPF), a navigation document, one XHTML
+// file per chapter, a style sheet and the images.
+func (b *Book) writeEPUB(w io.Writer) error {
+	pages := b.renderChapters(
+		func(int) string { return "" },
+		func(j int, id string) string {
+			target := fmt.Sprintf("chapter-%03d.xhtml", j+1)
+			if id != "" {
+				target += "#" + id
+			}
+			return target
+		},
+		func(img *bookImage) string { return "images/" + img.name },
+	)
+
+	zw := zip.NewWriter(w)
+	// The mimetype entry must come first, stored uncompressed and without
+	// extra fields, so it is written raw with a precomputed checksum.
+	mimetype := []byte("application/epub+zip")
+	mw, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(mimetype),
+		CompressedSize64:   uint64(len(mimetype)),
+		UncompressedSize64: uint64(len(mimetype)),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := mw.Write(mimetype); err != nil {
+		return err
+	}
+
+	add := func(name, content string) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: b.Modified})
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, content)
+		return err
+	}
+	files := []struct{ name, content string }{
+		{"META-INF/container.xml", epubContainer},
+		{"OEBPS/content.opf", b.epubPackage(pages)},
+		{"OEBPS/nav.xhtml", b.epubNav(pages)},
+		{"OEBPS/style.css", bookCSS},
+	}
+	for _, p := range pages {
+		files = append(files, struct{ name, content string }{"OEBPS/" + p.ID + ".xhtml", b.epubChapter(p)})
+	}
+	for _, f := range files {
+		if err := add(f.name, f.content); err != nil {
+			return err
+		}
+	}
+	for _, img := range b.sortedImages() {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: "OEBPS/images/" + img.name, Method: zip.Store, Modified: b.Modified})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(img.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// sortedImages returns the downloaded images in file name order.
+func (b *Book) sortedImages() []*bookImage {
+	var imgs []*bookImage
+	for _, img := range b.images {
+		if img != nil {
+			imgs = append(imgs, img)
+		}
+	}
+	for i := 1; i < len(imgs); i++ {
+		for j := i; j > 0 && imgs[j].name < imgs[j-1].name; j-- {
+			imgs[j], imgs[j-1] = imgs[j-1], imgs[j]
+		}
+	}
+	return imgs
+}
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+func (b *Book) epubPackage(pages []bookPage) string {
+	var s strings.Builder
+	s.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="bookid" xml:lang="` + xmlEscape(b.Language) + `">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+`)
+	fmt.Fprintf(&s, "    <dc:identifier id=\"bookid\">%s</dc:identifier>\n", xmlEscape(b.Identifier))
+	fmt.Fprintf(&s, "    <dc:title>%s</dc:title>\n", xmlEscape(b.Title))
+	fmt.Fprintf(&s, "    <dc:language>%s</dc:language>\n", xmlEscape(b.Language))
+	if b.Author != "" {
+		fmt.Fprintf(&s, "    <dc:creator>%s</dc:creator>\n", xmlEscape(b.Author))
+	}
+	fmt.Fprintf(&s, "    <meta property=\"dcterms:modified\">%s</meta>\n", b.Modified.UTC().Format("2006-01-02T15:04:05Z"))
+	s.WriteString("  </metadata>\n  <manifest>\n")
+	s.WriteString("    <item id=\"nav\" href=\"nav.xhtml\" media-type=\"application/xhtml+xml\" properties=\"nav\"/>\n")
+	s.WriteString("    <item id=\"css\" href=\"style.css\" media-type=\"text/css\"/>\n")
+	for _, p := range pages {
+		fmt.Fprintf(&s, "    <item id=\"%s\" href=\"%s.xhtml\" media-type=\"application/xhtml+xml\"/>\n", p.ID, p.ID)
+	}
+	for i, img := range b.sortedImages() {
+		fmt.Fprintf(&s, "    <item id=\"img-%d\" href=\"images/%s\" media-type=\"%s\"/>\n", i+1, img.name, img.mediaType)
+	}
+	s.WriteString("  </manifest>\n  <spine>\n")
+	for _, p := range pages {
+		fmt.Fprintf(&s, "    <itemref idref=\"%s\"/>\n", p.ID)
+	}
+	s.WriteString("  </spine>\n</package>\n")
+	return s.String()
+}
+
+// epubDocument wraps body in an XHTML document.
+func (b *Book) epubDocument(title, body string) string {
+	lang := xmlEscape(b.Language)
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="` + lang + `" lang="` + lang + `">
+<head>
+<meta charset="UTF-8"/>
+<title>` + xmlEscape(title) + `</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+` + body + `
+</body>
+</html>
+`
+}
+
+func (b *Book) epubNav(pages []bookPage) string {
+	var s strings.Builder
+	s.WriteString("<nav epub:type=\"toc\" id=\"toc\">\n<h1>Contents</h1>\n<ol>\n")
+	for _, p := range pages {
+		fmt.Fprintf(&s, "<li><a href=\"%s.xhtml\">%s</a>", p.ID, xmlEscape(p.Title))
+		if len(p.Sections) > 0 {
+			s.WriteString("\n<ol>\n")
+			for _, h := range p.Sections {
+				fmt.Fprintf(&s, "<li><a href=\"%s.xhtml#%s\">%s</a></li>\n", p.ID, h.Anchor, xmlEscape(h.Text))
+			}
+			s.WriteString("</ol>\n")
+		}
+		s.WriteString("</li>\n")
+	}
+	s.WriteString("</ol>\n</nav>")
+	return b.epubDocument(b.Title, s.String())
+}
+
+func (b *Book) epubChapter(p bookPage) string {
+	return b.epubDocument(p.Title, "<section epub:type=\"chapter\">\n"+string(p.Body)+"\n</section>")
+}
+
+// writeHTML writes a single page with a table of contents and the
+// chapters as sections. Heading ids are prefixed with the chapter number
+// to keep them unique.
+func (b *Book) writeHTML(w io.Writer) error {
+	pages := b.renderChapters(
+		func(i int) string { return fmt.Sprintf("c%d-", i+1) },
+		func(j int, id string) string {
+			if id != "" {
+				return "#" + id
+			}
+			return fmt.Sprintf("#chapter-%03d", j+1)
+		},
+		func(img *bookImage) string {
+			return "data:" + img.mediaType + ";base64," + base64.StdEncoding.EncodeToString(img.data)
+		},
+	)
+	return bookTemplate.Execute(w, struct {
+		*Book
+		Pages []bookPage
+	}{b, pages})
+}
+
+const bookCSS = `body { font-family: serif; line-height: 1.5; margin: 0 5%; }
+h1, h2, h3 { font-family: sans-serif; line-height: 1.2; }
+pre, code { font-family: monospace; font-size: 0.9em; }
+pre { white-space: pre-wrap; background: #f4f4f4; padding: 0.5em; }
+img { max-width: 100%; height: auto; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.4em; }
+`
+
+var bookTemplate = template.Must(template.New("book").Funcs(template.FuncMap{
+	"css": func() template.CSS { return template.CSS(bookCSS) },
+}).Parse(`<!DOCTYPE html>
+<html lang="{{.Language}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+{{css}}.chapter { border-top: 1px solid #ccc; margin-top: 3em; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+{{with .Author}}<p class="author">{{.}}</p>{{end}}
+</header>
+<nav id="toc">
+<h2>Contents</h2>
+<ol>
+{{range .Pages}}<li><a href="#{{.ID}}">{{.Title}}</a>{{if .Sections}}
+<ol>
+{{range .Sections}}<li><a href="#{{.Anchor}}">{{.Text}}</a></li>
+{{end}}</ol>{{end}}</li>
+{{end}}</ol>
+</nav>
+{{range .Pages}}<section class="chapter" id="{{.ID}}">
+{{.Body}}
+</section>
+{{end}}</body>
+</html>
+`))
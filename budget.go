@@ -0,0 +1,369 @@
+package snapapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// budgetEndpoints maps the names and paths accepted in BudgetRule.Endpoint
+// to the endpoint names that billable methods pass to the Budget. PDF is
+// sent to /v1/screenshot, so only its name selects it.
+var budgetEndpoints = map[string]string{
+	"screenshot":             "screenshot",
+	"pdf":                    "pdf",
+	"video":                  "video",
+	"scrape":                 "scrape",
+	"extract":                "extract",
+	"analyze":                "analyze",
+	"/v1/screenshot":         "screenshot",
+	"/v1/screenshot/storage": "screenshot",
+	"/v1/video":              "video",
+	"/v1/scrape":             "scrape",
+	"/v1/extract":            "extract",
+	"/v1/analyze":            "analyze",
+}
+
+// BudgetRule limits the number of billable requests matching an endpoint
+// and/or caller tag within the current usage period. Counts are kept
+// locally and reset when the account's UsageResult.ResetAt moves on.
+type BudgetRule struct {
+	// Endpoint restricts the rule to one endpoint: "screenshot" (which
+	// includes ScreenshotToStorage), "pdf", "video", "scrape", "extract" or
+	// "analyze". The paths "/v1/screenshot", "/v1/video" and so on are
+	// accepted as aliases. Empty matches every billable endpoint.
+	Endpoint string
+	// Tag restricts the rule to requests whose context carries this tag
+	// (see WithTag). Empty matches every caller.
+	Tag string
+	// SoftLimit fires BudgetOptions.OnSoftLimit once the rule has counted
+	// this many requests. Zero disables it.
+	SoftLimit int
+	// HardLimit refuses requests with a *BudgetExceededError once the rule
+	// has counted this many. Zero disables it.
+	HardLimit int
+}
+
+// BudgetOptions configures a Budget.
+type BudgetOptions struct {
+	// SoftThreshold is the fraction (0-1) of the account's Limit at which
+	// OnSoftLimit fires and throttling starts. Zero disables it.
+	SoftThreshold float64
+	// HardThreshold is the fraction (0-1) of the account's Limit at which
+	// requests are refused locally. Default: 1, i.e. refuse the request that
+	// would otherwise fail with HTTP 402.
+	HardThreshold float64
+	// Rules add per-endpoint and per-tag limits.
+	Rules []BudgetRule
+	// RefreshInterval is how often usage is re-fetched with GetUsage.
+	// Between refreshes, usage is tracked locally. Default: 5 minutes.
+	RefreshInterval time.Duration
+	// ThrottleDelay, if set, delays each billable request by this long once a
+	// soft limit has been reached, spreading the remaining quota out.
+	ThrottleDelay time.Duration
+	// OnSoftLimit is called (synchronously, once per usage period and
+	// scope) when a soft limit is reached.
+	OnSoftLimit func(BudgetEvent)
+}
+
+// BudgetEvent describes a soft limit being reached.
+type BudgetEvent struct {
+	// Scope is "account" for the account-wide threshold, or describes the
+	// rule, e.g. "endpoint=screenshot tag=team:seo".
+	Scope string
+	// Used is the number of requests counted in the scope.
+	Used int
+	// Limit is the threshold that was reached.
+	Limit int
+}
+
+// BudgetExceededError is returned, without contacting the API, when a
+// request would exceed a Budget's hard limit. It matches
+// errors.Is(err, ErrBudgetExceeded).
+type BudgetExceededError struct {
+	// Scope identifies the limit, as in BudgetEvent.Scope.
+	Scope string
+	// Used is the number of requests already counted in the scope.
+	Used int
+	// Limit is the hard limit.
+	Limit int
+}
+
+// Error implements the error interface.
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("snapapi: budget exceeded for %s (%d/%d)", e.Scope, e.Used, e.Limit)
+}
+
+// Is reports whether target is ErrBudgetExceeded.
+func (e *BudgetExceededError) Is(target error) bool { return target == ErrBudgetExceeded }
+
+// BudgetStatus is a snapshot of a Budget.
+type BudgetStatus struct {
+	// Used, Limit and Remaining are the account figures from the last refresh
+	// adjusted by requests made since. UsageKnown is false until the first
+	// successful refresh.
+	Used       int
+	Limit      int
+	Remaining  int
+	UsageKnown bool
+	// RefreshedAt is when usage was last fetched from the API.
+	RefreshedAt time.Time
+	// RuleCounts holds the current count for each BudgetOptions.Rules entry.
+	RuleCounts []int
+}
+
+// Budget guards a Client against exceeding its quota. It tracks account
+// usage (refreshed periodically via GetUsage and updated locally after each
+// successful billable request), fires callbacks at soft thresholds and
+// refuses requests at hard thresholds with a *BudgetExceededError instead of
+// sending requests that will fail with HTTP 402.
+//
+// Attach a Budget with WithBudget. A Budget may be shared by several clients
+// using the same account.
+//
+//	budget, err := snapapi.NewBudget(snapapi.BudgetOptions{
+//	    SoftThreshold: 0.8,
+//	    HardThreshold: 0.95,
+//	    Rules: []snapapi.BudgetRule{{Tag: "team:seo", HardLimit: 2000}},
+//	    OnSoftLimit: func(e snapapi.BudgetEvent) { log.Printf("budget %s at %d/%d", e.Scope, e.Used, e.Limit) },
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	client := snapapi.New("sk_...", snapapi.WithBudget(budget))
+type Budget struct {
+	opts      BudgetOptions
+	endpoints []string // the endpoint name of each rule, "" for any
+
+	mu          sync.Mutex
+	usage       UsageResult
+	known       bool
+	refreshedAt time.Time
+	lastAttempt time.Time
+	refreshing  bool
+	inflight    int
+	counts      []int
+	fired       map[string]bool
+}
+
+// NewBudget returns a Budget configured by opts. It returns an error if a
+// rule names an unknown endpoint.
+func NewBudget(opts BudgetOptions) (*Budget, error) {
+	endpoints := make([]string, len(opts.Rules))
+	for i, r := range opts.Rules {
+		if r.Endpoint == "" {
+			continue
+		}
+		name, ok := budgetEndpoints[r.Endpoint]
+		if !ok {
+			return nil, &APIError{
+				Code:       ErrInvalidParams,
+				Message:    fmt.Sprintf("budget rule %d: unknown endpoint %q", i, r.Endpoint),
+				StatusCode: 400,
+			}
+		}
+		endpoints[i] = name
+	}
+	if opts.HardThreshold <= 0 || opts.HardThreshold > 1 {
+		opts.HardThreshold = 1
+	}
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = 5 * time.Minute
+	}
+	return &Budget{
+		opts:      opts,
+		endpoints: endpoints,
+		counts:    make([]int, len(opts.Rules)),
+		fired:     make(map[string]bool),
+	}, nil
+}
+
+// Status returns a snapshot of the budget.
+func (b *Budget) Status() BudgetStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BudgetStatus{
+		Used:        b.usage.Used,
+		Limit:       b.usage.Limit,
+		Remaining:   b.usage.Remaining,
+		UsageKnown:  b.known,
+		RefreshedAt: b.refreshedAt,
+		RuleCounts:  append([]int(nil), b.counts...),
+	}
+}
+
+// Refresh fetches current usage through c immediately. It is called
+// automatically every RefreshInterval; call it directly to force an update.
+func (b *Budget) Refresh(ctx context.Context, c *Client) error {
+	usage, err := c.GetUsage(withInternalCall(ctx))
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refreshing = false
+	if err != nil {
+		return err
+	}
+	if b.known && b.usage.ResetAt != "" && usage.ResetAt != b.usage.ResetAt {
+		// A new usage period has started.
+		for i := range b.counts {
+			b.counts[i] = 0
+		}
+		b.fired = make(map[string]bool)
+	}
+	b.usage = *usage
+	b.known = true
+	b.refreshedAt = time.Now()
+	return nil
+}
+
+// acquire reserves budget for one request to endpoint, one of the names in
+// budgetEndpoints. The returned release function must be called with the
+// request's outcome.
+func (b *Budget) acquire(ctx context.Context, c *Client, endpoint string) (func(error), error) {
+	if b.needsRefresh() {
+		// A failed refresh leaves the local estimate in place until the
+		// next RefreshInterval.
+		_ = b.Refresh(ctx, c)
+	}
+
+	tags := TagsFromContext(ctx)
+	b.mu.Lock()
+	var matched []int
+	for i, r := range b.opts.Rules {
+		if (b.endpoints[i] == "" || b.endpoints[i] == endpoint) && r.matchesTag(tags) {
+			matched = append(matched, i)
+		}
+	}
+
+	// Check every hard limit before reserving anything.
+	if b.known && b.usage.Limit > 0 {
+		hard := int(b.opts.HardThreshold * float64(b.usage.Limit))
+		if used := b.usage.Used + b.inflight; used+1 > hard {
+			b.mu.Unlock()
+			return nil, &BudgetExceededError{Scope: "account", Used: used, Limit: hard}
+		}
+	}
+	for _, i := range matched {
+		r := b.opts.Rules[i]
+		if r.HardLimit > 0 && b.counts[i]+1 > r.HardLimit {
+			b.mu.Unlock()
+			return nil, &BudgetExceededError{Scope: r.scope(), Used: b.counts[i], Limit: r.HardLimit}
+		}
+	}
+
+	b.inflight++
+	for _, i := range matched {
+		b.counts[i]++
+	}
+	var events []BudgetEvent
+	soft := false
+	if b.known && b.usage.Limit > 0 && b.opts.SoftThreshold > 0 {
+		limit := int(b.opts.SoftThreshold * float64(b.usage.Limit))
+		if used := b.usage.Used + b.inflight; used >= limit {
+			soft = true
+			if !b.fired["account"] {
+				b.fired["account"] = true
+				events = append(events, BudgetEvent{Scope: "account", Used: used, Limit: limit})
+			}
+		}
+	}
+	for _, i := range matched {
+		r := b.opts.Rules[i]
+		if r.SoftLimit > 0 && b.counts[i] >= r.SoftLimit {
+			soft = true
+			if scope := r.scope(); !b.fired[scope] {
+				b.fired[scope] = true
+				events = append(events, BudgetEvent{Scope: scope, Used: b.counts[i], Limit: r.SoftLimit})
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	release := func(err error) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.inflight--
+		switch {
+		case err == nil:
+			b.usage.Used++
+			if b.usage.Remaining > 0 {
+				b.usage.Remaining--
+			}
+		case errors.Is(err, ErrQuota):
+			// The server disagrees with our estimate; trust it.
+			if b.usage.Limit > 0 {
+				b.usage.Used = b.usage.Limit
+			}
+			b.usage.Remaining = 0
+			fallthrough
+		default:
+			for _, i := range matched {
+				b.counts[i]--
+			}
+		}
+	}
+
+	if b.opts.OnSoftLimit != nil {
+		for _, e := range events {
+			b.opts.OnSoftLimit(e)
+		}
+	}
+	if soft && b.opts.ThrottleDelay > 0 {
+		select {
+		case <-ctx.Done():
+			release(ctx.Err())
+			return nil, ctx.Err()
+		case <-time.After(b.opts.ThrottleDelay):
+		}
+	}
+	return release, nil
+}
+
+// needsRefresh reports whether usage is stale, and if so marks a refresh as
+// in progress so concurrent callers don't all refresh at once.
+func (b *Budget) needsRefresh() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.refreshing {
+		return false
+	}
+	stale := time.Since(b.lastAttempt) >= b.opts.RefreshInterval
+	if !stale && b.usage.ResetAt != "" {
+		if t, err := time.Parse(time.RFC3339, b.usage.ResetAt); err == nil && time.Now().After(t) {
+			stale = true
+		}
+	}
+	if stale {
+		b.refreshing = true
+		b.lastAttempt = time.Now()
+	}
+	return stale
+}
+
+func (r BudgetRule) matchesTag(tags []string) bool {
+	if r.Tag == "" {
+		return true
+	}
+	for _, t := range tags {
+		if t == r.Tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (r BudgetRule) scope() string {
+	var parts []string
+	if r.Endpoint != "" {
+		parts = append(parts, "endpoint="+r.Endpoint)
+	}
+	if r.Tag != "" {
+		parts = append(parts, "tag="+r.Tag)
+	}
+	if len(parts) == 0 {
+		return "all"
+	}
+	return strings.Join(parts, " ")
+}
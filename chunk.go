@@ -0,0 +1,402 @@
+package snapapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Chunk is a piece of a Markdown document sized for embedding.
+type Chunk struct {
+	// Index is the position of the chunk in the document, from 0.
+	Index int `json:"index"`
+	// URL is the source URL from ChunkOptions.URL.
+	URL string `json:"url,omitempty"`
+	// Headings is the breadcrumb of headings the chunk is under, outermost
+	// first.
+	Headings []string `json:"headings"`
+	// Text is the chunk's Markdown.
+	Text string `json:"text"`
+	// Tokens is the token count of Text, as counted by ChunkOptions.CountTokens.
+	Tokens int `json:"tokens"`
+}
+
+// ChunkOptions configures ChunkMarkdown.
+type ChunkOptions struct {
+	// MaxTokens is the target maximum size of a chunk. Only a code block
+	// or table that is larger on its own produces a bigger chunk.
+	// Default: 512.
+	MaxTokens int
+	// Overlap is the number of tokens from the end of a chunk repeated at
+	// the start of the next one in the same section, so context carries
+	// across the boundary. Code blocks and tables are never repeated.
+	Overlap int
+	// URL is recorded on every chunk as its source.
+	URL string
+	// CountTokens counts the tokens of a string. Default: EstimateTokens.
+	// Set it to your embedding model's tokenizer for exact sizes.
+	CountTokens func(string) int
+}
+
+// EstimateTokens approximates the number of tokens a typical LLM tokenizer
+// produces for s: one per four characters, and one per Chinese or Japanese
+// character.
+func EstimateTokens(s string) int {
+	chars, cjk := 0, 0
+	for _, r := range s {
+		if isCJK(r) {
+			cjk++
+		} else {
+			chars++
+		}
+	}
+	return (chars+3)/4 + cjk
+}
+
+// ExtractChunks extracts p.URL as Markdown and splits it with
+// ChunkMarkdown. opts.URL defaults to the final URL of the page.
+//
+//	chunks, err := client.ExtractChunks(ctx, snapapi.ExtractParams{URL: "https://example.com/docs"},
+//	    snapapi.ChunkOptions{MaxTokens: 400, Overlap: 50})
+//	snapapi.WriteChunksJSONL(f, chunks)
+func (c *Client) ExtractChunks(ctx context.Context, p ExtractParams, opts ChunkOptions) ([]Chunk, error) {
+	p.Format = "markdown"
+	res, err := c.Extract(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if opts.URL == "" {
+		opts.URL = res.URL
+		if opts.URL == "" {
+			opts.URL = p.URL
+		}
+	}
+	return ChunkMarkdown(res.Content, opts), nil
+}
+
+// ChunkMarkdown splits a Markdown document into chunks of at most about
+// opts.MaxTokens tokens. Every heading starts a new chunk, and blocks
+// (paragraphs, lists, code blocks, tables) are packed into chunks whole.
+// Paragraphs and lists that are too large are split by line, then by
+// sentence, then by word; fenced code blocks and tables are never split.
+//
+//	md, _ := client.ExtractMarkdown(ctx, "https://example.com/docs")
+//	for _, c := range snapapi.ChunkMarkdown(md, snapapi.ChunkOptions{MaxTokens: 300}) {
+//	    fmt.Println(c.Index, strings.Join(c.Headings, " > "), c.Tokens)
+//	}
+func ChunkMarkdown(md string, opts ChunkOptions) []Chunk {
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = 512
+	}
+	if opts.CountTokens == nil {
+		opts.CountTokens = EstimateTokens
+	}
+	opts.Overlap = max(0, min(opts.Overlap, opts.MaxTokens/2))
+	ch := &chunker{opts: opts}
+	for _, b := range markdownBlocks(md) {
+		ch.add(b)
+	}
+	ch.flush(false)
+	return ch.chunks
+}
+
+// WriteChunksJSONL writes one JSON object per chunk, ready for embedding
+// pipelines.
+func WriteChunksJSONL(w io.Writer, chunks []Chunk) error {
+	enc := json.NewEncoder(w)
+	for _, c := range chunks {
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markdownBlockKind is the kind of a top-level Markdown block.
+type markdownBlockKind int
+
+const (
+	mdParagraph markdownBlockKind = iota
+	mdHeading
+	mdCode
+	mdTable
+)
+
+type markdownBlock struct {
+	kind  markdownBlockKind
+	text  string
+	level int    // headings
+	title string // headings, without markup
+}
+
+var mdTableDelim = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)+\|?\s*$`)
+
+// markdownBlocks splits md into headings, fenced code blocks, tables and
+// paragraphs (runs of non-blank lines, which includes lists).
+func markdownBlocks(md string) []markdownBlock {
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	var blocks []markdownBlock
+	var para []string
+	endPara := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, markdownBlock{kind: mdParagraph, text: strings.Join(para, "\n")})
+			para = nil
+		}
+	}
+	heading := func(level int, line, title string) {
+		endPara()
+		title = (&docBuilder{}).inline(title)
+		blocks = append(blocks, markdownBlock{kind: mdHeading, text: line, level: level, title: title})
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			endPara()
+		case mdFence.MatchString(line):
+			endPara()
+			open := mdFence.FindStringSubmatch(line)[1]
+			j := i + 1
+			for ; j < len(lines); j++ {
+				if m := mdFence.FindStringSubmatch(lines[j]); m != nil && m[1][0] == open[0] && len(m[1]) >= len(open) {
+					break
+				}
+			}
+			end := min(j, len(lines)-1)
+			blocks = append(blocks, markdownBlock{kind: mdCode, text: strings.Join(lines[i:end+1], "\n")})
+			i = end
+		case mdATX.MatchString(line):
+			m := mdATX.FindStringSubmatch(line)
+			heading(len(m[1]), line, m[2])
+		case len(para) == 0 && i+1 < len(lines) && mdSetext.MatchString(lines[i+1]) &&
+			mdBlock.FindString(line) == "" && !mdTableRule.MatchString(line):
+			level := 1
+			if strings.TrimSpace(lines[i+1])[0] == '-' {
+				level = 2
+			}
+			heading(level, line+"\n"+lines[i+1], line)
+			i++
+		case strings.Contains(line, "|") && i+1 < len(lines) && mdTableDelim.MatchString(lines[i+1]):
+			endPara()
+			j := i + 2
+			for j < len(lines) && strings.TrimSpace(lines[j]) != "" && strings.Contains(lines[j], "|") {
+				j++
+			}
+			blocks = append(blocks, markdownBlock{kind: mdTable, text: strings.Join(lines[i:j], "\n")})
+			i = j - 1
+		default:
+			para = append(para, line)
+		}
+	}
+	endPara()
+	return blocks
+}
+
+// chunkPiece is a part of the chunk being built.
+type chunkPiece struct {
+	text    string
+	tokens  int
+	atomic  bool // code block or table
+	heading int  // heading level, or 0 for content
+	overlap bool // repeated from the previous chunk
+	// sep joins the piece to the one before it: "\n\n" between blocks, or
+	// the separator a split block was broken at.
+	sep string
+}
+
+type chunker struct {
+	opts   ChunkOptions
+	crumbs []markdownBlock // enclosing headings
+	pieces []chunkPiece
+	chunks []Chunk
+}
+
+func (ch *chunker) tokens() int {
+	n := 0
+	for _, p := range ch.pieces {
+		n += p.tokens
+	}
+	return n
+}
+
+func (ch *chunker) hasContent() bool {
+	for _, p := range ch.pieces {
+		if p.heading == 0 && !p.overlap {
+			return true
+		}
+	}
+	return false
+}
+
+func (ch *chunker) add(b markdownBlock) {
+	if b.kind == mdHeading {
+		if ch.hasContent() {
+			ch.flush(false)
+		}
+		// Drop the overlap and the headings of empty sections that are not
+		// parents of this one.
+		kept := ch.pieces[:0]
+		for _, p := range ch.pieces {
+			if p.heading > 0 && p.heading < b.level {
+				kept = append(kept, p)
+			}
+		}
+		ch.pieces = kept
+		for len(ch.crumbs) > 0 && ch.crumbs[len(ch.crumbs)-1].level >= b.level {
+			ch.crumbs = ch.crumbs[:len(ch.crumbs)-1]
+		}
+		ch.crumbs = append(ch.crumbs, b)
+		ch.pieces = append(ch.pieces, chunkPiece{text: b.text, tokens: ch.opts.CountTokens(b.text), heading: b.level, sep: "\n\n"})
+		return
+	}
+
+	atomic := b.kind == mdCode || b.kind == mdTable
+	parts, sep := []string{b.text}, ""
+	if !atomic {
+		// Leave room for the overlap and the headings that start a chunk.
+		reserve := ch.opts.Overlap
+		if !ch.hasContent() {
+			reserve = max(reserve, ch.tokens())
+		}
+		parts, sep = splitToFit(b.text, max(ch.opts.MaxTokens-reserve, ch.opts.MaxTokens/2), ch.opts.CountTokens)
+	}
+	for i, text := range parts {
+		p := chunkPiece{text: text, tokens: ch.opts.CountTokens(text), atomic: atomic, sep: "\n\n"}
+		if i > 0 {
+			p.sep = sep
+		}
+		if ch.hasContent() && ch.tokens()+p.tokens > ch.opts.MaxTokens {
+			ch.flush(true)
+			if len(ch.pieces) > 0 && ch.tokens()+p.tokens > ch.opts.MaxTokens {
+				// No room for the overlap.
+				ch.pieces = nil
+			}
+		}
+		ch.pieces = append(ch.pieces, p)
+	}
+}
+
+// flush emits the current chunk. With overlap, the next chunk starts with
+// the tail of this one.
+func (ch *chunker) flush(overlap bool) {
+	if !ch.hasContent() {
+		return
+	}
+	var b strings.Builder
+	for i, p := range ch.pieces {
+		if i > 0 {
+			b.WriteString(p.sep)
+		}
+		b.WriteString(p.text)
+	}
+	crumbs := make([]string, len(ch.crumbs))
+	for i, h := range ch.crumbs {
+		crumbs[i] = h.title
+	}
+	text := b.String()
+	ch.chunks = append(ch.chunks, Chunk{
+		Index:    len(ch.chunks),
+		URL:      ch.opts.URL,
+		Headings: crumbs,
+		Text:     text,
+		Tokens:   ch.opts.CountTokens(text),
+	})
+
+	last := ch.pieces[len(ch.pieces)-1]
+	ch.pieces = nil
+	if overlap && ch.opts.Overlap > 0 && !last.atomic && last.heading == 0 {
+		if tail := tailTokens(last.text, ch.opts.Overlap, ch.opts.CountTokens); tail != "" {
+			ch.pieces = []chunkPiece{{text: tail, tokens: ch.opts.CountTokens(tail), overlap: true}}
+		}
+	}
+}
+
+// tailTokens returns the longest run of whole words at the end of s that
+// fits in n tokens.
+func tailTokens(s string, n int, count func(string) int) string {
+	words := strings.Fields(s)
+	start := len(words)
+	for start > 0 && count(strings.Join(words[start-1:], " ")) <= n {
+		start--
+	}
+	return strings.Join(words[start:], " ")
+}
+
+// splitToFit splits text into parts of at most limit tokens, breaking at
+// lines, then sentences, then words, and returns the separator that joins
+// the parts back. A single word larger than limit is kept whole.
+func splitToFit(text string, limit int, count func(string) int) (parts []string, sep string) {
+	if count(text) <= limit {
+		return []string{text}, ""
+	}
+	for _, split := range []struct {
+		units func(string) []string
+		sep   string
+	}{
+		{func(s string) []string { return strings.Split(s, "\n") }, "\n"},
+		{splitSentences, " "},
+		{strings.Fields, " "},
+	} {
+		units := split.units(text)
+		if len(units) < 2 {
+			continue
+		}
+		cur := ""
+		for _, u := range units {
+			if count(u) > limit {
+				if cur != "" {
+					parts = append(parts, cur)
+					cur = ""
+				}
+				sub, _ := splitToFit(u, limit, count)
+				parts = append(parts, sub...)
+				continue
+			}
+			next := u
+			if cur != "" {
+				next = cur + split.sep + u
+			}
+			if cur != "" && count(next) > limit {
+				parts = append(parts, cur)
+				next = u
+			}
+			cur = next
+		}
+		if cur != "" {
+			parts = append(parts, cur)
+		}
+		return parts, split.sep
+	}
+	return []string{text}, ""
+}
+
+// splitSentences splits s after sentence-ending punctuation followed by
+// whitespace, and after CJK full stops.
+func splitSentences(s string) []string {
+	var out []string
+	start := 0
+	runes := []rune(s)
+	for i, r := range runes {
+		end := false
+		switch r {
+		case '。', '！', '？':
+			end = true
+		case '.', '!', '?':
+			end = i+1 < len(runes) && unicode.IsSpace(runes[i+1])
+		}
+		if end {
+			if part := strings.TrimSpace(string(runes[start : i+1])); part != "" {
+				out = append(out, part)
+			}
+			start = i + 1
+		}
+	}
+	if part := strings.TrimSpace(string(runes[start:])); part != "" {
+		out = append(out, part)
+	}
+	return out
+}
@@ -0,0 +1,197 @@
+// Command snapindex builds and searches a local full-text index of pages
+// captured with SnapAPI.
+//
+//	snapindex add https://example.com/docs/install https://example.com/docs/faq
+//	snapindex crawl -max-pages 200 -field site=example https://example.com/docs/
+//	snapindex search '"api key" -deprecated site:example.com'
+//	snapindex search -json -n 20 install docker
+//	snapindex list
+//	snapindex delete https://example.com/docs/old
+//
+// The index is stored in the directory given by -dir (default ".snapindex").
+// The add and crawl commands read the API key from SNAPAPI_KEY.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	snapapi "github.com/Sleywill/snapapi-go"
+	"github.com/Sleywill/snapapi-go/index"
+)
+
+const usage = `usage: snapindex [-dir DIR] COMMAND [flags] [args]
+
+commands:
+  add URL...       extract pages and index them
+  crawl URL        crawl a site and index every page
+  search QUERY...  search the index
+  list             list indexed URLs
+  delete URL...    remove pages from the index
+`
+
+// fields is a repeatable -field key=value flag.
+type fields map[string]string
+
+func (f fields) String() string { return "" }
+
+func (f fields) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return errors.New("want key=value")
+	}
+	f[k] = v
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("snapindex: ")
+	dir := flag.String("dir", ".snapindex", "index directory")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ix, err := index.Open(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+	switch cmd {
+	case "add":
+		err = add(ctx, ix, args)
+	case "crawl":
+		err = crawl(ctx, ix, args)
+	case "search":
+		err = search(ix, args)
+	case "list":
+		for _, u := range ix.URLs() {
+			fmt.Println(u)
+		}
+	case "delete":
+		for _, u := range args {
+			if !ix.Delete(u) {
+				log.Printf("%s is not indexed", u)
+			}
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if cerr := ix.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func client() (*snapapi.Client, error) {
+	key := os.Getenv("SNAPAPI_KEY")
+	if key == "" {
+		return nil, errors.New("SNAPAPI_KEY environment variable is required")
+	}
+	return snapapi.New(key, snapapi.WithTimeout(60*time.Second), snapapi.WithRetries(2)), nil
+}
+
+func add(ctx context.Context, ix *index.Index, args []string) error {
+	fs := flag.NewFlagSet("add", flag.ExitOnError)
+	meta := fields{}
+	fs.Var(meta, "field", "metadata `key=value` stored with each page (repeatable)")
+	selector := fs.String("selector", "", "CSS selector of the content to extract")
+	fs.Parse(args)
+	c, err := client()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, u := range fs.Args() {
+		res, err := c.Extract(ctx, snapapi.ExtractParams{URL: u, Format: "markdown", Selector: *selector})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", u, err))
+			continue
+		}
+		if res.URL == "" {
+			res.URL = u
+		}
+		if err := ix.AddExtract(res, meta); err != nil {
+			return err
+		}
+		fmt.Printf("indexed %s (%d words)\n", res.URL, res.WordCount)
+	}
+	return errors.Join(errs...)
+}
+
+func crawl(ctx context.Context, ix *index.Index, args []string) error {
+	fs := flag.NewFlagSet("crawl", flag.ExitOnError)
+	meta := fields{}
+	fs.Var(meta, "field", "metadata `key=value` stored with each page (repeatable)")
+	maxPages := fs.Int("max-pages", 100, "maximum number of pages")
+	depth := fs.Int("depth", 3, "maximum link depth")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("crawl needs one seed URL")
+	}
+	c, err := client()
+	if err != nil {
+		return err
+	}
+	res, err := c.Crawl(ctx, fs.Arg(0), snapapi.CrawlOptions{MaxPages: *maxPages, MaxDepth: *depth}, ix.CrawlFunc(meta))
+	if err != nil {
+		return err
+	}
+	for u, err := range res.Failed {
+		log.Printf("%s: %v", u, err)
+	}
+	fmt.Printf("indexed %d pages, %d in the index\n", len(res.Pages), ix.Len())
+	return nil
+}
+
+func search(ix *index.Index, args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	limit := fs.Int("n", 10, "number of results")
+	offset := fs.Int("offset", 0, "skip the first results")
+	asJSON := fs.Bool("json", false, "print results as JSON")
+	since := fs.Duration("since", 0, "only pages captured within this duration")
+	fs.Parse(args)
+
+	opts := index.SearchOptions{Limit: *limit, Offset: *offset}
+	if *since > 0 {
+		opts.Since = time.Now().Add(-*since)
+	}
+	if !*asJSON {
+		opts.HighlightStart, opts.HighlightEnd = "\x1b[1m", "\x1b[0m"
+	}
+	res, err := ix.Search(strings.Join(fs.Args(), " "), opts)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+	for i, hit := range res.Hits {
+		title := hit.Title
+		if title == "" {
+			title = hit.URL
+		}
+		fmt.Printf("%d. %s (%.2f)\n   %s\n   %s\n\n", *offset+i+1, title, hit.Score, hit.URL, hit.Snippet)
+	}
+	fmt.Printf("%d of %d results\n", len(res.Hits), res.Total)
+	return nil
+}
@@ -0,0 +1,467 @@
+package snapapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SkipLinks can be returned by a CrawlFunc to crawl no further from the
+// current page. The crawl itself continues.
+var SkipLinks = errors.New("snapapi: skip links")
+
+// CrawlOptions configures Crawl.
+type CrawlOptions struct {
+	// MaxDepth is the number of link hops to follow from the seed (the seed
+	// is depth 0). Default: 3. Use a negative value for the seed only.
+	MaxDepth int
+	// MaxPages caps the number of pages fetched. Default: 100.
+	MaxPages int
+	// Include keeps only links matching at least one of these regular
+	// expressions. The seed is always crawled.
+	Include []string
+	// Exclude drops links matching any of these regular expressions.
+	Exclude []string
+	// AllowSubdomains also follows links to subdomains of the seed's host.
+	// Hosts differing only by a "www." prefix are always treated as the same.
+	AllowSubdomains bool
+	// StripQuery drops query strings from discovered links, so /a?x=1 and
+	// /a?x=2 are one page.
+	StripQuery bool
+	// IgnoreRobots skips robots.txt. By default disallowed paths are not
+	// fetched and the robots.txt Crawl-delay is honoured. If robots.txt
+	// fails with a server or network error, no page on that origin is
+	// fetched.
+	IgnoreRobots bool
+	// UserAgent is the token matched against robots.txt User-agent lines.
+	// Default: "SnapAPI".
+	UserAgent string
+	// Delay is the minimum time between page fetches. A longer robots.txt
+	// Crawl-delay takes precedence.
+	Delay time.Duration
+	// Concurrency is the number of pages fetched at once. Default: 1.
+	Concurrency int
+	// Scrape is the template for each page's Scrape call (e.g. WaitFor for
+	// SPAs). URL and Format are set by the crawler.
+	Scrape ScrapeParams
+}
+
+// CrawlPage is a crawled page, as passed to a CrawlFunc.
+type CrawlPage struct {
+	// URL is the normalized page URL.
+	URL string
+	// Depth is the number of link hops from the seed.
+	Depth int
+	// Referrer is the page the URL was first found on ("" for the seed).
+	Referrer string
+	// HTML is the rendered HTML returned by Scrape.
+	HTML string
+	// Links are the normalized, deduplicated http(s) links found on the
+	// page, on any host.
+	Links []string
+}
+
+// CrawlFunc is called for every successfully fetched page, e.g. to
+// Screenshot or Extract it. Returning SkipLinks stops the crawl from
+// following the page's links; any other error aborts the crawl. With
+// Concurrency > 1 it may be called concurrently.
+type CrawlFunc func(ctx context.Context, page *CrawlPage) error
+
+// CrawlResult summarises a crawl.
+type CrawlResult struct {
+	// Pages lists the URLs fetched successfully, in the order they finished.
+	Pages []string
+	// Failed maps URLs whose Scrape failed, or whose origin's robots.txt
+	// could not be fetched, to the error.
+	Failed map[string]error
+	// Disallowed lists discovered URLs skipped because of robots.txt.
+	Disallowed []string
+}
+
+// Crawl walks a site breadth-first from seed, fetching rendered HTML with
+// Scrape so that links inserted by JavaScript are found. Links are resolved,
+// normalized (fragment removed, query sorted, default port dropped) and
+// deduplicated, and only links on the seed's host are followed.
+//
+//	res, err := client.Crawl(ctx, "https://example.com", snapapi.CrawlOptions{
+//	    MaxDepth: 2,
+//	    MaxPages: 500,
+//	    Exclude:  []string{`/tag/`, `\.pdf$`},
+//	    Delay:    time.Second,
+//	}, func(ctx context.Context, page *snapapi.CrawlPage) error {
+//	    _, err := client.ScreenshotToFile(ctx, filename(page.URL), snapapi.ScreenshotParams{URL: page.URL})
+//	    return err
+//	})
+func (c *Client) Crawl(ctx context.Context, seed string, opts CrawlOptions, fn CrawlFunc) (*CrawlResult, error) {
+	seedURL, err := url.Parse(seed)
+	if err != nil || (seedURL.Scheme != "http" && seedURL.Scheme != "https") || seedURL.Host == "" {
+		return nil, &APIError{Code: ErrInvalidParams, Message: "seed must be an absolute http(s) URL", StatusCode: 400}
+	}
+	include, err := compilePatterns(opts.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compilePatterns(opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = 3
+	}
+	if opts.MaxPages <= 0 {
+		opts.MaxPages = 100
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = "SnapAPI"
+	}
+
+	cr := &crawler{
+		c:       c,
+		opts:    opts,
+		fn:      fn,
+		include: include,
+		exclude: exclude,
+		host:    crawlHost(seedURL.Hostname()),
+		robots:  make(map[string]*robotsRules),
+		seen:    make(map[string]bool),
+		result:  &CrawlResult{Failed: make(map[string]error)},
+	}
+	cr.cond = sync.NewCond(&cr.mu)
+	start := normalizeURL(seedURL, false)
+	cr.seen[start] = true
+	cr.queue = append(cr.queue, crawlTask{url: start})
+	return cr.run(ctx)
+}
+
+type crawlTask struct {
+	url      string
+	depth    int
+	referrer string
+}
+
+type crawler struct {
+	c       *Client
+	opts    CrawlOptions
+	fn      CrawlFunc
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+	host    string
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	queue     []crawlTask
+	seen      map[string]bool
+	fetched   int
+	active    int
+	err       error
+	result    *CrawlResult
+	robots    map[string]*robotsRules
+	nextFetch time.Time
+}
+
+func (cr *crawler) run(ctx context.Context) (*CrawlResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() {
+		cr.mu.Lock()
+		cr.cond.Broadcast()
+		cr.mu.Unlock()
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	for w := 0; w < cr.opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				task, ok := cr.next(ctx)
+				if !ok {
+					return
+				}
+				err := cr.visit(ctx, task)
+				cr.mu.Lock()
+				cr.active--
+				if err != nil && cr.err == nil {
+					cr.err = err
+					cancel()
+				}
+				cr.cond.Broadcast()
+				cr.mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if cr.err != nil {
+		return cr.result, cr.err
+	}
+	return cr.result, ctx.Err()
+}
+
+// next blocks until a task is available, or returns false once the crawl is
+// finished.
+func (cr *crawler) next(ctx context.Context) (crawlTask, bool) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	for {
+		if ctx.Err() != nil || cr.fetched >= cr.opts.MaxPages {
+			return crawlTask{}, false
+		}
+		if len(cr.queue) > 0 {
+			task := cr.queue[0]
+			cr.queue = cr.queue[1:]
+			cr.fetched++
+			cr.active++
+			return task, true
+		}
+		if cr.active == 0 {
+			return crawlTask{}, false
+		}
+		cr.cond.Wait()
+	}
+}
+
+// visit fetches one page, reports it and queues its links.
+func (cr *crawler) visit(ctx context.Context, task crawlTask) error {
+	u, _ := url.Parse(task.url)
+	rules, err := cr.robotsFor(ctx, u)
+	if err != nil {
+		return err
+	}
+	if rules != nil && rules.err != nil {
+		cr.mu.Lock()
+		cr.result.Failed[task.url] = rules.err
+		cr.fetched--
+		cr.mu.Unlock()
+		return nil
+	}
+	if !rules.allowed(u.RequestURI()) {
+		cr.mu.Lock()
+		cr.result.Disallowed = append(cr.result.Disallowed, task.url)
+		cr.fetched--
+		cr.mu.Unlock()
+		return nil
+	}
+	var robotsDelay time.Duration
+	if rules != nil {
+		robotsDelay = rules.crawlDelay
+	}
+	if err := cr.wait(ctx, robotsDelay); err != nil {
+		return nil
+	}
+
+	p := cr.opts.Scrape
+	p.URL, p.Format = task.url, "html"
+	res, err := cr.c.Scrape(ctx, p)
+	if err != nil {
+		if ctx.Err() == nil {
+			cr.mu.Lock()
+			cr.result.Failed[task.url] = err
+			cr.mu.Unlock()
+		}
+		return nil
+	}
+
+	base := u
+	if res.URL != "" {
+		if final, err := url.Parse(res.URL); err == nil {
+			base = final
+		}
+	}
+	page := &CrawlPage{URL: task.url, Depth: task.depth, Referrer: task.referrer, HTML: res.Data}
+	page.Links = extractLinks(parseHTML(res.Data), base, cr.opts.StripQuery)
+
+	follow := true
+	if cr.fn != nil {
+		if err := cr.fn(ctx, page); errors.Is(err, SkipLinks) {
+			follow = false
+		} else if err != nil {
+			return err
+		}
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.result.Pages = append(cr.result.Pages, task.url)
+	if !follow || task.depth >= cr.opts.MaxDepth {
+		return nil
+	}
+	for _, link := range page.Links {
+		if cr.seen[link] || !cr.inScope(link) {
+			continue
+		}
+		cr.seen[link] = true
+		cr.queue = append(cr.queue, crawlTask{url: link, depth: task.depth + 1, referrer: task.url})
+	}
+	return nil
+}
+
+// inScope reports whether link is on the crawled site and passes the
+// include/exclude patterns.
+func (cr *crawler) inScope(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	host := crawlHost(u.Hostname())
+	if host != cr.host && !(cr.opts.AllowSubdomains && strings.HasSuffix(host, "."+cr.host)) {
+		return false
+	}
+	for _, re := range cr.exclude {
+		if re.MatchString(link) {
+			return false
+		}
+	}
+	if len(cr.include) == 0 {
+		return true
+	}
+	for _, re := range cr.include {
+		if re.MatchString(link) {
+			return true
+		}
+	}
+	return false
+}
+
+// robotsFor returns the robots.txt rules for u's origin, fetching them on
+// first use. A missing robots.txt (4xx) allows everything; a server error or
+// network failure disallows everything on that origin (RFC 9309, section
+// 2.3.1.4). The only error returned is the context's.
+func (cr *crawler) robotsFor(ctx context.Context, u *url.URL) (*robotsRules, error) {
+	if cr.opts.IgnoreRobots {
+		return nil, nil
+	}
+	origin := u.Scheme + "://" + u.Host
+	cr.mu.Lock()
+	rules, ok := cr.robots[origin]
+	cr.mu.Unlock()
+	if ok {
+		return rules, nil
+	}
+
+	body, err := cr.c.download(ctx, origin+"/robots.txt")
+	var apiErr *APIError
+	switch {
+	case err == nil:
+		rules = parseRobots(string(body), cr.opts.UserAgent)
+	case errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500:
+		rules = &robotsRules{}
+	default:
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		rules = &robotsRules{
+			rules: []robotsRule{{allow: false, pattern: "/"}},
+			err:   fmt.Errorf("snapapi: fetch %s/robots.txt: %w", origin, err),
+		}
+	}
+	cr.mu.Lock()
+	cr.robots[origin] = rules
+	cr.mu.Unlock()
+	return rules, nil
+}
+
+// wait enforces the delay between fetches across all workers.
+func (cr *crawler) wait(ctx context.Context, robotsDelay time.Duration) error {
+	delay := cr.opts.Delay
+	if robotsDelay > delay {
+		delay = robotsDelay
+	}
+	if delay <= 0 {
+		return nil
+	}
+	cr.mu.Lock()
+	now := time.Now()
+	at := cr.nextFetch
+	if at.Before(now) {
+		at = now
+	}
+	cr.nextFetch = at.Add(delay)
+	cr.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(at)):
+		return nil
+	}
+}
+
+// extractLinks returns the normalized http(s) links of the <a> and <area>
+// elements in doc, honouring <base href>.
+func extractLinks(doc *htmlNode, base *url.URL, stripQuery bool) []string {
+	if b := doc.find("base"); b != nil {
+		if href, err := url.Parse(strings.TrimSpace(b.attr("href"))); err == nil && b.hasAttr("href") {
+			base = base.ResolveReference(href)
+		}
+	}
+	seen := make(map[string]bool)
+	var links []string
+	doc.walk(func(n *htmlNode) bool {
+		if n.tag != "a" && n.tag != "area" {
+			return true
+		}
+		href := strings.TrimSpace(n.attr("href"))
+		if href == "" || strings.HasPrefix(href, "#") {
+			return true
+		}
+		ref, err := url.Parse(href)
+		if err != nil {
+			return true
+		}
+		abs := base.ResolveReference(ref)
+		if abs.Scheme != "http" && abs.Scheme != "https" {
+			return true
+		}
+		link := normalizeURL(abs, stripQuery)
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+		return true
+	})
+	return links
+}
+
+// normalizeURL returns a canonical form of u for deduplication: lowercase
+// scheme and host, no default port, no fragment, "/" for an empty path and
+// sorted query parameters.
+func normalizeURL(u *url.URL, stripQuery bool) string {
+	n := *u
+	n.Scheme = strings.ToLower(n.Scheme)
+	host := strings.ToLower(n.Hostname())
+	if port := n.Port(); port != "" && !(n.Scheme == "http" && port == "80") && !(n.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	n.Host = host
+	n.Fragment, n.RawFragment = "", ""
+	n.User = nil
+	if n.Path == "" {
+		n.Path = "/"
+	}
+	if stripQuery {
+		n.RawQuery = ""
+	} else if n.RawQuery != "" {
+		q := n.Query()
+		for _, v := range q {
+			sort.Strings(v)
+		}
+		n.RawQuery = q.Encode()
+	}
+	return n.String()
+}
+
+// crawlHost returns host without a leading "www.".
+func crawlHost(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
@@ -0,0 +1,130 @@
+package snapapi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CredentialProvider supplies the API key for each request. It is consulted
+// once per HTTP attempt, so a provider can rotate keys in a long-running
+// process without rebuilding the Client. Implementations must be safe for
+// concurrent use.
+//
+// Set a provider with WithCredentials. The key passed to New is used as a
+// StaticCredentials provider when none is set.
+type CredentialProvider interface {
+	// APIKey returns the API key to authenticate the next request with.
+	APIKey(ctx context.Context) (string, error)
+}
+
+// StaticCredentials is a CredentialProvider that always returns the same key.
+type StaticCredentials string
+
+// APIKey returns the key.
+func (s StaticCredentials) APIKey(context.Context) (string, error) {
+	if s == "" {
+		return "", fmt.Errorf("snapapi: empty API key")
+	}
+	return string(s), nil
+}
+
+// EnvCredentials is a CredentialProvider that reads the key from the named
+// environment variable on every request.
+//
+//	client := snapapi.New("", snapapi.WithCredentials(snapapi.EnvCredentials("SNAPAPI_KEY")))
+type EnvCredentials string
+
+// APIKey returns the current value of the environment variable.
+func (e EnvCredentials) APIKey(context.Context) (string, error) {
+	key := strings.TrimSpace(os.Getenv(string(e)))
+	if key == "" {
+		return "", fmt.Errorf("snapapi: environment variable %s is not set", string(e))
+	}
+	return key, nil
+}
+
+// CredentialsFunc adapts an ordinary function to the CredentialProvider
+// interface, e.g. to fetch keys from a secret manager.
+//
+//	creds := snapapi.CredentialsFunc(func(ctx context.Context) (string, error) {
+//	    return vault.Get(ctx, "snapapi-key")
+//	})
+type CredentialsFunc func(ctx context.Context) (string, error)
+
+// APIKey calls f(ctx).
+func (f CredentialsFunc) APIKey(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// FileCredentials is a CredentialProvider that reads the key from a file and
+// re-reads it whenever the file's modification time or size changes, so a
+// key written by a secret-rotation sidecar (e.g. a mounted Kubernetes
+// secret) is picked up on the next request. Surrounding whitespace is
+// trimmed.
+type FileCredentials struct {
+	path string
+
+	mu      sync.Mutex
+	key     string
+	modTime time.Time
+	size    int64
+}
+
+// NewFileCredentials returns a FileCredentials provider for path.
+//
+//	client := snapapi.New("", snapapi.WithCredentials(snapapi.NewFileCredentials("/etc/snapapi/key")))
+func NewFileCredentials(path string) *FileCredentials {
+	return &FileCredentials{path: path}
+}
+
+// APIKey returns the key currently stored in the file. If the file becomes
+// unreadable after a key has been loaded, the last good key is returned.
+func (f *FileCredentials) APIKey(context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, err := os.Stat(f.path)
+	if err != nil {
+		if f.key != "" {
+			return f.key, nil
+		}
+		return "", fmt.Errorf("snapapi: read credentials file: %w", err)
+	}
+	if f.key != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.key, nil
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if f.key != "" {
+			return f.key, nil
+		}
+		return "", fmt.Errorf("snapapi: read credentials file: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		if f.key != "" {
+			return f.key, nil
+		}
+		return "", fmt.Errorf("snapapi: credentials file %s is empty", f.path)
+	}
+	f.key, f.modTime, f.size = key, info.ModTime(), info.Size()
+	return key, nil
+}
+
+// AuthMode selects how the API key is sent. Modes can be combined with |.
+type AuthMode int
+
+const (
+	// AuthAPIKeyHeader sends the key in the X-Api-Key header.
+	AuthAPIKeyHeader AuthMode = 1 << iota
+	// AuthBearer sends the key as "Authorization: Bearer <key>".
+	AuthBearer
+	// AuthQueryParam sends the key as the access_key query parameter.
+	AuthQueryParam
+
+	// AuthDefault sends both X-Api-Key and Authorization headers.
+	AuthDefault = AuthAPIKeyHeader | AuthBearer
+)
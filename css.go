@@ -0,0 +1,560 @@
+package snapapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// This file implements the subset of CSS selectors used by the client-side
+// HTML helpers: type, universal, #id, .class and attribute selectors
+// ([a], [a=v], [a~=v], [a^=v], [a$=v], [a*=v], [a|=v], optionally with an
+// " i" flag), the :first-child, :last-child, :only-child, :nth-child(),
+// :nth-last-child(), :first-of-type, :last-of-type, :nth-of-type(), :empty
+// and :not() pseudo-classes, the descendant, ">", "+" and "~" combinators,
+// and comma-separated selector lists.
+
+// cssSelector is a parsed selector list.
+type cssSelector []cssComplex
+
+// cssComplex is a chain of compound selectors joined by combinators, stored
+// right to left: parts[0] is the subject.
+type cssComplex struct {
+	parts       []cssCompound
+	combinators []byte // combinators[i] joins parts[i] to parts[i+1]
+}
+
+type cssCompound struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []cssAttr
+	pseudos []cssPseudo
+}
+
+type cssAttr struct {
+	name, op, value string
+	fold            bool
+}
+
+type cssPseudo struct {
+	name string
+	a, b int          // for nth-*
+	not  *cssSelector // for :not()
+}
+
+// compileCSS parses a selector list.
+func compileCSS(sel string) (cssSelector, error) {
+	p := &cssParser{s: sel}
+	out, err := p.list()
+	if err != nil {
+		return nil, err
+	}
+	p.space()
+	if p.i < len(p.s) {
+		return nil, p.errorf("unexpected %q", p.s[p.i:])
+	}
+	return out, nil
+}
+
+type cssParser struct {
+	s string
+	i int
+}
+
+func (p *cssParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid selector %q: %s", p.s, fmt.Sprintf(format, args...))
+}
+
+func (p *cssParser) space() bool {
+	start := p.i
+	for p.i < len(p.s) && isHTMLSpace(p.s[p.i]) {
+		p.i++
+	}
+	return p.i > start
+}
+
+func (p *cssParser) list() (cssSelector, error) {
+	var out cssSelector
+	for {
+		p.space()
+		c, err := p.complex()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+		p.space()
+		if p.i < len(p.s) && p.s[p.i] == ',' {
+			p.i++
+			continue
+		}
+		return out, nil
+	}
+}
+
+func (p *cssParser) complex() (cssComplex, error) {
+	var (
+		parts []cssCompound
+		combs []byte
+	)
+	for {
+		c, err := p.compound()
+		if err != nil {
+			return cssComplex{}, err
+		}
+		parts = append(parts, c)
+		hadSpace := p.space()
+		if p.i >= len(p.s) || p.s[p.i] == ',' || p.s[p.i] == ')' {
+			break
+		}
+		comb := byte(' ')
+		switch p.s[p.i] {
+		case '>', '+', '~':
+			comb = p.s[p.i]
+			p.i++
+			p.space()
+		default:
+			if !hadSpace {
+				return cssComplex{}, p.errorf("unexpected %q", p.s[p.i:])
+			}
+		}
+		combs = append(combs, comb)
+	}
+	// Reverse so the subject comes first.
+	out := cssComplex{}
+	for i := len(parts) - 1; i >= 0; i-- {
+		out.parts = append(out.parts, parts[i])
+	}
+	for i := len(combs) - 1; i >= 0; i-- {
+		out.combinators = append(out.combinators, combs[i])
+	}
+	return out, nil
+}
+
+func (p *cssParser) ident() string {
+	start := p.i
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		if c == '\\' && p.i+1 < len(p.s) {
+			p.i += 2
+			continue
+		}
+		if c == '-' || c == '_' || c >= 0x80 || (c >= '0' && c <= '9') || isASCIILetter(c) {
+			p.i++
+			continue
+		}
+		break
+	}
+	return strings.ReplaceAll(p.s[start:p.i], "\\", "")
+}
+
+func (p *cssParser) compound() (cssCompound, error) {
+	var c cssCompound
+	start := p.i
+	if p.i < len(p.s) && p.s[p.i] == '*' {
+		p.i++
+	} else if p.i < len(p.s) && (isASCIILetter(p.s[p.i]) || p.s[p.i] == '_') {
+		c.tag = strings.ToLower(p.ident())
+	}
+	for p.i < len(p.s) {
+		switch p.s[p.i] {
+		case '#':
+			p.i++
+			if c.id = p.ident(); c.id == "" {
+				return c, p.errorf("empty id")
+			}
+		case '.':
+			p.i++
+			class := p.ident()
+			if class == "" {
+				return c, p.errorf("empty class")
+			}
+			c.classes = append(c.classes, class)
+		case '[':
+			a, err := p.attr()
+			if err != nil {
+				return c, err
+			}
+			c.attrs = append(c.attrs, a)
+		case ':':
+			ps, err := p.pseudo()
+			if err != nil {
+				return c, err
+			}
+			c.pseudos = append(c.pseudos, ps)
+		default:
+			goto done
+		}
+	}
+done:
+	if p.i == start {
+		if p.i >= len(p.s) {
+			return c, p.errorf("unexpected end")
+		}
+		return c, p.errorf("unexpected %q", p.s[p.i:])
+	}
+	return c, nil
+}
+
+func (p *cssParser) attr() (cssAttr, error) {
+	p.i++ // [
+	p.space()
+	a := cssAttr{name: strings.ToLower(p.ident())}
+	if a.name == "" {
+		return a, p.errorf("empty attribute name")
+	}
+	p.space()
+	if p.i < len(p.s) && p.s[p.i] == ']' {
+		p.i++
+		return a, nil
+	}
+	for _, op := range []string{"~=", "^=", "$=", "*=", "|=", "="} {
+		if strings.HasPrefix(p.s[p.i:], op) {
+			a.op = op
+			p.i += len(op)
+			break
+		}
+	}
+	if a.op == "" {
+		return a, p.errorf("bad attribute selector")
+	}
+	p.space()
+	if p.i < len(p.s) && (p.s[p.i] == '"' || p.s[p.i] == '\'') {
+		q := p.s[p.i]
+		end := strings.IndexByte(p.s[p.i+1:], q)
+		if end < 0 {
+			return a, p.errorf("unterminated string")
+		}
+		a.value = p.s[p.i+1 : p.i+1+end]
+		p.i += end + 2
+	} else {
+		a.value = p.ident()
+	}
+	p.space()
+	if p.i < len(p.s) && (p.s[p.i] == 'i' || p.s[p.i] == 'I') {
+		a.fold = true
+		p.i++
+		p.space()
+	}
+	if p.i >= len(p.s) || p.s[p.i] != ']' {
+		return a, p.errorf("missing ]")
+	}
+	p.i++
+	return a, nil
+}
+
+func (p *cssParser) pseudo() (cssPseudo, error) {
+	p.i++ // :
+	ps := cssPseudo{name: strings.ToLower(p.ident())}
+	switch ps.name {
+	case "first-child", "last-child", "only-child", "first-of-type", "last-of-type", "only-of-type", "empty", "root":
+		return ps, nil
+	case "nth-child", "nth-last-child", "nth-of-type", "nth-last-of-type", "not":
+	default:
+		return ps, p.errorf("unsupported pseudo-class :%s", ps.name)
+	}
+	if p.i >= len(p.s) || p.s[p.i] != '(' {
+		return ps, p.errorf(":%s needs an argument", ps.name)
+	}
+	p.i++
+	if ps.name == "not" {
+		inner, err := p.list()
+		if err != nil {
+			return ps, err
+		}
+		ps.not = &inner
+	} else {
+		end := strings.IndexByte(p.s[p.i:], ')')
+		if end < 0 {
+			return ps, p.errorf("missing )")
+		}
+		var err error
+		if ps.a, ps.b, err = parseNth(p.s[p.i : p.i+end]); err != nil {
+			return ps, p.errorf("%v", err)
+		}
+		p.i += end
+	}
+	p.space()
+	if p.i >= len(p.s) || p.s[p.i] != ')' {
+		return ps, p.errorf("missing )")
+	}
+	p.i++
+	return ps, nil
+}
+
+// parseNth parses an an+b expression.
+func parseNth(s string) (a, b int, err error) {
+	s = strings.ToLower(strings.ReplaceAll(s, " ", ""))
+	switch s {
+	case "odd":
+		return 2, 1, nil
+	case "even":
+		return 2, 0, nil
+	}
+	n := strings.IndexByte(s, 'n')
+	if n < 0 {
+		b, err = strconv.Atoi(s)
+		return 0, b, err
+	}
+	switch as := s[:n]; as {
+	case "", "+":
+		a = 1
+	case "-":
+		a = -1
+	default:
+		if a, err = strconv.Atoi(as); err != nil {
+			return 0, 0, err
+		}
+	}
+	if rest := s[n+1:]; rest != "" {
+		if b, err = strconv.Atoi(rest); err != nil {
+			return 0, 0, err
+		}
+	}
+	return a, b, nil
+}
+
+// querySelectorAll returns the descendants of root matching sel, in
+// document order.
+func querySelectorAll(root *htmlNode, sel cssSelector) []*htmlNode {
+	var out []*htmlNode
+	root.walk(func(n *htmlNode) bool {
+		if n != root && n.tag != "" && sel.matches(n) {
+			out = append(out, n)
+		}
+		return true
+	})
+	return out
+}
+
+// querySelector returns the first descendant of root matching sel, or nil.
+func querySelector(root *htmlNode, sel cssSelector) *htmlNode {
+	var found *htmlNode
+	root.walk(func(n *htmlNode) bool {
+		if found != nil {
+			return false
+		}
+		if n != root && n.tag != "" && sel.matches(n) {
+			found = n
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+func (s cssSelector) matches(n *htmlNode) bool {
+	for _, c := range s {
+		if c.matches(n, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether n matches parts[i:] of the chain.
+func (c cssComplex) matches(n *htmlNode, i int) bool {
+	if !c.parts[i].matches(n) {
+		return false
+	}
+	if i == len(c.parts)-1 {
+		return true
+	}
+	switch c.combinators[i] {
+	case ' ':
+		for p := n.parent; p != nil && p.tag != "#document"; p = p.parent {
+			if c.matches(p, i+1) {
+				return true
+			}
+		}
+	case '>':
+		if p := n.parent; p != nil && p.tag != "#document" {
+			return c.matches(p, i+1)
+		}
+	case '+':
+		if s := n.prevElement(); s != nil {
+			return c.matches(s, i+1)
+		}
+	case '~':
+		for s := n.prevElement(); s != nil; s = s.prevElement() {
+			if c.matches(s, i+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c cssCompound) matches(n *htmlNode) bool {
+	if n.tag == "" || n.tag == "#document" {
+		return false
+	}
+	if c.tag != "" && c.tag != n.tag {
+		return false
+	}
+	if c.id != "" && n.attr("id") != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		have := strings.Fields(n.attr("class"))
+		for _, want := range c.classes {
+			if !containsString(have, want) {
+				return false
+			}
+		}
+	}
+	for _, a := range c.attrs {
+		if !a.matches(n) {
+			return false
+		}
+	}
+	for _, ps := range c.pseudos {
+		if !ps.matches(n) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a cssAttr) matches(n *htmlNode) bool {
+	if !n.hasAttr(a.name) {
+		return false
+	}
+	v, want := n.attr(a.name), a.value
+	if a.fold {
+		v, want = strings.ToLower(v), strings.ToLower(want)
+	}
+	switch a.op {
+	case "":
+		return true
+	case "=":
+		return v == want
+	case "~=":
+		return containsString(strings.Fields(v), want)
+	case "^=":
+		return want != "" && strings.HasPrefix(v, want)
+	case "$=":
+		return want != "" && strings.HasSuffix(v, want)
+	case "*=":
+		return want != "" && strings.Contains(v, want)
+	case "|=":
+		return v == want || strings.HasPrefix(v, want+"-")
+	}
+	return false
+}
+
+func (ps cssPseudo) matches(n *htmlNode) bool {
+	switch ps.name {
+	case "first-child":
+		return n.prevElement() == nil
+	case "last-child":
+		return n.nextElement() == nil
+	case "only-child":
+		return n.prevElement() == nil && n.nextElement() == nil
+	case "first-of-type":
+		return n.typeIndex(false) == 1
+	case "last-of-type":
+		return n.typeIndex(true) == 1
+	case "only-of-type":
+		return n.typeIndex(false) == 1 && n.typeIndex(true) == 1
+	case "nth-child":
+		return nthMatches(ps.a, ps.b, n.elementIndex(false))
+	case "nth-last-child":
+		return nthMatches(ps.a, ps.b, n.elementIndex(true))
+	case "nth-of-type":
+		return nthMatches(ps.a, ps.b, n.typeIndex(false))
+	case "nth-last-of-type":
+		return nthMatches(ps.a, ps.b, n.typeIndex(true))
+	case "empty":
+		for _, c := range n.children {
+			if c.tag != "" || c.text != "" {
+				return false
+			}
+		}
+		return true
+	case "root":
+		return n.parent != nil && n.parent.tag == "#document"
+	case "not":
+		return !ps.not.matches(n)
+	}
+	return false
+}
+
+// nthMatches reports whether the 1-based index pos is an+b for some n >= 0.
+func nthMatches(a, b, pos int) bool {
+	if a == 0 {
+		return pos == b
+	}
+	d := pos - b
+	return d%a == 0 && d/a >= 0
+}
+
+func (n *htmlNode) siblings() []*htmlNode {
+	if n.parent == nil {
+		return []*htmlNode{n}
+	}
+	return n.parent.children
+}
+
+func (n *htmlNode) prevElement() *htmlNode {
+	var prev *htmlNode
+	for _, s := range n.siblings() {
+		if s == n {
+			return prev
+		}
+		if s.tag != "" {
+			prev = s
+		}
+	}
+	return nil
+}
+
+func (n *htmlNode) nextElement() *htmlNode {
+	found := false
+	for _, s := range n.siblings() {
+		if s == n {
+			found = true
+		} else if found && s.tag != "" {
+			return s
+		}
+	}
+	return nil
+}
+
+// elementIndex returns n's 1-based position among its element siblings,
+// counted from the end if fromEnd.
+func (n *htmlNode) elementIndex(fromEnd bool) int {
+	return n.siblingIndex(fromEnd, func(s *htmlNode) bool { return s.tag != "" })
+}
+
+// typeIndex is like elementIndex but counts only siblings of n's type.
+func (n *htmlNode) typeIndex(fromEnd bool) int {
+	return n.siblingIndex(fromEnd, func(s *htmlNode) bool { return s.tag == n.tag })
+}
+
+func (n *htmlNode) siblingIndex(fromEnd bool, count func(*htmlNode) bool) int {
+	sibs := n.siblings()
+	idx := 0
+	for i := range sibs {
+		s := sibs[i]
+		if fromEnd {
+			s = sibs[len(sibs)-1-i]
+		}
+		if count(s) {
+			idx++
+		}
+		if s == n {
+			return idx
+		}
+	}
+	return 0
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -27,6 +27,9 @@ var (
 	ErrServer = errors.New("snapapi: server error")
 	// ErrNetwork is the sentinel for network-level connection failures.
 	ErrNetwork = errors.New("snapapi: network error")
+	// ErrBudgetExceeded is the sentinel for requests refused locally by a
+	// Budget's hard limit. The concrete error is a *BudgetExceededError.
+	ErrBudgetExceeded = errors.New("snapapi: budget exceeded")
 )
 
 // Error code constants returned in APIError.Code.
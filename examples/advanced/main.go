@@ -31,18 +31,32 @@ func main() {
 		"https://example.com",
 		"https://example.org",
 	}
+	var items []snapapi.BatchItem
 	for _, u := range monitorURLs {
-		_, err := client.ScreenshotToFile(ctx, fmt.Sprintf("monitor_%s.png", sanitize(u)), snapapi.ScreenshotParams{
+		items = append(items, snapapi.BatchItem{ID: u, Params: snapapi.ScreenshotParams{
 			URL:      u,
 			Format:   "png",
 			FullPage: true,
 			Width:    1280,
-		})
-		if err != nil {
-			log.Printf("Failed to capture %s: %v", u, err)
+		}})
+	}
+	results, err := client.Batch(ctx, items, snapapi.BatchOptions{
+		Concurrency:    4,
+		PerItemTimeout: 90 * time.Second,
+	})
+	if errors.Is(err, snapapi.ErrRateLimit) {
+		log.Printf("Some captures were rate limited")
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			log.Printf("Failed to capture %s: %v", r.ID, r.Err)
+			continue
+		}
+		if err := os.WriteFile(fmt.Sprintf("monitor_%s.png", sanitize(r.ID)), r.Data, 0644); err != nil {
+			log.Printf("Failed to write %s: %v", r.ID, err)
 			continue
 		}
-		fmt.Printf("Captured: %s\n", u)
+		fmt.Printf("Captured: %s\n", r.ID)
 	}
 
 	// --- Use case 2: SEO audit - extract content for analysis ---
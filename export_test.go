@@ -0,0 +1,21 @@
+package snapapi
+
+// SetQueueJournal replaces the journal a Queue writes to.
+func SetQueueJournal(q *Queue, w interface {
+	Write(p []byte) (int, error)
+	Sync() error
+	Close() error
+}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.f.Close()
+	q.f = w
+}
+
+// SetMaxSitemapSize changes the sitemap size cap until the returned
+// function is called.
+func SetMaxSitemapSize(n int64) (restore func()) {
+	prev := maxSitemapSize
+	maxSitemapSize = n
+	return func() { maxSitemapSize = prev }
+}
@@ -3,6 +3,7 @@ package snapapi
 import (
 	"context"
 	"net/http"
+	"time"
 )
 
 // ExtractParams holds all parameters for the Extract endpoint.
@@ -45,11 +46,17 @@ type ExtractResult struct {
 	// URL is the final URL after any redirects.
 	URL string `json:"url"`
 	// WordCount is the approximate number of words in the extracted content.
+	// It is the same as Stats.Words.
 	WordCount int `json:"word_count"`
 	// ResponseTime is the server-side render duration in milliseconds.
 	ResponseTime int `json:"responseTime"`
 	// Type is the output format that was actually returned (e.g. "markdown").
 	Type string `json:"type"`
+	// FetchedAt is when the SDK received the result.
+	FetchedAt time.Time `json:"fetched_at"`
+	// Stats describes the content: counts, reading time, language, heading
+	// outline and a content hash. It is computed by the SDK from Content.
+	Stats DocumentStats `json:"stats"`
 }
 
 // Extract extracts readable content from a URL, suitable for LLM consumption.
@@ -59,6 +66,7 @@ type ExtractResult struct {
 //	    Format: "markdown",
 //	})
 //	fmt.Println(content.Content)
+//	fmt.Println(content.WordCount, content.Stats.ReadingTime, content.Stats.Language)
 func (c *Client) Extract(ctx context.Context, p ExtractParams) (*ExtractResult, error) {
 	if p.URL == "" {
 		return nil, &APIError{Code: ErrInvalidParams, Message: "URL is required", StatusCode: 400}
@@ -66,14 +74,22 @@ func (c *Client) Extract(ctx context.Context, p ExtractParams) (*ExtractResult,
 	// The API returns {"success":true,"type":"markdown","url":"...","data":"..."}.
 	// We map the "data" field to Content for a consistent SDK interface.
 	var raw extractAPIResponse
-	if err := c.doJSON(ctx, http.MethodPost, "/v1/extract", p, &raw); err != nil {
+	if err := c.doBillableJSON(ctx, "extract", http.MethodPost, "/v1/extract", p, &raw); err != nil {
 		return nil, err
 	}
+	format := raw.Type
+	if format == "" {
+		format = p.Format
+	}
+	stats := ComputeDocumentStats(raw.Data, format)
 	return &ExtractResult{
 		Content:      raw.Data,
 		URL:          raw.URL,
+		WordCount:    stats.Words,
 		ResponseTime: raw.ResponseTime,
 		Type:         raw.Type,
+		FetchedAt:    time.Now(),
+		Stats:        stats,
 	}, nil
 }
 
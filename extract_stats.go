@@ -0,0 +1,395 @@
+package snapapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DocumentStats describes extracted content, so callers can decide what to
+// keep without parsing it again.
+type DocumentStats struct {
+	// Words is the number of words in the text. Each Chinese or Japanese
+	// character counts as a word, since those languages do not separate
+	// words with spaces.
+	Words int `json:"words"`
+	// Characters is the number of characters in the text, without markup
+	// and with runs of whitespace counted once.
+	Characters int `json:"characters"`
+	// ReadingTime is the estimated time to read the text at 238 words a
+	// minute, rounded to the second.
+	ReadingTime time.Duration `json:"readingTime"`
+	// Language is the detected ISO 639-1 language code ("en", "de", "ja"),
+	// or "" when the text is too short or ambiguous. For HTML the lang
+	// attribute of <html> takes precedence.
+	Language string `json:"language,omitempty"`
+	// Headings is the document outline in order. It is empty for plain text.
+	Headings []DocumentHeading `json:"headings,omitempty"`
+	// Links is the number of links, not counting images.
+	Links int `json:"links"`
+	// Images is the number of images.
+	Images int `json:"images"`
+	// ContentHash is the hex SHA-256 of the content as returned.
+	ContentHash string `json:"contentHash"`
+}
+
+// DocumentHeading is one entry of a document outline.
+type DocumentHeading struct {
+	// Level is 1 for a top-level heading through 6.
+	Level int `json:"level"`
+	// Text is the heading text without markup.
+	Text string `json:"text"`
+	// Anchor is the fragment that links to the heading: its id in HTML, or
+	// a GitHub-style slug of the text ("Getting started" -> "getting-started"),
+	// made unique with a "-1", "-2" suffix.
+	Anchor string `json:"anchor"`
+}
+
+// wordsPerMinute is the average silent reading speed for English prose.
+const wordsPerMinute = 238
+
+// ComputeDocumentStats returns the statistics of content in the given
+// format: "markdown" (the default), "html" or "text". Extract fills
+// ExtractResult.Stats with it; use it directly for content from elsewhere.
+//
+//	stats := snapapi.ComputeDocumentStats(md, "markdown")
+//	if stats.Language == "en" && stats.Words > 300 {
+//	    ingest(md)
+//	}
+func ComputeDocumentStats(content, format string) DocumentStats {
+	var d docBuilder
+	switch format {
+	case "html":
+		d.html(content)
+	case "text", "json":
+		d.text.WriteString(content)
+	default:
+		d.markdown(content)
+	}
+
+	text := d.text.String()
+	sum := sha256.Sum256([]byte(content))
+	stats := DocumentStats{
+		Words:       countWords(text),
+		Characters:  len([]rune(collapseSpace(text))),
+		Headings:    d.headings,
+		Links:       d.links,
+		Images:      d.images,
+		ContentHash: hex.EncodeToString(sum[:]),
+		Language:    d.lang,
+	}
+	stats.ReadingTime = (time.Duration(stats.Words) * time.Minute / wordsPerMinute).Round(time.Second)
+	if stats.Language == "" {
+		stats.Language = detectLanguage(text)
+	}
+	return stats
+}
+
+// docBuilder collects the plain text and structure of a document.
+type docBuilder struct {
+	text     strings.Builder
+	headings []DocumentHeading
+	anchors  map[string]int
+	links    int
+	images   int
+	lang     string
+}
+
+func (d *docBuilder) heading(level int, text, id string) {
+	if d.anchors == nil {
+		d.anchors = make(map[string]int)
+	}
+	anchor := id
+	if anchor == "" {
+		anchor = slugify(text)
+		if n := d.anchors[anchor]; n > 0 {
+			anchor += "-" + strconv.Itoa(n)
+		}
+	}
+	d.anchors[anchor]++
+	d.headings = append(d.headings, DocumentHeading{Level: level, Text: text, Anchor: anchor})
+}
+
+// htmlBlockText are elements whose content is separated from the text
+// around it.
+var htmlBlockText = map[string]bool{
+	"br": true, "li": true, "td": true, "th": true, "tr": true, "dt": true,
+	"dd": true, "caption": true, "body": true, "title": true,
+}
+
+func (d *docBuilder) html(s string) {
+	doc := parseHTML(s)
+	if h := doc.find("html"); h != nil {
+		d.lang = normalizeLang(h.attr("lang"))
+	}
+	root := doc
+	if b := doc.find("body"); b != nil {
+		root = b
+	}
+	d.htmlText(root)
+}
+
+func (d *docBuilder) htmlText(n *htmlNode) {
+	switch n.tag {
+	case "":
+		d.text.WriteString(n.text)
+		return
+	case "script", "style", "template", "noscript", "head":
+		return
+	case "a":
+		if n.hasAttr("href") {
+			d.links++
+		}
+	case "img":
+		d.images++
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		d.heading(int(n.tag[1]-'0'), n.innerText(), n.attr("id"))
+	}
+	block := htmlClosesP[n.tag] || htmlBlockText[n.tag]
+	if block {
+		d.text.WriteByte('\n')
+	}
+	for _, c := range n.children {
+		d.htmlText(c)
+	}
+	if block {
+		d.text.WriteByte('\n')
+	}
+}
+
+var (
+	mdFence     = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+	mdATX       = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	mdSetext    = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	mdBlock     = regexp.MustCompile(`^ {0,3}(?:>[ \t]?)*(?:[-*+][ \t]+|\d{1,9}[.)][ \t]+)?`)
+	mdImage     = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink      = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	mdRefLink   = regexp.MustCompile(`\[([^\]]+)\]\[[^\]]*\]`)
+	mdAutolink  = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	mdEmphasis  = regexp.MustCompile("\\*+|~~|`+|\\|")
+	mdTableRule = regexp.MustCompile(`^[\s|:-]+$`)
+)
+
+func (d *docBuilder) markdown(s string) {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	fence := ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "":
+				fence = m[1]
+				continue
+			case m[1][0] == fence[0] && len(m[1]) >= len(fence):
+				fence = ""
+				continue
+			}
+		}
+		if fence != "" {
+			d.text.WriteString(line + "\n")
+			continue
+		}
+
+		if m := mdATX.FindStringSubmatch(line); m != nil {
+			text := d.inline(m[2])
+			d.heading(len(m[1]), text, "")
+			d.text.WriteString(text + "\n")
+			continue
+		}
+		if i+1 < len(lines) && strings.TrimSpace(line) != "" && !mdTableRule.MatchString(line) {
+			if m := mdSetext.FindStringSubmatch(lines[i+1]); m != nil && mdBlock.FindString(line) == "" {
+				level := 1
+				if m[1][0] == '-' {
+					level = 2
+				}
+				text := d.inline(line)
+				d.heading(level, text, "")
+				d.text.WriteString(text + "\n")
+				i++
+				continue
+			}
+		}
+		if mdTableRule.MatchString(line) {
+			// Table delimiter rows and thematic breaks.
+			d.text.WriteString("\n")
+			continue
+		}
+		d.text.WriteString(d.inline(mdBlock.ReplaceAllString(line, "")) + "\n")
+	}
+}
+
+// inline counts the links and images of a line of Markdown and returns its
+// text.
+func (d *docBuilder) inline(s string) string {
+	s = mdImage.ReplaceAllStringFunc(s, func(m string) string {
+		d.images++
+		return mdImage.FindStringSubmatch(m)[1]
+	})
+	for _, re := range []*regexp.Regexp{mdLink, mdRefLink, mdAutolink} {
+		s = re.ReplaceAllStringFunc(s, func(m string) string {
+			d.links++
+			return re.FindStringSubmatch(m)[1]
+		})
+	}
+	return collapseSpace(mdEmphasis.ReplaceAllString(s, " "))
+}
+
+// isCJK reports whether r is written without spaces between words.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+// countWords counts whitespace-separated tokens that contain a letter or
+// digit, plus one word per Chinese or Japanese character.
+func countWords(s string) int {
+	n := 0
+	for _, f := range strings.Fields(s) {
+		word := false
+		for _, r := range f {
+			switch {
+			case isCJK(r):
+				n++
+			case unicode.IsLetter(r) || unicode.IsDigit(r):
+				word = true
+			}
+		}
+		if word {
+			n++
+		}
+	}
+	return n
+}
+
+// slugify returns the GitHub-style anchor of a heading: lowercased, with
+// punctuation removed and spaces turned into hyphens.
+func slugify(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+func normalizeLang(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if i := strings.IndexAny(s, "-_"); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+// languageScripts maps scripts used by a single common language to it.
+var languageScripts = []struct {
+	script *unicode.RangeTable
+	lang   string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+// stopwords are frequent short words of languages written in Latin script.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "was", "on", "are", "this", "you"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "den", "ein", "zu", "sich", "auf", "für", "eine", "ich"},
+	"fr": {"le", "la", "les", "et", "est", "des", "une", "que", "dans", "pour", "pas", "qui", "sur", "du", "au"},
+	"es": {"el", "la", "de", "que", "y", "los", "en", "las", "por", "una", "para", "con", "es", "del", "se"},
+	"it": {"il", "di", "che", "la", "e", "per", "una", "sono", "non", "del", "della", "con", "gli", "le", "un"},
+	"pt": {"de", "que", "não", "uma", "os", "para", "com", "da", "do", "em", "as", "se", "por", "mais", "é"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "te", "zijn", "voor", "met", "die", "ook"},
+}
+
+// stopwordLangs maps each stopword to the languages it belongs to.
+var stopwordLangs = func() map[string][]string {
+	m := make(map[string][]string)
+	for lang, words := range stopwords {
+		for _, w := range words {
+			m[w] = append(m[w], lang)
+		}
+	}
+	return m
+}()
+
+// detectLanguage guesses the language of text from its script or, for
+// Latin script, from the frequency of common words.
+func detectLanguage(text string) string {
+	var letters, latin, kana, han int
+	scripts := make([]int, len(languageScripts))
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		default:
+			for i, s := range languageScripts {
+				if unicode.Is(s.script, r) {
+					scripts[i]++
+					break
+				}
+			}
+		}
+	}
+	if letters < 20 {
+		return ""
+	}
+	if kana+han > letters/2 {
+		if kana > 0 {
+			return "ja"
+		}
+		return "zh"
+	}
+	for i, n := range scripts {
+		if n > letters/2 {
+			return languageScripts[i].lang
+		}
+	}
+	if latin <= letters/2 {
+		return ""
+	}
+
+	scores := make(map[string]int)
+	words := 0
+	for _, f := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		words++
+		for _, lang := range stopwordLangs[f] {
+			scores[lang]++
+		}
+	}
+	best, bestScore, second := "", 0, 0
+	for lang, n := range scores {
+		switch {
+		case n > bestScore || (n == bestScore && lang < best):
+			second = bestScore
+			best, bestScore = lang, n
+		case n > second:
+			second = n
+		}
+	}
+	// Require enough evidence and a clear winner.
+	if bestScore < 3 || bestScore*20 < words || bestScore == second {
+		return ""
+	}
+	return best
+}
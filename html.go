@@ -0,0 +1,430 @@
+package snapapi
+
+import (
+	"html"
+	"strings"
+)
+
+// This file holds a small, forgiving HTML parser used by the client-side
+// helpers that work on scraped pages (crawling, metadata, tables, links).
+// It builds a tree that is close enough to what a browser would build for
+// real-world pages, without pulling in a dependency.
+
+// htmlNode is an element or text node. The document root has tag
+// "#document"; text nodes have an empty tag.
+type htmlNode struct {
+	tag      string
+	attrs    []htmlAttr
+	text     string
+	parent   *htmlNode
+	children []*htmlNode
+}
+
+type htmlAttr struct {
+	name, value string
+}
+
+// htmlVoid elements never have children or end tags.
+var htmlVoid = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+// htmlRawText elements contain text up to their end tag, without markup.
+var htmlRawText = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true,
+	"xmp": true, "iframe": true, "noembed": true,
+}
+
+// htmlClosesP are start tags that implicitly close an open <p>.
+var htmlClosesP = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"div": true, "dl": true, "fieldset": true, "figure": true, "footer": true,
+	"form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "header": true, "hr": true, "main": true, "nav": true,
+	"ol": true, "p": true, "pre": true, "section": true, "table": true,
+	"ul": true, "details": true, "figcaption": true, "menu": true,
+}
+
+// parseHTML parses an HTML document or fragment.
+func parseHTML(s string) *htmlNode {
+	root := &htmlNode{tag: "#document"}
+	p := htmlParser{src: s, stack: []*htmlNode{root}}
+	p.parse()
+	return root
+}
+
+type htmlParser struct {
+	src   string
+	pos   int
+	stack []*htmlNode
+}
+
+func (p *htmlParser) current() *htmlNode { return p.stack[len(p.stack)-1] }
+
+func (p *htmlParser) parse() {
+	for p.pos < len(p.src) {
+		lt := strings.IndexByte(p.src[p.pos:], '<')
+		if lt < 0 {
+			p.addText(p.src[p.pos:])
+			return
+		}
+		if lt > 0 {
+			p.addText(p.src[p.pos : p.pos+lt])
+			p.pos += lt
+		}
+		rest := p.src[p.pos:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				p.pos = len(p.src)
+			} else {
+				p.pos += 4 + end + 3
+			}
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				p.pos = len(p.src)
+			} else {
+				p.pos += end + 1
+			}
+		case strings.HasPrefix(rest, "</"):
+			p.endTag()
+		case len(rest) > 1 && isASCIILetter(rest[1]):
+			p.startTag()
+		default:
+			p.addText("<")
+			p.pos++
+		}
+	}
+}
+
+func (p *htmlParser) addText(s string) {
+	if s == "" {
+		return
+	}
+	cur := p.current()
+	text := html.UnescapeString(s)
+	if n := len(cur.children); n > 0 && cur.children[n-1].tag == "" {
+		cur.children[n-1].text += text
+		return
+	}
+	cur.children = append(cur.children, &htmlNode{text: text, parent: cur})
+}
+
+func (p *htmlParser) endTag() {
+	end := strings.IndexByte(p.src[p.pos:], '>')
+	if end < 0 {
+		p.pos = len(p.src)
+		return
+	}
+	name := strings.ToLower(strings.TrimSpace(p.src[p.pos+2 : p.pos+end]))
+	if i := strings.IndexAny(name, " \t\r\n/"); i >= 0 {
+		name = name[:i]
+	}
+	p.pos += end + 1
+	for i := len(p.stack) - 1; i > 0; i-- {
+		if p.stack[i].tag == name {
+			p.stack = p.stack[:i]
+			return
+		}
+		if name != "p" && isHTMLScope(p.stack[i].tag) && !isHTMLScope(name) {
+			// Don't let a stray end tag close a table or list it is not in.
+			return
+		}
+	}
+}
+
+func (p *htmlParser) startTag() {
+	s := p.src
+	i := p.pos + 1
+	start := i
+	for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' && s[i] != '/' {
+		i++
+	}
+	n := &htmlNode{tag: strings.ToLower(s[start:i])}
+	selfClosing := false
+	for i < len(s) {
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+		if s[i] == '>' {
+			i++
+			break
+		}
+		if s[i] == '/' {
+			selfClosing = true
+			i++
+			continue
+		}
+		nameStart := i
+		for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		name := strings.ToLower(s[nameStart:i])
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		value := ""
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isHTMLSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				q := s[i]
+				end := strings.IndexByte(s[i+1:], q)
+				if end < 0 {
+					value, i = s[i+1:], len(s)
+				} else {
+					value, i = s[i+1:i+1+end], i+1+end+1
+				}
+			} else {
+				valStart := i
+				for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				value = s[valStart:i]
+			}
+		}
+		if name != "" && n.attr(name) == "" {
+			n.attrs = append(n.attrs, htmlAttr{name: name, value: html.UnescapeString(value)})
+		}
+	}
+	p.pos = i
+
+	p.implicitClose(n.tag)
+	cur := p.current()
+	n.parent = cur
+	cur.children = append(cur.children, n)
+	if htmlVoid[n.tag] || (selfClosing && !htmlRawText[n.tag]) {
+		return
+	}
+	if htmlRawText[n.tag] {
+		end := indexEndTag(s[p.pos:], n.tag)
+		text := s[p.pos : p.pos+end]
+		if n.tag == "title" || n.tag == "textarea" {
+			text = html.UnescapeString(text)
+		}
+		if text != "" {
+			n.children = append(n.children, &htmlNode{text: text, parent: n})
+		}
+		p.pos += end
+		if gt := strings.IndexByte(s[p.pos:], '>'); gt >= 0 {
+			p.pos += gt + 1
+		} else {
+			p.pos = len(s)
+		}
+		return
+	}
+	p.stack = append(p.stack, n)
+}
+
+// indexEndTag returns the index of the first "</tag" in s, matched
+// case-insensitively, or len(s).
+func indexEndTag(s, tag string) int {
+	for i := 0; ; {
+		j := strings.Index(s[i:], "</")
+		if j < 0 {
+			return len(s)
+		}
+		i += j
+		if end := i + 2 + len(tag); end <= len(s) && strings.EqualFold(s[i+2:end], tag) {
+			return i
+		}
+		i += 2
+	}
+}
+
+// implicitClose pops elements that the start tag tag implicitly ends.
+func (p *htmlParser) implicitClose(tag string) {
+	closeTo := func(names map[string]bool, stopAt map[string]bool) {
+		for i := len(p.stack) - 1; i > 0; i-- {
+			t := p.stack[i].tag
+			if stopAt[t] {
+				return
+			}
+			if names[t] {
+				p.stack = p.stack[:i]
+				return
+			}
+		}
+	}
+	if htmlClosesP[tag] {
+		closeTo(map[string]bool{"p": true}, map[string]bool{"button": true, "table": true, "td": true, "th": true, "li": true, "div": true, "section": true, "article": true})
+	}
+	switch tag {
+	case "li":
+		closeTo(map[string]bool{"li": true}, map[string]bool{"ul": true, "ol": true, "menu": true})
+	case "dt", "dd":
+		closeTo(map[string]bool{"dt": true, "dd": true}, map[string]bool{"dl": true})
+	case "option":
+		closeTo(map[string]bool{"option": true}, map[string]bool{"select": true, "datalist": true})
+	case "td", "th":
+		closeTo(map[string]bool{"td": true, "th": true}, map[string]bool{"tr": true, "table": true})
+	case "tr":
+		closeTo(map[string]bool{"tr": true}, map[string]bool{"table": true, "thead": true, "tbody": true, "tfoot": true})
+	case "thead", "tbody", "tfoot":
+		closeTo(map[string]bool{"thead": true, "tbody": true, "tfoot": true}, map[string]bool{"table": true})
+	}
+}
+
+// isHTMLScope reports whether tag bounds implicit end-tag matching.
+func isHTMLScope(tag string) bool {
+	switch tag {
+	case "table", "ul", "ol", "td", "th", "tr", "select":
+		return true
+	}
+	return false
+}
+
+func isHTMLSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+func isASCIILetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// attr returns the value of the named attribute, or "".
+func (n *htmlNode) attr(name string) string {
+	for _, a := range n.attrs {
+		if a.name == name {
+			return a.value
+		}
+	}
+	return ""
+}
+
+// hasAttr reports whether the named attribute is present.
+func (n *htmlNode) hasAttr(name string) bool {
+	for _, a := range n.attrs {
+		if a.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// walk calls fn for n and its descendants in document order. Returning
+// false from fn skips the node's children.
+func (n *htmlNode) walk(fn func(*htmlNode) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, c := range n.children {
+		c.walk(fn)
+	}
+}
+
+// findAll returns the descendant elements named tag.
+func (n *htmlNode) findAll(tag string) []*htmlNode {
+	var out []*htmlNode
+	n.walk(func(c *htmlNode) bool {
+		if c != n && c.tag == tag {
+			out = append(out, c)
+		}
+		return true
+	})
+	return out
+}
+
+// find returns the first descendant element named tag, or nil.
+func (n *htmlNode) find(tag string) *htmlNode {
+	var found *htmlNode
+	n.walk(func(c *htmlNode) bool {
+		if found != nil {
+			return false
+		}
+		if c != n && c.tag == tag {
+			found = c
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// textContent returns the concatenated text of n's descendants, excluding
+// scripts and styles.
+func (n *htmlNode) textContent() string {
+	var b strings.Builder
+	n.walk(func(c *htmlNode) bool {
+		switch c.tag {
+		case "":
+			b.WriteString(c.text)
+		case "script", "style", "template":
+			return false
+		}
+		return true
+	})
+	return b.String()
+}
+
+// innerText returns textContent with runs of whitespace collapsed to single
+// spaces and the ends trimmed.
+func (n *htmlNode) innerText() string {
+	return collapseSpace(n.textContent())
+}
+
+// collapseSpace collapses runs of whitespace to single spaces and trims.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// innerHTML serialises n's children.
+func (n *htmlNode) innerHTML() string {
+	var b strings.Builder
+	for _, c := range n.children {
+		c.render(&b)
+	}
+	return b.String()
+}
+
+// outerHTML serialises n and its children.
+func (n *htmlNode) outerHTML() string {
+	var b strings.Builder
+	n.render(&b)
+	return b.String()
+}
+
+func (n *htmlNode) render(b *strings.Builder) {
+	switch n.tag {
+	case "":
+		if p := n.parent; p != nil && htmlRawText[p.tag] && p.tag != "title" && p.tag != "textarea" {
+			b.WriteString(n.text)
+		} else {
+			b.WriteString(html.EscapeString(n.text))
+		}
+		return
+	case "#document":
+		for _, c := range n.children {
+			c.render(b)
+		}
+		return
+	}
+	b.WriteByte('<')
+	b.WriteString(n.tag)
+	for _, a := range n.attrs {
+		b.WriteByte(' ')
+		b.WriteString(a.name)
+		b.WriteString(`="`)
+		b.WriteString(html.EscapeString(a.value))
+		b.WriteByte('"')
+	}
+	b.WriteByte('>')
+	if htmlVoid[n.tag] {
+		return
+	}
+	for _, c := range n.children {
+		c.render(b)
+	}
+	b.WriteString("</")
+	b.WriteString(n.tag)
+	b.WriteByte('>')
+}
@@ -0,0 +1,315 @@
+// Package index is a small full-text search index over pages captured with
+// SnapAPI. Extract results and crawled pages are tokenized into an inverted
+// index that is kept in memory and saved to a single file, and queried with
+// BM25 ranking, highlighted snippets and URL, time and field filters.
+//
+//	ix, err := index.Open("./search")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer ix.Close()
+//	res, err := client.Extract(ctx, snapapi.ExtractParams{URL: "https://example.com/docs/install"})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	ix.AddExtract(res, map[string]string{"section": "docs"})
+//
+//	results, err := ix.Search(`"api key" -deprecated site:example.com`, index.SearchOptions{Limit: 5})
+//	for _, hit := range results.Hits {
+//	    fmt.Printf("%.2f %s\n  %s\n", hit.Score, hit.URL, hit.Snippet)
+//	}
+//
+// An Index is safe for concurrent use, but a directory must not be opened by
+// more than one Index at a time.
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	snapapi "github.com/Sleywill/snapapi-go"
+)
+
+// FileName is the name of the index file inside the directory passed to
+// Open.
+const FileName = "index.gob"
+
+// formatVersion is bumped when the file layout changes.
+const formatVersion = 1
+
+// Document is a page stored in the index.
+type Document struct {
+	// URL identifies the document; adding a document with the same URL
+	// replaces it.
+	URL string
+	// Title is searched together with the content, with a higher weight.
+	Title string
+	// Content is the plain text of the page.
+	Content string
+	// Fields are arbitrary metadata, matched exactly by filters.
+	Fields map[string]string
+	// Time is when the page was captured.
+	Time time.Time
+}
+
+// Index is an inverted index of Documents. Create one with Open or New.
+type Index struct {
+	mu    sync.RWMutex
+	path  string // "" for an in-memory index
+	dirty bool
+	data  indexData
+	byURL map[string]uint32
+}
+
+// indexData is the part of the index that is saved to disk.
+type indexData struct {
+	Version  int
+	NextID   uint32
+	Docs     map[uint32]*docRecord
+	Postings map[string][]posting // sorted by Doc
+	Terms    int64                // total number of terms in all documents
+}
+
+type docRecord struct {
+	Document
+	Length     int // number of terms, title included
+	TitleTerms int // the first TitleTerms positions are the title
+}
+
+// posting lists the positions of a term in a document.
+type posting struct {
+	Doc uint32
+	Pos []uint32
+}
+
+// New returns an empty in-memory index. Save and Close do nothing.
+func New() *Index {
+	return &Index{
+		data:  indexData{Version: formatVersion, Docs: make(map[uint32]*docRecord), Postings: make(map[string][]posting)},
+		byURL: make(map[string]uint32),
+	}
+}
+
+// Open loads the index saved in dir, or returns an empty index if there is
+// none yet. The directory is created by the first Save.
+func Open(dir string) (*Index, error) {
+	ix := New()
+	ix.path = filepath.Join(dir, FileName)
+	data, err := os.ReadFile(ix.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("index: %w", err)
+	}
+	var d indexData
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&d); err != nil {
+		return nil, fmt.Errorf("index: decode %s: %w", ix.path, err)
+	}
+	if d.Version != formatVersion {
+		return nil, fmt.Errorf("index: %s has format version %d, want %d", ix.path, d.Version, formatVersion)
+	}
+	if d.Docs == nil {
+		d.Docs = make(map[uint32]*docRecord)
+	}
+	if d.Postings == nil {
+		d.Postings = make(map[string][]posting)
+	}
+	ix.data = d
+	for id, doc := range d.Docs {
+		ix.byURL[doc.URL] = id
+	}
+	return ix, nil
+}
+
+// Save writes the index to disk if it changed since it was opened or last
+// saved. The file is replaced atomically.
+func (ix *Index) Save() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.path == "" || !ix.dirty {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&ix.data); err != nil {
+		return fmt.Errorf("index: encode: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(ix.path), 0o755); err != nil {
+		return fmt.Errorf("index: %w", err)
+	}
+	tmp := ix.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("index: %w", err)
+	}
+	if err := os.Rename(tmp, ix.path); err != nil {
+		return fmt.Errorf("index: %w", err)
+	}
+	ix.dirty = false
+	return nil
+}
+
+// Close saves the index.
+func (ix *Index) Close() error {
+	return ix.Save()
+}
+
+// Len returns the number of documents.
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.data.Docs)
+}
+
+// Get returns the document with the given URL.
+func (ix *Index) Get(url string) (Document, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	id, ok := ix.byURL[url]
+	if !ok {
+		return Document{}, false
+	}
+	return ix.data.Docs[id].Document, true
+}
+
+// URLs returns the URLs of all documents, sorted.
+func (ix *Index) URLs() []string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	urls := make([]string, 0, len(ix.byURL))
+	for u := range ix.byURL {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// Add indexes doc, replacing any document with the same URL.
+func (ix *Index) Add(doc Document) error {
+	if doc.URL == "" {
+		return errors.New("index: document URL is required")
+	}
+	if doc.Time.IsZero() {
+		doc.Time = time.Now()
+	}
+	title, content := terms(doc.Title), terms(doc.Content)
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if id, ok := ix.byURL[doc.URL]; ok {
+		ix.remove(id)
+	}
+	id := ix.data.NextID
+	ix.data.NextID++
+	positions := make(map[string][]uint32)
+	for i, t := range append(title, content...) {
+		positions[t] = append(positions[t], uint32(i))
+	}
+	for t, pos := range positions {
+		// IDs only grow, so appending keeps the lists sorted.
+		ix.data.Postings[t] = append(ix.data.Postings[t], posting{Doc: id, Pos: pos})
+	}
+	ix.data.Docs[id] = &docRecord{Document: doc, Length: len(title) + len(content), TitleTerms: len(title)}
+	ix.data.Terms += int64(len(title) + len(content))
+	ix.byURL[doc.URL] = id
+	ix.dirty = true
+	return nil
+}
+
+// AddExtract indexes an Extract result of any format. The title is the
+// first top-level heading and the time is res.FetchedAt.
+func (ix *Index) AddExtract(res *snapapi.ExtractResult, fields map[string]string) error {
+	if res == nil {
+		return errors.New("index: nil ExtractResult")
+	}
+	title := ""
+	for _, h := range res.Stats.Headings {
+		if h.Level == 1 {
+			title = h.Text
+			break
+		}
+	}
+	if title == "" && len(res.Stats.Headings) > 0 {
+		title = res.Stats.Headings[0].Text
+	}
+	return ix.Add(Document{
+		URL:     res.URL,
+		Title:   title,
+		Content: plainText(res.Content, res.Type),
+		Fields:  fields,
+		Time:    res.FetchedAt,
+	})
+}
+
+// AddHTML indexes the text of an HTML page, titled by its <title> element.
+func (ix *Index) AddHTML(pageURL, htmlStr string, fields map[string]string) error {
+	title := ""
+	if meta, err := snapapi.ParsePageMetadata(htmlStr, pageURL); err == nil {
+		title = meta.Title
+	}
+	return ix.Add(Document{URL: pageURL, Title: title, Content: plainText(htmlStr, "html"), Fields: fields})
+}
+
+// CrawlFunc returns a snapapi.CrawlFunc that indexes the HTML of every
+// crawled page:
+//
+//	_, err := client.Crawl(ctx, "https://example.com", snapapi.CrawlOptions{MaxPages: 200},
+//	    ix.CrawlFunc(map[string]string{"site": "example"}))
+func (ix *Index) CrawlFunc(fields map[string]string) snapapi.CrawlFunc {
+	return func(_ context.Context, page *snapapi.CrawlPage) error {
+		return ix.AddHTML(page.URL, page.HTML, fields)
+	}
+}
+
+// Delete removes the document with the given URL and reports whether it
+// was indexed.
+func (ix *Index) Delete(url string) bool {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	id, ok := ix.byURL[url]
+	if ok {
+		ix.remove(id)
+		ix.dirty = true
+	}
+	return ok
+}
+
+// remove drops document id from the postings. ix.mu must be held.
+func (ix *Index) remove(id uint32) {
+	doc := ix.data.Docs[id]
+	seen := make(map[string]bool)
+	for _, t := range append(terms(doc.Title), terms(doc.Content)...) {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		list := ix.data.Postings[t]
+		i := sort.Search(len(list), func(i int) bool { return list[i].Doc >= id })
+		if i < len(list) && list[i].Doc == id {
+			list = append(list[:i:i], list[i+1:]...)
+		}
+		if len(list) == 0 {
+			delete(ix.data.Postings, t)
+		} else {
+			ix.data.Postings[t] = list
+		}
+	}
+	ix.data.Terms -= int64(doc.Length)
+	delete(ix.data.Docs, id)
+	delete(ix.byURL, doc.URL)
+}
+
+// matchHost reports whether host is site or one of its subdomains.
+func matchHost(host, site string) bool {
+	host, site = strings.ToLower(host), strings.ToLower(strings.TrimPrefix(site, "www."))
+	host = strings.TrimPrefix(host, "www.")
+	return host == site || strings.HasSuffix(host, "."+site)
+}
@@ -0,0 +1,248 @@
+package index_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	snapapi "github.com/Sleywill/snapapi-go"
+	"github.com/Sleywill/snapapi-go/index"
+)
+
+var day = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func testIndex(t *testing.T, ix *index.Index) {
+	t.Helper()
+	docs := []index.Document{
+		{
+			URL:     "https://example.com/docs/install",
+			Title:   "Installing the CLI",
+			Content: "Download the binary, then set your API key. The API key is shown in the dashboard. Docker images are also available.",
+			Fields:  map[string]string{"section": "docs"},
+			Time:    day,
+		},
+		{
+			URL:     "https://blog.example.com/keys",
+			Title:   "Rotating keys",
+			Content: "Rotate every key regularly. An old API key stops working after a day. The key API is deprecated.",
+			Fields:  map[string]string{"section": "blog"},
+			Time:    day.Add(24 * time.Hour),
+		},
+		{
+			URL:     "https://other.org/docker",
+			Title:   "Docker",
+			Content: "Run the image with docker run. 日本語のテキスト.",
+			Time:    day.Add(48 * time.Hour),
+		},
+	}
+	for _, d := range docs {
+		if err := ix.Add(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func urls(res *index.SearchResult) []string {
+	var out []string
+	for _, h := range res.Hits {
+		out = append(out, h.URL)
+	}
+	return out
+}
+
+func TestSearch(t *testing.T) {
+	ix := index.New()
+	testIndex(t, ix)
+
+	for _, tc := range []struct {
+		query string
+		opts  index.SearchOptions
+		want  []string
+	}{
+		{"docker", index.SearchOptions{}, []string{"https://other.org/docker", "https://example.com/docs/install"}},
+		{"API KEY", index.SearchOptions{}, []string{"https://blog.example.com/keys", "https://example.com/docs/install"}},
+		{`"api key"`, index.SearchOptions{}, []string{"https://blog.example.com/keys", "https://example.com/docs/install"}},
+		{`"key api"`, index.SearchOptions{}, []string{"https://blog.example.com/keys"}},
+		{`api key -deprecated`, index.SearchOptions{}, []string{"https://example.com/docs/install"}},
+		{`key -"key api"`, index.SearchOptions{}, []string{"https://example.com/docs/install"}},
+		{"key site:example.com", index.SearchOptions{}, []string{"https://blog.example.com/keys", "https://example.com/docs/install"}},
+		{"key site:blog.example.com", index.SearchOptions{}, []string{"https://blog.example.com/keys"}},
+		{"key url:https://example.com/docs/", index.SearchOptions{}, []string{"https://example.com/docs/install"}},
+		{"key section:blog", index.SearchOptions{}, []string{"https://blog.example.com/keys"}},
+		{"key", index.SearchOptions{Fields: map[string]string{"section": "docs"}}, []string{"https://example.com/docs/install"}},
+		{"", index.SearchOptions{}, []string{"https://other.org/docker", "https://blog.example.com/keys", "https://example.com/docs/install"}},
+		{"", index.SearchOptions{Since: day.Add(time.Hour), Until: day.Add(48 * time.Hour)}, []string{"https://blog.example.com/keys"}},
+		{"", index.SearchOptions{Offset: 1, Limit: 1}, []string{"https://blog.example.com/keys"}},
+		{"日本", index.SearchOptions{}, []string{"https://other.org/docker"}},
+		{"kubernetes", index.SearchOptions{}, nil},
+	} {
+		res, err := ix.Search(tc.query, tc.opts)
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", tc.query, err)
+		}
+		if got := urls(res); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Search(%q, %+v) = %v, want %v", tc.query, tc.opts, got, tc.want)
+		}
+	}
+
+	if _, err := ix.Search(`"api key`, index.SearchOptions{}); err == nil {
+		t.Error("Search(unterminated quote) succeeded")
+	}
+	res, _ := ix.Search("", index.SearchOptions{Limit: 1})
+	if res.Total != 3 || len(res.Hits) != 1 {
+		t.Errorf("Total = %d, hits = %d", res.Total, len(res.Hits))
+	}
+}
+
+func TestSearch_Snippet(t *testing.T) {
+	ix := index.New()
+	testIndex(t, ix)
+
+	res, _ := ix.Search(`"api key" dashboard`, index.SearchOptions{SnippetWords: 8})
+	if len(res.Hits) != 1 {
+		t.Fatalf("hits = %v", urls(res))
+	}
+	hit := res.Hits[0]
+	if hit.Title != "Installing the CLI" || hit.Fields["section"] != "docs" || !hit.Time.Equal(day) || hit.Score <= 0 {
+		t.Errorf("hit = %+v", hit)
+	}
+	if want := "… **API key** is shown in the **dashboard**. Docker …"; hit.Snippet != want {
+		t.Errorf("Snippet = %q, want %q", hit.Snippet, want)
+	}
+
+	res, _ = ix.Search("download", index.SearchOptions{SnippetWords: 3, HighlightStart: "<b>", HighlightEnd: "</b>"})
+	if want := "<b>Download</b> the binary, …"; len(res.Hits) != 1 || res.Hits[0].Snippet != want {
+		t.Errorf("hits = %+v, want snippet %q", res.Hits, want)
+	}
+}
+
+func TestSearch_Ranking(t *testing.T) {
+	ix := index.New()
+	ix.Add(index.Document{URL: "https://a.example/1", Content: "golang tips and a long text about many other unrelated things"})
+	ix.Add(index.Document{URL: "https://a.example/2", Content: "golang golang golang"})
+	ix.Add(index.Document{URL: "https://a.example/3", Title: "Golang", Content: "a long text about many other unrelated things"})
+	ix.Add(index.Document{URL: "https://a.example/4", Content: "nothing to see"})
+
+	res, _ := ix.Search("golang", index.SearchOptions{})
+	want := []string{"https://a.example/2", "https://a.example/3", "https://a.example/1"}
+	if got := urls(res); !reflect.DeepEqual(got, want) {
+		t.Errorf("ranking = %v, want %v", got, want)
+	}
+	for i := 1; i < len(res.Hits); i++ {
+		if res.Hits[i].Score > res.Hits[i-1].Score {
+			t.Errorf("scores are not descending: %v", res.Hits)
+		}
+	}
+}
+
+func TestIndex_ReplaceDeletePersist(t *testing.T) {
+	dir := t.TempDir()
+	ix, err := index.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testIndex(t, ix)
+	if err := ix.Add(index.Document{URL: "https://other.org/docker", Title: "Podman", Content: "Run containers without a daemon."}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ix.Add(index.Document{Content: "no URL"}); err == nil {
+		t.Error("Add(no URL) succeeded")
+	}
+	if res, _ := ix.Search("docker", index.SearchOptions{}); !reflect.DeepEqual(urls(res), []string{"https://example.com/docs/install"}) {
+		t.Errorf("replaced document still matches: %v", urls(res))
+	}
+	if !ix.Delete("https://blog.example.com/keys") || ix.Delete("https://blog.example.com/keys") {
+		t.Error("Delete() reported the wrong result")
+	}
+	if err := ix.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ix, err = index.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ix.Len() != 2 || !reflect.DeepEqual(ix.URLs(), []string{"https://example.com/docs/install", "https://other.org/docker"}) {
+		t.Fatalf("reopened index has %v", ix.URLs())
+	}
+	doc, ok := ix.Get("https://other.org/docker")
+	if !ok || doc.Title != "Podman" {
+		t.Errorf("Get() = %+v, %v", doc, ok)
+	}
+	for q, want := range map[string][]string{
+		"daemon": {"https://other.org/docker"},
+		"rotate": nil,
+		"key":    {"https://example.com/docs/install"},
+	} {
+		if res, _ := ix.Search(q, index.SearchOptions{}); !reflect.DeepEqual(urls(res), want) {
+			t.Errorf("Search(%q) after reopening = %v, want %v", q, urls(res), want)
+		}
+	}
+}
+
+func TestAddExtractAndCrawl(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/extract":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true, "type": "markdown", "url": "https://example.com/guide",
+				"data": "# Guide\n\nSee the [setup page](/setup) and **configure** `proxies`.\n\n```\nsecret_code\n```\n",
+			})
+		case "/v1/scrape":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"results": []map[string]interface{}{{
+					"page": 1, "url": "https://example.com/",
+					"data": `<html><head><title>Home</title><style>.x{color:red}</style></head>` +
+						`<body><p>Welcome</p><p>home<script>var hidden = 1</script></p></body></html>`,
+				}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	client := snapapi.New("test-key", snapapi.WithBaseURL(srv.URL), snapapi.WithRetries(0))
+	ctx := context.Background()
+
+	ix := index.New()
+	res, err := client.Extract(ctx, snapapi.ExtractParams{URL: "https://example.com/guide", Format: "markdown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ix.AddExtract(res, map[string]string{"kind": "guide"}); err != nil {
+		t.Fatal(err)
+	}
+	doc, _ := ix.Get("https://example.com/guide")
+	if doc.Title != "Guide" || doc.Fields["kind"] != "guide" || doc.Time.IsZero() {
+		t.Errorf("document = %+v", doc)
+	}
+	if strings.ContainsAny(doc.Content, "[]*`#") || strings.Contains(doc.Content, "/setup") {
+		t.Errorf("Content still has Markdown: %q", doc.Content)
+	}
+	for _, q := range []string{"setup page", "configure proxies", "secret_code"} {
+		if r, _ := ix.Search(q, index.SearchOptions{}); r.Total != 1 {
+			t.Errorf("Search(%q) found %d documents", q, r.Total)
+		}
+	}
+
+	_, err = client.Crawl(ctx, "https://example.com/", snapapi.CrawlOptions{MaxPages: 1, IgnoreRobots: true}, ix.CrawlFunc(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := ix.Search("welcome home", index.SearchOptions{})
+	if r.Total != 1 || r.Hits[0].Title != "Home" {
+		t.Fatalf("crawled page not found: %+v", r)
+	}
+	for _, q := range []string{"hidden", "color"} {
+		if r, _ := ix.Search(q, index.SearchOptions{}); r.Total != 0 {
+			t.Errorf("Search(%q) matched script or style text", q)
+		}
+	}
+}
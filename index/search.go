@@ -0,0 +1,409 @@
+package index
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// BM25 parameters. Title terms count titleWeight times.
+const (
+	bm25K1      = 1.2
+	bm25B       = 0.75
+	titleWeight = 2
+)
+
+// SearchOptions configures Search. The filters are combined with those in
+// the query.
+type SearchOptions struct {
+	// Limit is the maximum number of hits returned. Default: 10.
+	Limit int
+	// Offset skips the first hits, for paging.
+	Offset int
+	// URLPrefix keeps documents whose URL starts with it.
+	URLPrefix string
+	// Site keeps documents on this host or its subdomains.
+	Site string
+	// Fields keeps documents whose fields have exactly these values.
+	Fields map[string]string
+	// Since and Until keep documents captured in [Since, Until).
+	Since, Until time.Time
+	// SnippetWords is the length of the snippet in words. Default: 30.
+	SnippetWords int
+	// HighlightStart and HighlightEnd surround matched words in the
+	// snippet. Default: "**" and "**".
+	HighlightStart, HighlightEnd string
+}
+
+// Hit is a document matching a search.
+type Hit struct {
+	URL     string            `json:"url"`
+	Title   string            `json:"title"`
+	Score   float64           `json:"score"`
+	Snippet string            `json:"snippet"`
+	Fields  map[string]string `json:"fields,omitempty"`
+	Time    time.Time         `json:"time"`
+}
+
+// SearchResult is a page of hits.
+type SearchResult struct {
+	// Total is the number of matching documents, before Limit and Offset.
+	Total int   `json:"total"`
+	Hits  []Hit `json:"hits"`
+}
+
+// query is a parsed search query. Phrases are lists of terms; a single
+// word is a phrase of one term.
+type query struct {
+	include [][]string
+	exclude [][]string
+	filters SearchOptions
+}
+
+var queryFilter = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_.-]*):(.+)$`)
+
+// parseQuery parses q into opts' filters and the terms to search for.
+func parseQuery(q string, opts SearchOptions) (*query, error) {
+	p := &query{filters: opts}
+	p.filters.Fields = make(map[string]string, len(opts.Fields))
+	for k, v := range opts.Fields {
+		p.filters.Fields[k] = v
+	}
+	for q = strings.TrimSpace(q); q != ""; q = strings.TrimSpace(q) {
+		neg := false
+		if q[0] == '-' && len(q) > 1 {
+			neg, q = true, q[1:]
+		}
+		var word string
+		quoted := q[0] == '"'
+		if quoted {
+			end := strings.IndexByte(q[1:], '"')
+			if end < 0 {
+				return nil, errors.New("index: unterminated quote in query")
+			}
+			word, q = q[1:end+1], q[end+2:]
+		} else if i := strings.IndexFunc(q, unicode.IsSpace); i >= 0 {
+			word, q = q[:i], q[i:]
+		} else {
+			word, q = q, ""
+		}
+
+		if m := queryFilter.FindStringSubmatch(word); m != nil && !quoted && !neg {
+			switch key, value := strings.ToLower(m[1]), m[2]; key {
+			case "site":
+				p.filters.Site = value
+			case "url":
+				p.filters.URLPrefix = value
+			default:
+				p.filters.Fields[m[1]] = value
+			}
+			continue
+		}
+		phrase := terms(word)
+		if len(phrase) == 0 {
+			continue
+		}
+		if neg {
+			p.exclude = append(p.exclude, phrase)
+		} else {
+			p.include = append(p.include, phrase)
+		}
+	}
+	return p, nil
+}
+
+// Search returns the documents matching q, best first. A query is a list
+// of words, all of which must appear in the title or content:
+//
+//	install docker                 both words
+//	"api key"                      the exact phrase
+//	-deprecated -"old api"         without the word or phrase
+//	site:example.com               on example.com or a subdomain
+//	url:https://example.com/docs/  URL starts with the prefix
+//	section:docs                   field "section" equals "docs"
+//
+// Matches are ranked with BM25. A query with no words returns every
+// document that passes the filters, newest first.
+func (ix *Index) Search(q string, opts SearchOptions) (*SearchResult, error) {
+	pq, err := parseQuery(q, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+	if opts.SnippetWords <= 0 {
+		opts.SnippetWords = 30
+	}
+	if opts.HighlightStart == "" && opts.HighlightEnd == "" {
+		opts.HighlightStart, opts.HighlightEnd = "**", "**"
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	var candidates []uint32
+	if len(pq.include) == 0 {
+		for id := range ix.data.Docs {
+			candidates = append(candidates, id)
+		}
+	} else {
+		// Start from the rarest term of the first phrase.
+		rarest := pq.include[0][0]
+		for _, t := range pq.include[0] {
+			if len(ix.data.Postings[t]) < len(ix.data.Postings[rarest]) {
+				rarest = t
+			}
+		}
+		for _, p := range ix.data.Postings[rarest] {
+			candidates = append(candidates, p.Doc)
+		}
+	}
+
+	type scored struct {
+		id    uint32
+		score float64
+	}
+	var matches []scored
+	highlight := make(map[string]bool)
+	for _, phrase := range pq.include {
+		for _, t := range phrase {
+			highlight[t] = true
+		}
+	}
+docs:
+	for _, id := range candidates {
+		doc := ix.data.Docs[id]
+		if !pq.filters.match(doc) {
+			continue
+		}
+		for _, phrase := range pq.include {
+			if !ix.contains(id, phrase) {
+				continue docs
+			}
+		}
+		for _, phrase := range pq.exclude {
+			if ix.contains(id, phrase) {
+				continue docs
+			}
+		}
+		matches = append(matches, scored{id, ix.score(id, highlight)})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := ix.data.Docs[matches[i].id], ix.data.Docs[matches[j].id]
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		if !a.Time.Equal(b.Time) {
+			return a.Time.After(b.Time)
+		}
+		return a.URL < b.URL
+	})
+
+	res := &SearchResult{Total: len(matches), Hits: []Hit{}}
+	if opts.Offset < len(matches) {
+		matches = matches[max(opts.Offset, 0):]
+	} else {
+		matches = nil
+	}
+	for _, m := range matches[:min(opts.Limit, len(matches))] {
+		doc := ix.data.Docs[m.id]
+		res.Hits = append(res.Hits, Hit{
+			URL:     doc.URL,
+			Title:   doc.Title,
+			Score:   m.score,
+			Snippet: snippet(doc.Content, highlight, opts.SnippetWords, opts.HighlightStart, opts.HighlightEnd),
+			Fields:  doc.Fields,
+			Time:    doc.Time,
+		})
+	}
+	return res, nil
+}
+
+// match reports whether doc passes the filters.
+func (o *SearchOptions) match(doc *docRecord) bool {
+	if o.URLPrefix != "" && !strings.HasPrefix(doc.URL, o.URLPrefix) {
+		return false
+	}
+	if o.Site != "" {
+		u, err := url.Parse(doc.URL)
+		if err != nil || !matchHost(u.Hostname(), o.Site) {
+			return false
+		}
+	}
+	for k, v := range o.Fields {
+		if got, ok := doc.Fields[k]; !ok || got != v {
+			return false
+		}
+	}
+	if !o.Since.IsZero() && doc.Time.Before(o.Since) {
+		return false
+	}
+	if !o.Until.IsZero() && !doc.Time.Before(o.Until) {
+		return false
+	}
+	return true
+}
+
+// posting returns the positions of term in document id.
+func (ix *Index) posting(id uint32, term string) []uint32 {
+	list := ix.data.Postings[term]
+	i := sort.Search(len(list), func(i int) bool { return list[i].Doc >= id })
+	if i < len(list) && list[i].Doc == id {
+		return list[i].Pos
+	}
+	return nil
+}
+
+// contains reports whether document id contains the terms of phrase in
+// order.
+func (ix *Index) contains(id uint32, phrase []string) bool {
+	pos := make([][]uint32, len(phrase))
+	for i, t := range phrase {
+		if pos[i] = ix.posting(id, t); pos[i] == nil {
+			return false
+		}
+	}
+	if len(phrase) == 1 {
+		return true
+	}
+next:
+	for _, p := range pos[0] {
+		for i := 1; i < len(phrase); i++ {
+			want := p + uint32(i)
+			j := sort.Search(len(pos[i]), func(j int) bool { return pos[i][j] >= want })
+			if j == len(pos[i]) || pos[i][j] != want {
+				continue next
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// score is the BM25 score of document id for the given terms.
+func (ix *Index) score(id uint32, terms map[string]bool) float64 {
+	n := float64(len(ix.data.Docs))
+	avg := float64(ix.data.Terms) / n
+	doc := ix.data.Docs[id]
+	norm := 1 - bm25B
+	if avg > 0 {
+		norm += bm25B * float64(doc.Length) / avg
+	}
+	score := 0.0
+	for t := range terms {
+		pos := ix.posting(id, t)
+		if len(pos) == 0 {
+			continue
+		}
+		tf := 0.0
+		for _, p := range pos {
+			if int(p) < doc.TitleTerms {
+				tf += titleWeight
+			} else {
+				tf++
+			}
+		}
+		df := float64(len(ix.data.Postings[t]))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+	}
+	return score
+}
+
+var spaceRun = regexp.MustCompile(`\s+`)
+
+// snippet returns a window of about words words of text around the most
+// matches of the highlighted terms, with the matches surrounded by pre and
+// post.
+func snippet(text string, highlight map[string]bool, words int, pre, post string) string {
+	toks := tokenize(text)
+	if len(toks) == 0 {
+		return ""
+	}
+
+	// Find the window with the most distinct terms, then the most matches.
+	counts := make(map[string]int)
+	distinct, hits := 0, 0
+	best, bestScore := 0, 0
+	for i, t := range toks {
+		if highlight[t.term] {
+			if counts[t.term] == 0 {
+				distinct++
+			}
+			counts[t.term]++
+			hits++
+		}
+		if i >= words {
+			if old := toks[i-words].term; highlight[old] {
+				counts[old]--
+				hits--
+				if counts[old] == 0 {
+					distinct--
+				}
+			}
+		}
+		if s := distinct*len(toks) + hits; s > bestScore {
+			best, bestScore = max(0, i-words+1), s
+		}
+	}
+	// Center the matches in the window.
+	end := min(best+words, len(toks))
+	first, last := -1, -1
+	for i := best; i < end; i++ {
+		if highlight[toks[i].term] {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first >= 0 {
+		best = max(0, min(first-(words-(last-first+1))/2, len(toks)-words))
+		end = min(best+words, len(toks))
+	}
+
+	var b strings.Builder
+	if best > 0 {
+		b.WriteString("… ")
+	}
+	open := false
+	for i := best; i < end; i++ {
+		t := toks[i]
+		gap := ""
+		if i > best {
+			gap = spaceRun.ReplaceAllString(text[toks[i-1].end:t.start], " ")
+		}
+		if open && (!highlight[t.term] || strings.TrimSpace(gap) != "") {
+			b.WriteString(post)
+			open = false
+		}
+		b.WriteString(gap)
+		if highlight[t.term] && !open {
+			b.WriteString(pre)
+			open = true
+		}
+		b.WriteString(text[t.start:t.end])
+	}
+	if open {
+		b.WriteString(post)
+	}
+	// Keep punctuation that ends the last word.
+	rest := text[toks[end-1].end:]
+	if end < len(toks) {
+		rest = text[toks[end-1].end:toks[end].start]
+	}
+	if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+		rest = rest[:i]
+	}
+	b.WriteString(rest)
+	if end < len(toks) {
+		b.WriteString(" …")
+	}
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package index
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// token is a term and where it was found in the text.
+type token struct {
+	term       string
+	start, end int // byte offsets
+}
+
+// tokenize splits s into lower-case terms: runs of letters and digits, with
+// every Han, Hiragana, Katakana and Hangul character a term of its own.
+func tokenize(s string) []token {
+	var toks []token
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			toks = append(toks, token{term: strings.ToLower(s[start:end]), start: start, end: end})
+			start = -1
+		}
+	}
+	for i, r := range s {
+		switch {
+		case isCJK(r):
+			flush(i)
+			end := i + utf8.RuneLen(r)
+			toks = append(toks, token{term: s[i:end], start: i, end: end})
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+			if start < 0 {
+				start = i
+			}
+		default:
+			flush(i)
+		}
+	}
+	flush(len(s))
+	return toks
+}
+
+// terms returns the terms of s.
+func terms(s string) []string {
+	toks := tokenize(s)
+	out := make([]string, len(toks))
+	for i, t := range toks {
+		out[i] = t.term
+	}
+	return out
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+var (
+	htmlSkipped = regexp.MustCompile(`(?is)<(script|style|noscript|template|svg|head)\b.*?</(script|style|noscript|template|svg|head)\s*>|<!--.*?-->`)
+	htmlTag     = regexp.MustCompile(`(?s)<[^>]*>`)
+
+	mdFenceLine = regexp.MustCompile("(?m)^ {0,3}(```|~~~).*$")
+	mdImage     = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink      = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	mdRefDef    = regexp.MustCompile(`(?m)^ {0,3}\[[^\]]+\]:.*$`)
+	mdHeading   = regexp.MustCompile(`(?m)^ {0,3}#{1,6}[ \t]+|[ \t]+#+[ \t]*$`)
+	mdMarkers   = regexp.MustCompile("(?m)^ {0,3}(?:>[ \t]?)+|^[ \t]*(?:[-*+]|\\d+[.)])[ \t]+|[*_~`|]+|^[ \t]*[-=:]{3,}[ \t]*$")
+)
+
+// plainText returns the text of content, an extracted page in the given
+// format ("html", "markdown" or "text"), without markup.
+func plainText(content, format string) string {
+	switch format {
+	case "html":
+		s := htmlSkipped.ReplaceAllString(content, " ")
+		s = htmlTag.ReplaceAllString(s, " ")
+		return strings.TrimSpace(html.UnescapeString(s))
+	case "text", "json":
+		return content
+	}
+	s := mdFenceLine.ReplaceAllString(content, "")
+	s = mdRefDef.ReplaceAllString(s, "")
+	s = mdImage.ReplaceAllString(s, "$1")
+	s = mdLink.ReplaceAllString(s, "$1")
+	s = mdHeading.ReplaceAllString(s, "")
+	s = mdMarkers.ReplaceAllString(s, " ")
+	s = htmlTag.ReplaceAllString(s, " ")
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,386 @@
+package snapapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PoolStrategy selects how a KeyPool spreads requests across its keys.
+type PoolStrategy int
+
+const (
+	// PoolRoundRobin cycles through available keys in order.
+	PoolRoundRobin PoolStrategy = iota
+	// PoolWeighted distributes requests in proportion to PoolKey.Weight
+	// (smooth weighted round-robin).
+	PoolWeighted
+)
+
+// PoolKey is one API key in a KeyPool.
+type PoolKey struct {
+	// Key is the raw API key. Required.
+	Key string
+	// Name labels the key in KeyStatus. Default: a masked form of Key.
+	Name string
+	// Weight is the key's share of traffic under PoolWeighted. Default: 1.
+	Weight int
+}
+
+// KeyPoolOptions configures a KeyPool.
+type KeyPoolOptions struct {
+	// Strategy selects round-robin (default) or weighted routing.
+	Strategy PoolStrategy
+	// RateLimitCooldown is how long a rate-limited key is skipped when the
+	// server sends no Retry-After. Default: 1 minute.
+	RateLimitCooldown time.Duration
+	// QuotaCooldown is how long a quota-exhausted key is skipped when its
+	// UsageResult.ResetAt is unknown. Default: 1 hour.
+	QuotaCooldown time.Duration
+	// OnExhausted, if set, is called (synchronously) whenever a key is taken
+	// out of rotation.
+	OnExhausted func(KeyStatus)
+}
+
+// KeyStatus is a point-in-time view of one pooled key.
+type KeyStatus struct {
+	// Name is the key's label.
+	Name string
+	// Available reports whether the key is currently in rotation.
+	Available bool
+	// ExhaustedUntil is when an exhausted key returns to rotation.
+	ExhaustedUntil time.Time
+	// Used, Limit and Remaining are the quota figures from the last
+	// Refresh (or quota error), with Remaining decremented locally for each
+	// successful request since. UsageKnown is false until then.
+	Used       int
+	Limit      int
+	Remaining  int
+	UsageKnown bool
+	// Requests and Failures count requests sent with the key.
+	Requests int64
+	Failures int64
+	// LastError is the most recent error returned for the key.
+	LastError string
+}
+
+type pooledKey struct {
+	key    string
+	weight int
+	// current is the running weight for smooth weighted round-robin.
+	current int
+	status  KeyStatus
+}
+
+// KeyPool is a CredentialProvider that spreads requests across several API
+// keys (e.g. several SnapAPI accounts) and fails over between them.
+//
+// When a request with a pooled key returns ErrQuotaExceeded or
+// ErrRateLimited, the key is taken out of rotation - until its
+// UsageResult.ResetAt for quota errors, or for the Retry-After period for
+// rate limits - and the request is retried immediately with the next key.
+// Once every key is exhausted, requests fail with a QUOTA_EXCEEDED *APIError
+// whose RetryAfter is the time until the first key becomes available again.
+//
+//	pool := snapapi.NewKeyPool([]snapapi.PoolKey{
+//	    {Key: os.Getenv("SNAPAPI_KEY_A"), Name: "team-a"},
+//	    {Key: os.Getenv("SNAPAPI_KEY_B"), Name: "team-b"},
+//	}, snapapi.KeyPoolOptions{
+//	    OnExhausted: func(s snapapi.KeyStatus) { log.Printf("key %s exhausted until %s", s.Name, s.ExhaustedUntil) },
+//	})
+//	client := snapapi.New("", snapapi.WithCredentials(pool))
+type KeyPool struct {
+	opts KeyPoolOptions
+
+	mu   sync.Mutex
+	keys []*pooledKey
+	next int
+}
+
+// NewKeyPool returns a KeyPool over keys. Entries with an empty Key are
+// ignored.
+func NewKeyPool(keys []PoolKey, opts KeyPoolOptions) *KeyPool {
+	if opts.RateLimitCooldown <= 0 {
+		opts.RateLimitCooldown = time.Minute
+	}
+	if opts.QuotaCooldown <= 0 {
+		opts.QuotaCooldown = time.Hour
+	}
+	p := &KeyPool{opts: opts}
+	for _, k := range keys {
+		if k.Key == "" {
+			continue
+		}
+		name := k.Name
+		if name == "" {
+			name = maskKey(k.Key)
+		}
+		weight := k.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		p.keys = append(p.keys, &pooledKey{key: k.Key, weight: weight, status: KeyStatus{Name: name}})
+	}
+	return p
+}
+
+// APIKey returns the next available key according to the pool's strategy.
+func (p *KeyPool) APIKey(context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.keys) == 0 {
+		return "", errors.New("snapapi: key pool is empty")
+	}
+	now := time.Now()
+	var available []*pooledKey
+	for _, k := range p.keys {
+		if k.available(now) {
+			available = append(available, k)
+		}
+	}
+	if len(available) == 0 {
+		return "", p.exhaustedError(now)
+	}
+
+	var chosen *pooledKey
+	if p.opts.Strategy == PoolWeighted {
+		total := 0
+		for _, k := range available {
+			k.current += k.weight
+			total += k.weight
+			if chosen == nil || k.current > chosen.current {
+				chosen = k
+			}
+		}
+		chosen.current -= total
+	} else {
+		for i := 0; i < len(p.keys); i++ {
+			k := p.keys[(p.next+i)%len(p.keys)]
+			if k.available(now) {
+				chosen = k
+				p.next = (p.next + i + 1) % len(p.keys)
+				break
+			}
+		}
+	}
+	chosen.status.Requests++
+	return chosen.key, nil
+}
+
+// Status returns a snapshot of every key in the pool.
+func (p *KeyPool) Status() []KeyStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	out := make([]KeyStatus, len(p.keys))
+	for i, k := range p.keys {
+		out[i] = k.snapshot(now)
+	}
+	return out
+}
+
+// Remaining returns the total known remaining quota across the keys that
+// are currently available. Keys whose usage is unknown are not counted, so
+// call Refresh first for an accurate figure.
+func (p *KeyPool) Remaining() int {
+	total := 0
+	for _, s := range p.Status() {
+		if s.Available && s.UsageKnown {
+			total += s.Remaining
+		}
+	}
+	return total
+}
+
+// Refresh fetches GetUsage for every key in the pool through c, updating
+// KeyStatus and taking keys with no remaining quota out of rotation until
+// their ResetAt. Errors for individual keys are joined and returned; the
+// remaining keys are still refreshed.
+//
+//	if err := pool.Refresh(ctx, client); err == nil && pool.Remaining() < 500 {
+//	    alert("SnapAPI key pool is running low")
+//	}
+func (p *KeyPool) Refresh(ctx context.Context, c *Client) error {
+	p.mu.Lock()
+	keys := append([]*pooledKey(nil), p.keys...)
+	p.mu.Unlock()
+
+	var errs []error
+	for _, k := range keys {
+		usage, err := c.GetUsage(withInternalCall(withPinnedKey(ctx, k.key)))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", k.status.Name, err))
+			continue
+		}
+		p.applyUsage(k, usage)
+	}
+	return errors.Join(errs...)
+}
+
+// applyUsage records usage figures for k and takes it out of rotation if it
+// has no quota left.
+func (p *KeyPool) applyUsage(k *pooledKey, u *UsageResult) {
+	p.mu.Lock()
+	k.status.Used, k.status.Limit, k.status.Remaining = u.Used, u.Limit, u.Remaining
+	k.status.UsageKnown = true
+	var notify *KeyStatus
+	if u.Limit > 0 && u.Remaining <= 0 {
+		until := time.Now().Add(p.opts.QuotaCooldown)
+		if t, err := time.Parse(time.RFC3339, u.ResetAt); err == nil {
+			until = t
+		}
+		if until.After(time.Now()) {
+			k.status.ExhaustedUntil = until
+			s := k.snapshot(time.Now())
+			notify = &s
+		}
+	} else {
+		k.status.ExhaustedUntil = time.Time{}
+	}
+	p.mu.Unlock()
+	if notify != nil && p.opts.OnExhausted != nil {
+		p.opts.OnExhausted(*notify)
+	}
+}
+
+// observe records the outcome of a request made with key through c. Quota
+// and rate-limit errors take the key out of rotation; for quota errors the
+// key's usage is fetched to learn when it resets.
+func (p *KeyPool) observe(ctx context.Context, c *Client, key string, err error) {
+	k := p.lookup(key)
+	if k == nil {
+		return
+	}
+	var apiErr *APIError
+	if err == nil {
+		p.mu.Lock()
+		if k.status.UsageKnown && k.status.Remaining > 0 {
+			k.status.Remaining--
+			k.status.Used++
+		}
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Lock()
+	k.status.Failures++
+	k.status.LastError = err.Error()
+	p.mu.Unlock()
+	if !errors.As(err, &apiErr) {
+		return
+	}
+
+	switch apiErr.Code {
+	case ErrRateLimited:
+		cooldown := p.opts.RateLimitCooldown
+		if apiErr.RetryAfter > 0 {
+			cooldown = time.Duration(apiErr.RetryAfter) * time.Second
+		}
+		p.exhaust(k, time.Now().Add(cooldown))
+	case ErrQuotaExceeded:
+		usage, uerr := c.GetUsage(withInternalCall(withPinnedKey(ctx, key)))
+		if uerr == nil {
+			// Force the key out of rotation even if the usage figures lag.
+			usage.Remaining = 0
+			if usage.Limit == 0 {
+				usage.Limit = usage.Used
+			}
+			if usage.Limit == 0 {
+				usage.Limit = 1
+			}
+			p.applyUsage(k, usage)
+			return
+		}
+		p.exhaust(k, time.Now().Add(p.opts.QuotaCooldown))
+	}
+}
+
+// exhaust takes k out of rotation until the given time.
+func (p *KeyPool) exhaust(k *pooledKey, until time.Time) {
+	p.mu.Lock()
+	k.status.ExhaustedUntil = until
+	s := k.snapshot(time.Now())
+	p.mu.Unlock()
+	if p.opts.OnExhausted != nil {
+		p.opts.OnExhausted(s)
+	}
+}
+
+// hasAvailable reports whether any key is currently in rotation.
+func (p *KeyPool) hasAvailable() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for _, k := range p.keys {
+		if k.available(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// size returns the number of keys in the pool.
+func (p *KeyPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.keys)
+}
+
+func (p *KeyPool) lookup(key string) *pooledKey {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, k := range p.keys {
+		if k.key == key {
+			return k
+		}
+	}
+	return nil
+}
+
+// exhaustedError builds the error returned when no key is available.
+// p.mu must be held.
+func (p *KeyPool) exhaustedError(now time.Time) error {
+	var earliest time.Time
+	for _, k := range p.keys {
+		if earliest.IsZero() || k.status.ExhaustedUntil.Before(earliest) {
+			earliest = k.status.ExhaustedUntil
+		}
+	}
+	wait := earliest.Sub(now)
+	return &APIError{
+		Code:       ErrQuotaExceeded,
+		Message:    fmt.Sprintf("all %d pooled API keys are exhausted until %s", len(p.keys), earliest.UTC().Format(time.RFC3339)),
+		RetryAfter: int((wait + time.Second - 1) / time.Second),
+	}
+}
+
+func (k *pooledKey) available(now time.Time) bool {
+	return !now.Before(k.status.ExhaustedUntil)
+}
+
+func (k *pooledKey) snapshot(now time.Time) KeyStatus {
+	s := k.status
+	s.Available = k.available(now)
+	if s.Available {
+		s.ExhaustedUntil = time.Time{}
+	}
+	return s
+}
+
+// maskKey returns a log-safe representation of an API key.
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return key[:4] + "…" + key[len(key)-4:]
+}
+
+// pinnedKeyCtx is the context key for withPinnedKey.
+type pinnedKeyCtx struct{}
+
+// withPinnedKey returns a context whose requests use key directly, bypassing
+// the client's CredentialProvider and any KeyPool bookkeeping.
+func withPinnedKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, pinnedKeyCtx{}, key)
+}
@@ -0,0 +1,378 @@
+package snapapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Link is an <a href> or <area href> element of a page.
+type Link struct {
+	// URL is the absolute URL the link points to, resolved against the page
+	// URL and any <base href>.
+	URL string `json:"url"`
+	// Href is the href attribute as written.
+	Href string `json:"href"`
+	// Text is the link's whitespace-collapsed text, falling back to the
+	// aria-label, title, or the alt text of an image inside the link.
+	Text string `json:"text"`
+	// Rel holds the lowercased rel keywords.
+	Rel []string `json:"rel,omitempty"`
+	// NoFollow is true when rel includes nofollow, ugc or sponsored.
+	NoFollow bool `json:"nofollow,omitempty"`
+	// Internal is true for http(s) links to the page's own host.
+	Internal bool `json:"internal"`
+}
+
+// Links scrapes the rendered HTML of pageURL and returns every link on it in
+// document order, including repeats.
+//
+//	links, err := client.Links(ctx, "https://example.com")
+//	for _, l := range links {
+//	    if !l.Internal && !l.NoFollow {
+//	        fmt.Println(l.URL, l.Text)
+//	    }
+//	}
+func (c *Client) Links(ctx context.Context, pageURL string) ([]Link, error) {
+	res, err := c.Scrape(ctx, ScrapeParams{URL: pageURL, Format: "html"})
+	if err != nil {
+		return nil, err
+	}
+	base := pageURL
+	if res.URL != "" {
+		base = res.URL
+	}
+	return ParseLinks(res.Data, base)
+}
+
+// ParseLinks returns the links of an HTML document, as Links does. pageURL
+// is used to resolve relative links and decide which are internal.
+func ParseLinks(htmlStr, pageURL string) ([]Link, error) {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, &APIError{Code: ErrInvalidParams, Message: "invalid page URL: " + err.Error(), StatusCode: 400}
+	}
+	doc := parseHTML(htmlStr)
+	base := page
+	if b := doc.find("base"); b != nil && b.hasAttr("href") {
+		if href, err := url.Parse(strings.TrimSpace(b.attr("href"))); err == nil {
+			base = base.ResolveReference(href)
+		}
+	}
+
+	var links []Link
+	doc.walk(func(n *htmlNode) bool {
+		if (n.tag != "a" && n.tag != "area") || !n.hasAttr("href") {
+			return true
+		}
+		l := Link{Href: n.attr("href"), Text: n.innerText()}
+		if ref, err := url.Parse(strings.TrimSpace(l.Href)); err == nil {
+			abs := base.ResolveReference(ref)
+			l.URL = abs.String()
+			l.Internal = (abs.Scheme == "http" || abs.Scheme == "https") && strings.EqualFold(abs.Host, page.Host)
+		}
+		if l.Text == "" {
+			l.Text = linkFallbackText(n)
+		}
+		for _, r := range strings.Fields(strings.ToLower(n.attr("rel"))) {
+			l.Rel = append(l.Rel, r)
+			if r == "nofollow" || r == "ugc" || r == "sponsored" {
+				l.NoFollow = true
+			}
+		}
+		links = append(links, l)
+		return true
+	})
+	return links, nil
+}
+
+func linkFallbackText(n *htmlNode) string {
+	for _, a := range []string{"aria-label", "title"} {
+		if s := collapseSpace(n.attr(a)); s != "" {
+			return s
+		}
+	}
+	if img := n.find("img"); img != nil {
+		return collapseSpace(img.attr("alt"))
+	}
+	return ""
+}
+
+// LinkCheckOptions configures CheckLinks.
+type LinkCheckOptions struct {
+	// Methods are tried in order until one gets a response below 400.
+	// Default: HEAD, then GET (for servers that reject or mishandle HEAD).
+	Methods []string
+	// Concurrency is the number of links checked at once. Default: 8.
+	Concurrency int
+	// PerHostConcurrency caps concurrent requests to one host. Default: 2.
+	PerHostConcurrency int
+	// PerHostInterval is the minimum time between the starts of two
+	// requests to one host.
+	PerHostInterval time.Duration
+	// Timeout bounds each request, including reading its headers.
+	// Default: 10s.
+	Timeout time.Duration
+	// MaxRedirects is the number of redirects followed. Default: 10.
+	MaxRedirects int
+	// HTTPClient sends the requests. Its CheckRedirect is not used;
+	// redirects are followed by CheckLinks. Default: the client's own
+	// HTTP client.
+	HTTPClient *http.Client
+	// UserAgent is sent with every request. Default: the SDK user agent.
+	UserAgent string
+}
+
+// LinkCheck is the result of checking one link.
+type LinkCheck struct {
+	// URL is the link that was checked, without its fragment.
+	URL string `json:"url"`
+	// Status is the status code of the final response, or 0 if there was
+	// none.
+	Status int `json:"status"`
+	// Method is the method that produced Status.
+	Method string `json:"method,omitempty"`
+	// Redirects lists each redirect response, in order.
+	Redirects []Redirect `json:"redirects,omitempty"`
+	// FinalURL is the URL of the final response.
+	FinalURL string `json:"finalUrl,omitempty"`
+	// Timeout is true when the request timed out.
+	Timeout bool `json:"timeout,omitempty"`
+	// Err is the error of the last attempt, if any.
+	Err error `json:"-"`
+	// Duration is the time spent checking the link, across attempts.
+	Duration time.Duration `json:"duration"`
+}
+
+// Redirect is one hop of a redirect chain.
+type Redirect struct {
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+}
+
+// Broken reports whether the link failed, timed out or returned a 4xx/5xx
+// status.
+func (l LinkCheck) Broken() bool { return l.Err != nil || l.Status >= 400 }
+
+// CheckLinks requests each URL and reports its status, following and
+// recording redirects. Duplicate URLs (ignoring fragments) are checked
+// once; results are in the order the URLs were first seen. Links that are
+// not http(s) are reported with an error.
+//
+//	links, _ := client.Links(ctx, "https://example.com")
+//	var urls []string
+//	for _, l := range links {
+//	    urls = append(urls, l.URL)
+//	}
+//	for _, r := range client.CheckLinks(ctx, urls, snapapi.LinkCheckOptions{PerHostInterval: 200 * time.Millisecond}) {
+//	    if r.Broken() {
+//	        fmt.Println(r.URL, r.Status, r.Err)
+//	    }
+//	}
+func (c *Client) CheckLinks(ctx context.Context, urls []string, opts LinkCheckOptions) []LinkCheck {
+	if len(opts.Methods) == 0 {
+		opts.Methods = []string{http.MethodHead, http.MethodGet}
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 8
+	}
+	if opts.PerHostConcurrency <= 0 {
+		opts.PerHostConcurrency = 2
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxRedirects <= 0 {
+		opts.MaxRedirects = 10
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = userAgent
+	}
+	base := opts.HTTPClient
+	if base == nil {
+		base = c.httpClient
+	}
+	// Follow redirects by hand to record each hop.
+	hc := *base
+	hc.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	// Timeout applies per request instead.
+	hc.Timeout = 0
+
+	seen := make(map[string]bool)
+	var results []LinkCheck
+	for _, u := range urls {
+		if i := strings.IndexByte(u, '#'); i >= 0 {
+			u = u[:i]
+		}
+		if !seen[u] {
+			seen[u] = true
+			results = append(results, LinkCheck{URL: u})
+		}
+	}
+
+	lc := &linkChecker{opts: opts, hc: &hc, hosts: make(map[string]*hostLimiter)}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.Concurrency)
+	for i := range results {
+		wg.Add(1)
+		go func(r *LinkCheck) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				r.Err = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+			lc.check(ctx, r)
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
+
+type linkChecker struct {
+	opts LinkCheckOptions
+	hc   *http.Client
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimiter
+}
+
+// hostLimiter enforces PerHostConcurrency and PerHostInterval for a host.
+type hostLimiter struct {
+	sem  chan struct{}
+	mu   sync.Mutex
+	next time.Time
+}
+
+func (lc *linkChecker) host(h string) *hostLimiter {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	hl, ok := lc.hosts[h]
+	if !ok {
+		hl = &hostLimiter{sem: make(chan struct{}, lc.opts.PerHostConcurrency)}
+		lc.hosts[h] = hl
+	}
+	return hl
+}
+
+// acquire waits for a request slot on the host; release must be called
+// when the request is done.
+func (hl *hostLimiter) acquire(ctx context.Context, interval time.Duration) (release func(), err error) {
+	select {
+	case hl.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release = func() { <-hl.sem }
+	if interval > 0 {
+		hl.mu.Lock()
+		now := time.Now()
+		at := hl.next
+		if at.Before(now) {
+			at = now
+		}
+		hl.next = at.Add(interval)
+		hl.mu.Unlock()
+		select {
+		case <-time.After(time.Until(at)):
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+func (lc *linkChecker) check(ctx context.Context, r *LinkCheck) {
+	start := time.Now()
+	defer func() { r.Duration = time.Since(start) }()
+
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		r.Err = fmt.Errorf("snapapi: not an http(s) URL: %q", r.URL)
+		return
+	}
+	for _, method := range lc.opts.Methods {
+		*r = LinkCheck{URL: r.URL, Method: method}
+		lc.follow(ctx, r, method, u)
+		if r.Err == nil && r.Status < 400 {
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// follow requests u with method, following redirects.
+func (lc *linkChecker) follow(ctx context.Context, r *LinkCheck, method string, u *url.URL) {
+	for hops := 0; ; hops++ {
+		status, location, err := lc.request(ctx, method, u)
+		r.FinalURL = u.String()
+		if err != nil {
+			r.Err = err
+			r.Timeout = isTimeout(err)
+			return
+		}
+		r.Status = status
+		if status < 300 || status >= 400 || location == "" {
+			return
+		}
+		r.Redirects = append(r.Redirects, Redirect{URL: u.String(), Status: status})
+		if hops >= lc.opts.MaxRedirects {
+			r.Err = fmt.Errorf("snapapi: stopped after %d redirects", lc.opts.MaxRedirects)
+			return
+		}
+		next, err := u.Parse(location)
+		if err != nil {
+			r.Err = fmt.Errorf("snapapi: invalid redirect location %q: %w", location, err)
+			return
+		}
+		next.Fragment = ""
+		u = next
+		if status == http.StatusSeeOther && method != http.MethodHead {
+			method = http.MethodGet
+		}
+	}
+}
+
+func (lc *linkChecker) request(ctx context.Context, method string, u *url.URL) (status int, location string, err error) {
+	release, err := lc.host(strings.ToLower(u.Host)).acquire(ctx, lc.opts.PerHostInterval)
+	if err != nil {
+		return 0, "", err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, lc.opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("User-Agent", lc.opts.UserAgent)
+	resp, err := lc.hc.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	// Drain a little of the body so the connection can be reused.
+	_, _ = io.CopyN(io.Discard, resp.Body, 64<<10)
+	resp.Body.Close()
+	return resp.StatusCode, resp.Header.Get("Location"), nil
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
@@ -0,0 +1,283 @@
+package snapapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MarkdownOptions configures ProcessMarkdown.
+type MarkdownOptions struct {
+	// AbsoluteLinks rewrites relative link and image URLs, including
+	// reference definitions, against the page URL. Fragment-only links
+	// ("#install") are kept as they are.
+	AbsoluteLinks bool
+	// FrontMatter prepends YAML front matter with the title (the first
+	// heading), url, fetched_at and word_count.
+	FrontMatter bool
+	// FrontMatterFields are added to the front matter after the standard
+	// fields, sorted by key.
+	FrontMatterFields map[string]string
+	// ImageDir, when set, is the directory http(s) images are downloaded
+	// into. Image references are rewritten to relative paths.
+	ImageDir string
+	// MarkdownDir is the directory the Markdown will be saved in; image
+	// paths are relative to it. Default: the current directory.
+	MarkdownDir string
+	// MaxImageSize is the largest image downloaded into ImageDir, in bytes.
+	// Larger images keep their remote URL. Default: 10 MB.
+	MaxImageSize int64
+}
+
+// defaultMaxImageSize is the default MarkdownOptions.MaxImageSize.
+const defaultMaxImageSize = 10 << 20
+
+// ExtractMarkdownDocument extracts p.URL as Markdown and post-processes it
+// with ProcessMarkdown, for self-contained documents:
+//
+//	md, err := client.ExtractMarkdownDocument(ctx, snapapi.ExtractParams{URL: "https://example.com/guide"},
+//	    snapapi.MarkdownOptions{
+//	        AbsoluteLinks: true,
+//	        FrontMatter:   true,
+//	        ImageDir:      "docs/guide/images",
+//	        MarkdownDir:   "docs/guide",
+//	    })
+//	os.WriteFile("docs/guide/index.md", []byte(md), 0o644)
+func (c *Client) ExtractMarkdownDocument(ctx context.Context, p ExtractParams, opts MarkdownOptions) (string, error) {
+	p.Format = "markdown"
+	res, err := c.Extract(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	if res.URL == "" {
+		res.URL = p.URL
+	}
+	return c.ProcessMarkdown(ctx, res, opts)
+}
+
+// ProcessMarkdown rewrites the Markdown of an Extract result as configured
+// by opts. Links inside code blocks and code spans are left alone.
+//
+// Images that cannot be downloaded, or whose content is not an image, keep
+// their remote URL; their errors are joined into the returned error, which is returned together with the
+// processed Markdown.
+func (c *Client) ProcessMarkdown(ctx context.Context, res *ExtractResult, opts MarkdownOptions) (string, error) {
+	base, err := url.Parse(res.URL)
+	if err != nil {
+		return "", &APIError{Code: ErrInvalidParams, Message: "invalid page URL: " + err.Error(), StatusCode: 400}
+	}
+	if opts.MarkdownDir == "" {
+		opts.MarkdownDir = "."
+	}
+	if opts.MaxImageSize <= 0 {
+		opts.MaxImageSize = defaultMaxImageSize
+	}
+
+	loc := &imageLocalizer{c: c, opts: opts, paths: make(map[string]string)}
+	md := rewriteMarkdownURLs(res.Content, func(ref string, image bool) string {
+		u := ref
+		if opts.AbsoluteLinks {
+			u = absoluteRef(base, ref)
+		}
+		if image && opts.ImageDir != "" {
+			if local, ok := loc.localize(ctx, absoluteRef(base, ref)); ok {
+				return local
+			}
+		}
+		return u
+	})
+	if opts.FrontMatter {
+		md = frontMatter(res, opts.FrontMatterFields) + md
+	}
+	return md, errors.Join(loc.errs...)
+}
+
+// absoluteRef resolves ref against base, leaving fragment-only and
+// unparsable references as they are.
+func absoluteRef(base *url.URL, ref string) string {
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+func frontMatter(res *ExtractResult, extra map[string]string) string {
+	title := ""
+	for _, h := range res.Stats.Headings {
+		if h.Level == 1 {
+			title = h.Text
+			break
+		}
+	}
+	if title == "" && len(res.Stats.Headings) > 0 {
+		title = res.Stats.Headings[0].Text
+	}
+	fetched := res.FetchedAt
+	if fetched.IsZero() {
+		fetched = time.Now()
+	}
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "title: %s\n", strconv.Quote(title))
+	fmt.Fprintf(&b, "url: %s\n", strconv.Quote(res.URL))
+	fmt.Fprintf(&b, "fetched_at: %s\n", fetched.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "word_count: %d\n", res.WordCount)
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s: %s\n", k, strconv.Quote(extra[k]))
+	}
+	b.WriteString("---\n\n")
+	return b.String()
+}
+
+// imageLocalizer downloads images once each and returns their local paths.
+type imageLocalizer struct {
+	c     *Client
+	opts  MarkdownOptions
+	paths map[string]string // image URL -> Markdown path, "" if it failed
+	errs  []error
+}
+
+// imageExtensions maps sniffed content types to file extensions.
+var imageExtensions = map[string]string{
+	"image/png": ".png", "image/jpeg": ".jpg", "image/gif": ".gif",
+	"image/webp": ".webp", "image/bmp": ".bmp", "image/x-icon": ".ico",
+}
+
+func (l *imageLocalizer) localize(ctx context.Context, u string) (string, bool) {
+	if p, ok := l.paths[u]; ok {
+		return p, p != ""
+	}
+	l.paths[u] = ""
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", false
+	}
+	data, err := l.c.downloadLimited(ctx, u, l.opts.MaxImageSize)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("snapapi: download image %s: %w", u, err))
+		return "", false
+	}
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		l.errs = append(l.errs, fmt.Errorf("snapapi: download image %s: content is %s, not an image", u, contentType))
+		return "", false
+	}
+
+	// name-<hash><ext>, so images with the same name from different
+	// directories do not collide.
+	ext := strings.ToLower(path.Ext(parsed.Path))
+	stem := strings.TrimSuffix(path.Base(parsed.Path), path.Ext(parsed.Path))
+	if sniffed, ok := imageExtensions[contentType]; ok {
+		ext = sniffed
+	} else if ext == "" || len(ext) > 5 {
+		ext = ".img"
+	}
+	stem = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, stem)
+	if len(stem) > 40 {
+		stem = stem[:40]
+	}
+	if stem == "" || stem == "-" {
+		stem = "image"
+	}
+	sum := sha256.Sum256([]byte(u))
+	name := stem + "-" + hex.EncodeToString(sum[:4]) + ext
+
+	if err := os.MkdirAll(l.opts.ImageDir, 0o755); err != nil {
+		l.errs = append(l.errs, fmt.Errorf("snapapi: %w", err))
+		return "", false
+	}
+	file := filepath.Join(l.opts.ImageDir, name)
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		l.errs = append(l.errs, fmt.Errorf("snapapi: %w", err))
+		return "", false
+	}
+	rel, err := filepath.Rel(l.opts.MarkdownDir, file)
+	if err != nil {
+		rel = file
+	}
+	l.paths[u] = filepath.ToSlash(rel)
+	return l.paths[u], true
+}
+
+var (
+	// mdInlineLink matches [text](dest "title") and ![alt](dest), allowing
+	// an image inside the link text.
+	mdInlineLink = regexp.MustCompile(`(!?)\[((?:[^\[\]\\]|\\.|!\[[^\]]*\]\([^)]*\))*)\]\(\s*(<[^>]*>|[^\s)]+)((?:\s+"[^"]*"|\s+'[^']*')?\s*)\)`)
+	// mdRefDef matches a reference definition, [id]: dest "title", but not
+	// a footnote ([^id]: text).
+	mdRefDef = regexp.MustCompile(`^( {0,3}\[[^\]^][^\]]*\]:[ \t]*)(<[^>]*>|\S+)(.*)$`)
+)
+
+// rewriteMarkdownURLs calls fn for the destination of every inline link,
+// image and reference definition outside code, and replaces it with the
+// result.
+func rewriteMarkdownURLs(md string, fn func(ref string, image bool) string) string {
+	dest := func(d string, image bool) string {
+		if strings.HasPrefix(d, "<") && strings.HasSuffix(d, ">") {
+			return "<" + fn(d[1:len(d)-1], image) + ">"
+		}
+		return fn(d, image)
+	}
+	var inline func(s string) string
+	inline = func(s string) string {
+		return mdInlineLink.ReplaceAllStringFunc(s, func(m string) string {
+			g := mdInlineLink.FindStringSubmatch(m)
+			image := g[1] == "!"
+			return g[1] + "[" + inline(g[2]) + "](" + dest(g[3], image) + g[4] + ")"
+		})
+	}
+
+	lines := strings.Split(md, "\n")
+	fence := ""
+	for i, line := range lines {
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			if fence == "" {
+				fence = m[1]
+			} else if m[1][0] == fence[0] && len(m[1]) >= len(fence) {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+		if m := mdRefDef.FindStringSubmatch(line); m != nil {
+			lines[i] = m[1] + dest(m[2], false) + m[3]
+			continue
+		}
+		// Rewrite outside code spans only.
+		parts := strings.Split(line, "`")
+		for j := 0; j < len(parts); j += 2 {
+			parts[j] = inline(parts[j])
+		}
+		lines[i] = strings.Join(parts, "`")
+	}
+	return strings.Join(lines, "\n")
+}
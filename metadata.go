@@ -0,0 +1,696 @@
+package snapapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PageMetadata is the metadata of a web page: the document title, <meta>
+// and <link> tags, OpenGraph and Twitter card fields, icons and JSON-LD
+// structured data. URLs are resolved against the page URL.
+type PageMetadata struct {
+	// URL is the page URL the metadata was read from.
+	URL string `json:"url"`
+	// Lang is the <html lang> attribute.
+	Lang string `json:"lang,omitempty"`
+	// Title is the text of the <title> element.
+	Title string `json:"title"`
+	// Description is <meta name="description">.
+	Description string `json:"description,omitempty"`
+	// Keywords is <meta name="keywords">, split on commas.
+	Keywords []string `json:"keywords,omitempty"`
+	// Canonical is <link rel="canonical">.
+	Canonical string `json:"canonical,omitempty"`
+	// Robots holds the directives of <meta name="robots">.
+	Robots RobotsDirectives `json:"robots"`
+	// Alternates are the <link rel="alternate" hreflang="..."> links.
+	Alternates []HreflangLink `json:"alternates,omitempty"`
+	// OpenGraph holds the og:* properties.
+	OpenGraph OpenGraph `json:"openGraph"`
+	// Twitter holds the twitter:* card fields.
+	Twitter TwitterCard `json:"twitter"`
+	// Icons are the favicon, apple-touch-icon and mask-icon links.
+	Icons []PageIcon `json:"icons,omitempty"`
+	// Meta maps every named <meta> tag (name, property or http-equiv,
+	// lowercased) to its first content value.
+	Meta map[string]string `json:"meta,omitempty"`
+	// JSONLD holds every JSON-LD object on the page. Top-level arrays and
+	// @graph containers are flattened into their items.
+	JSONLD []map[string]interface{} `json:"jsonLd,omitempty"`
+	// JSONLDErrors describes JSON-LD blocks that could not be decoded.
+	JSONLDErrors []string `json:"jsonLdErrors,omitempty"`
+}
+
+// RobotsDirectives are the directives of a robots meta tag.
+type RobotsDirectives struct {
+	NoIndex      bool `json:"noindex,omitempty"`
+	NoFollow     bool `json:"nofollow,omitempty"`
+	NoArchive    bool `json:"noarchive,omitempty"`
+	NoSnippet    bool `json:"nosnippet,omitempty"`
+	NoImageIndex bool `json:"noimageindex,omitempty"`
+	// Directives lists every directive as written, lowercased (for
+	// example "max-snippet:50").
+	Directives []string `json:"directives,omitempty"`
+}
+
+// HreflangLink is an alternate language version of the page.
+type HreflangLink struct {
+	Lang string `json:"hreflang"`
+	URL  string `json:"url"`
+}
+
+// OpenGraph holds the OpenGraph (og:*) properties of a page.
+type OpenGraph struct {
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Type        string           `json:"type,omitempty"`
+	URL         string           `json:"url,omitempty"`
+	SiteName    string           `json:"siteName,omitempty"`
+	Locale      string           `json:"locale,omitempty"`
+	Images      []OpenGraphImage `json:"images,omitempty"`
+	// Properties maps every og:* property, without the "og:" prefix, to
+	// its values in document order.
+	Properties map[string][]string `json:"properties,omitempty"`
+}
+
+// OpenGraphImage is an og:image together with its structured properties.
+type OpenGraphImage struct {
+	URL    string `json:"url"`
+	Type   string `json:"type,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	Alt    string `json:"alt,omitempty"`
+}
+
+// TwitterCard holds the Twitter card (twitter:*) fields of a page.
+type TwitterCard struct {
+	Card        string `json:"card,omitempty"`
+	Site        string `json:"site,omitempty"`
+	Creator     string `json:"creator,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	ImageAlt    string `json:"imageAlt,omitempty"`
+	// Properties maps every twitter:* field, without the prefix, to its
+	// first value.
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// PageIcon is an icon link such as a favicon or apple-touch-icon.
+type PageIcon struct {
+	Rel   string `json:"rel"`
+	URL   string `json:"url"`
+	Sizes string `json:"sizes,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+// PageMetadata scrapes the rendered HTML of pageURL and returns its metadata.
+//
+//	meta, err := client.PageMetadata(ctx, "https://example.com/blog/post")
+//	fmt.Println(meta.Title, meta.Description, meta.Canonical)
+//	fmt.Println(meta.OpenGraph.Images, meta.Twitter.Card)
+//	for _, a := range meta.Articles() {
+//	    fmt.Println(a.Headline, a.DatePublished)
+//	}
+func (c *Client) PageMetadata(ctx context.Context, pageURL string) (*PageMetadata, error) {
+	res, err := c.Scrape(ctx, ScrapeParams{URL: pageURL, Format: "html"})
+	if err != nil {
+		return nil, err
+	}
+	base := pageURL
+	if res.URL != "" {
+		base = res.URL
+	}
+	return ParsePageMetadata(res.Data, base)
+}
+
+// ParsePageMetadata reads the metadata of an HTML document without a
+// request. pageURL, if set, is used to resolve relative URLs.
+func ParsePageMetadata(htmlStr, pageURL string) (*PageMetadata, error) {
+	var base *url.URL
+	if pageURL != "" {
+		var err error
+		if base, err = url.Parse(pageURL); err != nil {
+			return nil, &APIError{Code: ErrInvalidParams, Message: "invalid page URL: " + err.Error(), StatusCode: 400}
+		}
+	}
+	doc := parseHTML(htmlStr)
+	if b := doc.find("base"); b != nil && b.hasAttr("href") && base != nil {
+		if href, err := url.Parse(strings.TrimSpace(b.attr("href"))); err == nil {
+			base = base.ResolveReference(href)
+		}
+	}
+	resolve := func(s string) string {
+		s = strings.TrimSpace(s)
+		if base == nil || s == "" {
+			return s
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return s
+		}
+		return base.ResolveReference(u).String()
+	}
+
+	m := &PageMetadata{URL: pageURL, Meta: make(map[string]string)}
+	if h := doc.find("html"); h != nil {
+		m.Lang = strings.TrimSpace(h.attr("lang"))
+	}
+	doc.walk(func(n *htmlNode) bool {
+		switch n.tag {
+		case "svg", "math":
+			// Their <title> elements are not the document title.
+			return false
+		case "title":
+			if m.Title == "" {
+				m.Title = n.innerText()
+			}
+		case "meta":
+			m.addMeta(n, resolve)
+		case "link":
+			m.addLink(n, resolve)
+		case "script":
+			if strings.EqualFold(strings.TrimSpace(n.attr("type")), "application/ld+json") {
+				m.addJSONLD(n.innerHTML())
+			}
+		}
+		return true
+	})
+	if len(m.Meta) == 0 {
+		m.Meta = nil
+	}
+	return m, nil
+}
+
+func (m *PageMetadata) addMeta(n *htmlNode, resolve func(string) string) {
+	key := n.attr("name")
+	if key == "" {
+		key = n.attr("property")
+	}
+	if key == "" {
+		key = n.attr("http-equiv")
+	}
+	key = strings.ToLower(strings.TrimSpace(key))
+	if key == "" {
+		return
+	}
+	content := strings.TrimSpace(n.attr("content"))
+	if _, ok := m.Meta[key]; !ok {
+		m.Meta[key] = content
+	}
+
+	switch {
+	case key == "description":
+		if m.Description == "" {
+			m.Description = content
+		}
+	case key == "keywords":
+		for _, k := range strings.Split(content, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				m.Keywords = append(m.Keywords, k)
+			}
+		}
+	case key == "robots":
+		m.Robots.add(content)
+	case strings.HasPrefix(key, "og:"):
+		m.OpenGraph.add(strings.TrimPrefix(key, "og:"), content, resolve)
+	case strings.HasPrefix(key, "twitter:"):
+		m.Twitter.add(strings.TrimPrefix(key, "twitter:"), content, resolve)
+	}
+}
+
+func (m *PageMetadata) addLink(n *htmlNode, resolve func(string) string) {
+	rel := strings.ToLower(strings.Join(strings.Fields(n.attr("rel")), " "))
+	href := n.attr("href")
+	if rel == "" || strings.TrimSpace(href) == "" {
+		return
+	}
+	rels := strings.Fields(rel)
+	switch {
+	case containsString(rels, "canonical"):
+		if m.Canonical == "" {
+			m.Canonical = resolve(href)
+		}
+	case containsString(rels, "alternate") && n.hasAttr("hreflang"):
+		m.Alternates = append(m.Alternates, HreflangLink{Lang: strings.TrimSpace(n.attr("hreflang")), URL: resolve(href)})
+	case containsString(rels, "icon") || containsString(rels, "apple-touch-icon") ||
+		containsString(rels, "apple-touch-icon-precomposed") || containsString(rels, "mask-icon"):
+		m.Icons = append(m.Icons, PageIcon{Rel: rel, URL: resolve(href), Sizes: n.attr("sizes"), Type: n.attr("type")})
+	}
+}
+
+func (m *PageMetadata) addJSONLD(body string) {
+	body = strings.TrimSpace(body)
+	// Some sites wrap the JSON in an HTML comment or CDATA section.
+	for _, w := range [][2]string{{"<!--", "-->"}, {"<![CDATA[", "]]>"}, {"//<![CDATA[", "//]]>"}} {
+		if strings.HasPrefix(body, w[0]) && strings.HasSuffix(body, w[1]) {
+			body = strings.TrimSpace(body[len(w[0]) : len(body)-len(w[1])])
+		}
+	}
+	if body == "" {
+		return
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		m.JSONLDErrors = append(m.JSONLDErrors, err.Error())
+		return
+	}
+	var add func(v interface{})
+	add = func(v interface{}) {
+		switch t := v.(type) {
+		case []interface{}:
+			for _, item := range t {
+				add(item)
+			}
+		case map[string]interface{}:
+			if graph, ok := t["@graph"]; ok {
+				add(graph)
+				return
+			}
+			m.JSONLD = append(m.JSONLD, t)
+		}
+	}
+	add(v)
+}
+
+func (r *RobotsDirectives) add(content string) {
+	for _, d := range strings.Split(content, ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d == "" {
+			continue
+		}
+		r.Directives = append(r.Directives, d)
+		switch d {
+		case "noindex":
+			r.NoIndex = true
+		case "nofollow":
+			r.NoFollow = true
+		case "none":
+			r.NoIndex, r.NoFollow = true, true
+		case "noarchive":
+			r.NoArchive = true
+		case "nosnippet":
+			r.NoSnippet = true
+		case "noimageindex":
+			r.NoImageIndex = true
+		}
+	}
+}
+
+func (og *OpenGraph) add(prop, content string, resolve func(string) string) {
+	if og.Properties == nil {
+		og.Properties = make(map[string][]string)
+	}
+	og.Properties[prop] = append(og.Properties[prop], content)
+
+	last := func() *OpenGraphImage {
+		if len(og.Images) == 0 {
+			og.Images = append(og.Images, OpenGraphImage{})
+		}
+		return &og.Images[len(og.Images)-1]
+	}
+	setOnce := func(dst *string) {
+		if *dst == "" {
+			*dst = content
+		}
+	}
+	switch prop {
+	case "title":
+		setOnce(&og.Title)
+	case "description":
+		setOnce(&og.Description)
+	case "type":
+		setOnce(&og.Type)
+	case "url":
+		if og.URL == "" {
+			og.URL = resolve(content)
+		}
+	case "site_name":
+		setOnce(&og.SiteName)
+	case "locale":
+		setOnce(&og.Locale)
+	case "image":
+		// Each og:image starts a new image; the structured properties
+		// that follow describe it.
+		og.Images = append(og.Images, OpenGraphImage{URL: resolve(content)})
+	case "image:url", "image:secure_url":
+		if img := last(); img.URL == "" {
+			img.URL = resolve(content)
+		}
+	case "image:type":
+		last().Type = content
+	case "image:width":
+		last().Width, _ = strconv.Atoi(content)
+	case "image:height":
+		last().Height, _ = strconv.Atoi(content)
+	case "image:alt":
+		last().Alt = content
+	}
+}
+
+func (tc *TwitterCard) add(prop, content string, resolve func(string) string) {
+	if tc.Properties == nil {
+		tc.Properties = make(map[string]string)
+	}
+	if _, ok := tc.Properties[prop]; ok {
+		return
+	}
+	tc.Properties[prop] = content
+	switch prop {
+	case "card":
+		tc.Card = content
+	case "site":
+		tc.Site = content
+	case "creator":
+		tc.Creator = content
+	case "title":
+		tc.Title = content
+	case "description":
+		tc.Description = content
+	case "image", "image:src":
+		if tc.Image == "" {
+			tc.Image = resolve(content)
+		}
+	case "image:alt":
+		tc.ImageAlt = content
+	}
+}
+
+// Favicon returns the URL of the page's favicon: the first "icon" link, or
+// /favicon.ico on the page's host when there is none.
+func (m *PageMetadata) Favicon() string {
+	for _, icon := range m.Icons {
+		if containsString(strings.Fields(icon.Rel), "icon") {
+			return icon.URL
+		}
+	}
+	if len(m.Icons) > 0 {
+		return m.Icons[0].URL
+	}
+	if u, err := url.Parse(m.URL); err == nil && u.Host != "" {
+		return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/favicon.ico"}).String()
+	}
+	return ""
+}
+
+// SchemaProduct is a schema.org Product.
+type SchemaProduct struct {
+	Name        string
+	Description string
+	URL         string
+	SKU         string
+	GTIN        string
+	Brand       string
+	Images      []string
+	Offers      []SchemaOffer
+	Rating      *SchemaRating
+	// Raw is the decoded JSON-LD object.
+	Raw map[string]interface{}
+}
+
+// SchemaOffer is a schema.org Offer. Price is kept as published.
+type SchemaOffer struct {
+	Price         string
+	PriceCurrency string
+	Availability  string
+	URL           string
+	Seller        string
+}
+
+// SchemaRating is a schema.org AggregateRating.
+type SchemaRating struct {
+	Value float64
+	Best  float64
+	Count int
+}
+
+// SchemaArticle is a schema.org Article or one of its subtypes
+// (NewsArticle, BlogPosting, ...).
+type SchemaArticle struct {
+	Type          string
+	Headline      string
+	Description   string
+	URL           string
+	Authors       []string
+	Publisher     string
+	Images        []string
+	DatePublished time.Time
+	DateModified  time.Time
+	Raw           map[string]interface{}
+}
+
+// SchemaOrganization is a schema.org Organization or one of its subtypes
+// (Corporation, LocalBusiness, ...).
+type SchemaOrganization struct {
+	Type   string
+	Name   string
+	URL    string
+	Logo   string
+	SameAs []string
+	Raw    map[string]interface{}
+}
+
+// SchemaBreadcrumbList is a schema.org BreadcrumbList, ordered by position.
+type SchemaBreadcrumbList struct {
+	Items []SchemaBreadcrumb
+	Raw   map[string]interface{}
+}
+
+// SchemaBreadcrumb is one ListItem of a BreadcrumbList.
+type SchemaBreadcrumb struct {
+	Position int
+	Name     string
+	URL      string
+}
+
+var (
+	schemaArticleTypes = []string{"Article", "NewsArticle", "BlogPosting", "TechArticle",
+		"ScholarlyArticle", "Report", "SocialMediaPosting", "LiveBlogPosting", "AnalysisNewsArticle"}
+	schemaOrganizationTypes = []string{"Organization", "Corporation", "LocalBusiness", "NGO",
+		"EducationalOrganization", "GovernmentOrganization", "NewsMediaOrganization", "OnlineStore", "OnlineBusiness"}
+)
+
+// Products returns the schema.org Product objects in the page's JSON-LD.
+func (m *PageMetadata) Products() []SchemaProduct {
+	var out []SchemaProduct
+	for _, obj := range m.schemaObjects("Product", "ProductGroup") {
+		p := SchemaProduct{
+			Name:        ldString(obj["name"]),
+			Description: ldString(obj["description"]),
+			URL:         ldString(obj["url"]),
+			SKU:         ldString(obj["sku"]),
+			Brand:       ldString(obj["brand"]),
+			Images:      ldStrings(obj["image"]),
+			Raw:         obj,
+		}
+		for _, k := range []string{"gtin", "gtin13", "gtin12", "gtin14", "gtin8"} {
+			if p.GTIN = ldString(obj[k]); p.GTIN != "" {
+				break
+			}
+		}
+		for _, o := range ldObjects(obj["offers"]) {
+			if inner := ldObjects(o["offers"]); len(inner) > 0 && ldString(o["price"]) == "" {
+				// An AggregateOffer listing its offers.
+				for _, io := range inner {
+					p.Offers = append(p.Offers, schemaOffer(io))
+				}
+				continue
+			}
+			p.Offers = append(p.Offers, schemaOffer(o))
+		}
+		if r := ldObjects(obj["aggregateRating"]); len(r) > 0 {
+			p.Rating = &SchemaRating{
+				Value: ldFloat(r[0]["ratingValue"]),
+				Best:  ldFloat(r[0]["bestRating"]),
+				Count: int(ldFloat(r[0]["reviewCount"])),
+			}
+			if p.Rating.Count == 0 {
+				p.Rating.Count = int(ldFloat(r[0]["ratingCount"]))
+			}
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func schemaOffer(o map[string]interface{}) SchemaOffer {
+	price := ldString(o["price"])
+	if price == "" {
+		price = ldString(o["lowPrice"])
+	}
+	return SchemaOffer{
+		Price:         price,
+		PriceCurrency: ldString(o["priceCurrency"]),
+		Availability:  ldString(o["availability"]),
+		URL:           ldString(o["url"]),
+		Seller:        ldString(o["seller"]),
+	}
+}
+
+// Articles returns the schema.org Article objects (including subtypes) in the
+// page's JSON-LD.
+func (m *PageMetadata) Articles() []SchemaArticle {
+	var out []SchemaArticle
+	for _, obj := range m.schemaObjects(schemaArticleTypes...) {
+		a := SchemaArticle{
+			Type:        ldString(obj["@type"]),
+			Headline:    ldString(obj["headline"]),
+			Description: ldString(obj["description"]),
+			URL:         ldString(obj["url"]),
+			Authors:     ldStrings(obj["author"]),
+			Publisher:   ldString(obj["publisher"]),
+			Images:      ldStrings(obj["image"]),
+			Raw:         obj,
+		}
+		if a.Headline == "" {
+			a.Headline = ldString(obj["name"])
+		}
+		a.DatePublished, _ = parseLooseTime(ldString(obj["datePublished"]), "")
+		a.DateModified, _ = parseLooseTime(ldString(obj["dateModified"]), "")
+		out = append(out, a)
+	}
+	return out
+}
+
+// Organizations returns the schema.org Organization objects (including
+// common subtypes) in the page's JSON-LD.
+func (m *PageMetadata) Organizations() []SchemaOrganization {
+	var out []SchemaOrganization
+	for _, obj := range m.schemaObjects(schemaOrganizationTypes...) {
+		out = append(out, SchemaOrganization{
+			Type:   ldString(obj["@type"]),
+			Name:   ldString(obj["name"]),
+			URL:    ldString(obj["url"]),
+			Logo:   ldString(obj["logo"]),
+			SameAs: ldStrings(obj["sameAs"]),
+			Raw:    obj,
+		})
+	}
+	return out
+}
+
+// Breadcrumbs returns the schema.org BreadcrumbList objects in the page's
+// JSON-LD.
+func (m *PageMetadata) Breadcrumbs() []SchemaBreadcrumbList {
+	var out []SchemaBreadcrumbList
+	for _, obj := range m.schemaObjects("BreadcrumbList") {
+		list := SchemaBreadcrumbList{Raw: obj}
+		for _, item := range ldObjects(obj["itemListElement"]) {
+			b := SchemaBreadcrumb{
+				Position: int(ldFloat(item["position"])),
+				Name:     ldString(item["name"]),
+				URL:      ldString(item["item"]),
+			}
+			if it := ldObjects(item["item"]); len(it) > 0 {
+				if b.Name == "" {
+					b.Name = ldString(it[0]["name"])
+				}
+				b.URL = ldString(it[0]["@id"])
+				if b.URL == "" {
+					b.URL = ldString(it[0]["url"])
+				}
+			}
+			list.Items = append(list.Items, b)
+		}
+		sortBreadcrumbs(list.Items)
+		out = append(out, list)
+	}
+	return out
+}
+
+func sortBreadcrumbs(items []SchemaBreadcrumb) {
+	// Insertion sort keeps document order for equal positions.
+	for i := 1; i < len(items); i++ {
+		for j := i; j > 0 && items[j].Position < items[j-1].Position; j-- {
+			items[j], items[j-1] = items[j-1], items[j]
+		}
+	}
+}
+
+// schemaObjects returns the JSON-LD objects whose @type is one of types.
+func (m *PageMetadata) schemaObjects(types ...string) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, obj := range m.JSONLD {
+		for _, t := range ldStrings(obj["@type"]) {
+			// Accept both "Product" and "https://schema.org/Product".
+			if i := strings.LastIndexAny(t, "/#"); i >= 0 {
+				t = t[i+1:]
+			}
+			if containsString(types, t) {
+				out = append(out, obj)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// ldString returns a JSON-LD value as a string. For objects it returns their
+// name, url or @id; for arrays, the first element.
+func ldString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return strings.TrimSpace(t)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	case []interface{}:
+		for _, item := range t {
+			if s := ldString(item); s != "" {
+				return s
+			}
+		}
+	case map[string]interface{}:
+		for _, k := range []string{"name", "url", "@id", "contentUrl"} {
+			if s := ldString(t[k]); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// ldStrings returns a JSON-LD value that may be a single value or an array
+// as a list of strings.
+func ldStrings(v interface{}) []string {
+	items, ok := v.([]interface{})
+	if !ok {
+		items = []interface{}{v}
+	}
+	var out []string
+	for _, item := range items {
+		if s := ldString(item); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ldObjects returns a JSON-LD value that may be an object or an array of
+// objects as a list of objects.
+func ldObjects(v interface{}) []map[string]interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{t}
+	case []interface{}:
+		var out []map[string]interface{}
+		for _, item := range t {
+			if obj, ok := item.(map[string]interface{}); ok {
+				out = append(out, obj)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// ldFloat returns a JSON-LD number, which may be published as a string.
+func ldFloat(v interface{}) float64 {
+	f, _ := strconv.ParseFloat(cleanNumber(ldString(v)), 64)
+	return f
+}
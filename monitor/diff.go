@@ -0,0 +1,260 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is the kind of an Edit.
+type Op string
+
+// Edit operations.
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Edit is one run of a diff: text present in both versions, or only in
+// the new (Insert) or old (Delete) one.
+type Edit struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// WordDiff compares two texts word by word. Runs of words with the same
+// operation are merged into one Edit, joined by single spaces.
+//
+//	for _, e := range monitor.WordDiff("price $10 per month", "price $12 per month") {
+//	    fmt.Printf("%s %q\n", e.Op, e.Text) // equal "price", delete "$10", insert "$12", equal "per month"
+//	}
+func WordDiff(a, b string) []Edit {
+	return mergeEdits(diffTokens(strings.Fields(a), strings.Fields(b)), " ")
+}
+
+// LineDiff compares two texts line by line, one Edit per line.
+func LineDiff(a, b string) []Edit {
+	return diffTokens(splitLines(a), splitLines(b))
+}
+
+// UnifiedDiff returns a unified diff of two texts with context lines of
+// context around each change, or "" if they are equal.
+//
+//	fmt.Print(monitor.UnifiedDiff(old, cur, "yesterday", "today", 3))
+func UnifiedDiff(a, b, fromName, toName string, context int) string {
+	edits := LineDiff(a, b)
+	changed := false
+	for _, e := range edits {
+		if e.Op != Equal {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+	if context < 0 {
+		context = 0
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	// Line numbers (1-based) of each edit in the old and new text.
+	type line struct {
+		Edit
+		aLine, bLine int
+	}
+	lines := make([]line, len(edits))
+	al, bl := 1, 1
+	for i, e := range edits {
+		lines[i] = line{Edit: e, aLine: al, bLine: bl}
+		if e.Op != Insert {
+			al++
+		}
+		if e.Op != Delete {
+			bl++
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		if lines[i].Op == Equal {
+			i++
+			continue
+		}
+		// Grow the hunk while changes are within 2*context lines of each other.
+		start := max(0, i-context)
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].Op != Equal {
+				end = j
+			} else if j-end > 2*context {
+				break
+			}
+		}
+		end = min(len(lines), end+context+1)
+
+		aStart, bStart, aCount, bCount := lines[start].aLine, lines[start].bLine, 0, 0
+		for _, l := range lines[start:end] {
+			if l.Op != Insert {
+				aCount++
+			}
+			if l.Op != Delete {
+				bCount++
+			}
+		}
+		if aCount == 0 {
+			aStart--
+		}
+		if bCount == 0 {
+			bStart--
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, l := range lines[start:end] {
+			prefix := " "
+			switch l.Op {
+			case Insert:
+				prefix = "+"
+			case Delete:
+				prefix = "-"
+			}
+			out.WriteString(prefix + l.Text + "\n")
+		}
+		i = end
+	}
+	return out.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// mergeEdits joins consecutive edits with the same operation.
+func mergeEdits(edits []Edit, sep string) []Edit {
+	var out []Edit
+	for _, e := range edits {
+		if n := len(out); n > 0 && out[n-1].Op == e.Op {
+			out[n-1].Text += sep + e.Text
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// maxEditDistance bounds the work done by diffTokens; beyond it the
+// differing middle is reported as deleted and re-inserted.
+const maxEditDistance = 2000
+
+// diffTokens returns a shortest edit script from a to b, one Edit per
+// token, using Myers' algorithm.
+func diffTokens(a, b []string) []Edit {
+	// Strip the common prefix and suffix, which is cheap and usually most
+	// of a page.
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	var out []Edit
+	for _, t := range a[:pre] {
+		out = append(out, Edit{Equal, t})
+	}
+	out = append(out, myers(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for _, t := range a[len(a)-suf:] {
+		out = append(out, Edit{Equal, t})
+	}
+	return out
+}
+
+func myers(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	maxD := min(n+m, maxEditDistance)
+	off := maxD + 1
+	v := make([]int, 2*maxD+3)
+	// trace[d] holds v[-d..d] as it was before round d.
+	var trace [][]int
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+
+	// Too many differences: replace the whole range.
+	out := make([]Edit, 0, n+m)
+	for _, t := range a {
+		out = append(out, Edit{Delete, t})
+	}
+	for _, t := range b {
+		out = append(out, Edit{Insert, t})
+	}
+	return out
+}
+
+func backtrack(trace [][]int, a, b []string) []Edit {
+	x, y := len(a), len(b)
+	var rev []Edit
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			rev = append(rev, Edit{Equal, a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				rev = append(rev, Edit{Insert, b[y-1]})
+			} else {
+				rev = append(rev, Edit{Delete, a[x-1]})
+			}
+			x, y = prevX, prevY
+		}
+	}
+	for i, j := 0, len(rev)-1; i < j; i, j = i+1, j-1 {
+		rev[i], rev[j] = rev[j], rev[i]
+	}
+	return rev
+}
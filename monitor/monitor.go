@@ -0,0 +1,378 @@
+// Package monitor watches web pages for changes. On every check each target
+// is read with SnapAPI's Extract or Scrape endpoint (optionally scoped to a
+// CSS selector) and, optionally, captured with Screenshot. The result is
+// compared with the target's previous snapshot: text as unified and
+// word-level diffs, screenshots as the fraction of pixels that changed.
+// Changes that survive the target's noise filters are saved to a Store and
+// passed to the change handlers.
+//
+//	client := snapapi.New(os.Getenv("SNAPAPI_KEY"))
+//	m := monitor.New(client, monitor.DirStore("./snapshots"))
+//	m.Add(monitor.Target{
+//	    URL:              "https://competitor.com/pricing",
+//	    Selector:         ".pricing-table",
+//	    Screenshot:       true,
+//	    Ignore:           []string{`Updated \d+ minutes ago`},
+//	    NumericThreshold: 0.02,
+//	})
+//	m.OnChange(func(ctx context.Context, c monitor.Change) error {
+//	    fmt.Print(c.Diff)
+//	    return nil
+//	})
+//	err := m.Run(ctx, time.Hour, func(err error) { log.Print(err) })
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	snapapi "github.com/Sleywill/snapapi-go"
+)
+
+// Source selects the endpoint a target's text is read with.
+type Source string
+
+// Sources.
+const (
+	// SourceExtract reads the page's readable text with Extract.
+	SourceExtract Source = "extract"
+	// SourceScrape reads the rendered text with Scrape, which includes
+	// navigation and other boilerplate that Extract leaves out.
+	SourceScrape Source = "scrape"
+)
+
+// DefaultVisualThreshold is the VisualThreshold used when a target sets none.
+const DefaultVisualThreshold = 0.01
+
+// Target is a page, or part of one, to watch.
+type Target struct {
+	// Name identifies the target in the store and in changes. Default: the
+	// URL, followed by the selector if there is one.
+	Name string `json:"name,omitempty"`
+	// URL of the page. Required.
+	URL string `json:"url"`
+	// Selector scopes the text (and nothing else) to the matching element.
+	Selector string `json:"selector,omitempty"`
+	// Source is the endpoint used to read the text. Default: SourceExtract.
+	Source Source `json:"source,omitempty"`
+	// Screenshot also captures the page and compares it visually.
+	Screenshot bool `json:"screenshot,omitempty"`
+	// ScreenshotParams configures the capture. URL is set from the target
+	// and Format defaults to "png".
+	ScreenshotParams snapapi.ScreenshotParams `json:"-"`
+	// Ignore holds regular expressions for noise, such as timestamps or
+	// visitor counters. Matches are removed from both texts before they
+	// are compared.
+	Ignore []string `json:"ignore,omitempty"`
+	// NumericThreshold ignores text changes that only move numbers by at
+	// most this fraction of their old value (0.02 is 2%). Zero reports
+	// every change.
+	NumericThreshold float64 `json:"numericThreshold,omitempty"`
+	// VisualThreshold is the fraction of changed pixels above which a
+	// screenshot counts as changed. Default: DefaultVisualThreshold.
+	VisualThreshold float64 `json:"visualThreshold,omitempty"`
+}
+
+// ID returns the name the target is stored under.
+func (t Target) ID() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	if t.Selector != "" {
+		return t.URL + " " + t.Selector
+	}
+	return t.URL
+}
+
+// Change describes how a target differs from its previous snapshot.
+type Change struct {
+	Target   Target    `json:"target"`
+	Previous *Snapshot `json:"previous"`
+	Current  *Snapshot `json:"current"`
+	// TextChanged is true when the filtered text changed beyond the noise
+	// filters. Diff and Words are only set when it is.
+	TextChanged bool `json:"textChanged"`
+	// Diff is a unified diff of the filtered texts.
+	Diff string `json:"diff,omitempty"`
+	// Words is a word-level diff of the filtered texts.
+	Words []Edit `json:"words,omitempty"`
+	// VisualScore is the fraction of pixels that changed between the two
+	// screenshots, when both exist.
+	VisualScore float64 `json:"visualScore"`
+	// VisualChanged is true when VisualScore is above the target's
+	// VisualThreshold.
+	VisualChanged bool `json:"visualChanged"`
+}
+
+// Added returns the words inserted by the change.
+func (c Change) Added() []string { return c.words(Insert) }
+
+// Removed returns the words deleted by the change.
+func (c Change) Removed() []string { return c.words(Delete) }
+
+func (c Change) words(op Op) []string {
+	var out []string
+	for _, e := range c.Words {
+		if e.Op == op {
+			out = append(out, e.Text)
+		}
+	}
+	return out
+}
+
+// Handler is called with every change a check finds. An error is reported
+// by Check but does not stop other handlers.
+type Handler func(ctx context.Context, c Change) error
+
+// Monitor checks a set of targets against their snapshot history. Its
+// methods are safe for concurrent use.
+type Monitor struct {
+	client *snapapi.Client
+	store  Store
+
+	mu       sync.Mutex
+	targets  []*target
+	handlers []Handler
+}
+
+type target struct {
+	Target
+	ignore []*regexp.Regexp
+}
+
+// New returns a Monitor that reads pages with c and keeps snapshots in
+// store. A nil store keeps them in memory.
+func New(c *snapapi.Client, store Store) *Monitor {
+	if store == nil {
+		store = &MemoryStore{}
+	}
+	return &Monitor{client: c, store: store}
+}
+
+// Add adds targets to the monitor. It returns an error, and adds none of
+// them, if a target has no URL or an invalid Ignore pattern.
+func (m *Monitor) Add(targets ...Target) error {
+	compiled := make([]*target, 0, len(targets))
+	for _, t := range targets {
+		if t.URL == "" {
+			return errors.New("monitor: target URL is required")
+		}
+		if t.Source == "" {
+			t.Source = SourceExtract
+		}
+		if t.Source != SourceExtract && t.Source != SourceScrape {
+			return fmt.Errorf("monitor: %s: unknown source %q", t.ID(), t.Source)
+		}
+		if t.VisualThreshold <= 0 {
+			t.VisualThreshold = DefaultVisualThreshold
+		}
+		ct := &target{Target: t}
+		for _, p := range t.Ignore {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return fmt.Errorf("monitor: %s: invalid Ignore pattern: %w", t.ID(), err)
+			}
+			ct.ignore = append(ct.ignore, re)
+		}
+		compiled = append(compiled, ct)
+	}
+	m.mu.Lock()
+	m.targets = append(m.targets, compiled...)
+	m.mu.Unlock()
+	return nil
+}
+
+// OnChange registers a handler for changes. Handlers run in the order they
+// were registered.
+func (m *Monitor) OnChange(h Handler) {
+	m.mu.Lock()
+	m.handlers = append(m.handlers, h)
+	m.mu.Unlock()
+}
+
+// Check checks every target once, in the order they were added, and
+// returns the changes found. A target's first check only records its
+// baseline snapshot. Snapshots are saved only when they differ from the
+// previous one, so changes below the noise thresholds accumulate until
+// they are reported.
+//
+// Errors from individual targets and handlers are joined into the returned
+// error; the remaining targets are still checked.
+func (m *Monitor) Check(ctx context.Context) ([]Change, error) {
+	m.mu.Lock()
+	targets := append([]*target(nil), m.targets...)
+	handlers := append([]Handler(nil), m.handlers...)
+	m.mu.Unlock()
+
+	var changes []Change
+	var errs []error
+	for _, t := range targets {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		c, err := m.check(ctx, t)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("monitor: %s: %w", t.ID(), err))
+			continue
+		}
+		if c == nil {
+			continue
+		}
+		changes = append(changes, *c)
+		for _, h := range handlers {
+			if err := h(ctx, *c); err != nil {
+				errs = append(errs, fmt.Errorf("monitor: %s: handler: %w", t.ID(), err))
+			}
+		}
+	}
+	return changes, errors.Join(errs...)
+}
+
+// Run calls Check immediately and then every interval until ctx is done,
+// returning ctx's error. Check errors are passed to onError, if set.
+func (m *Monitor) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	if interval <= 0 {
+		return errors.New("monitor: interval must be positive")
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := m.Check(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) check(ctx context.Context, t *target) (*Change, error) {
+	cur, err := m.snapshot(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := m.store.Latest(ctx, t.ID())
+	if err != nil {
+		return nil, err
+	}
+	if prev == nil {
+		return nil, m.store.Save(ctx, cur)
+	}
+
+	c := &Change{Target: t.Target, Previous: prev, Current: cur}
+	before, after := t.filter(prev.Text), t.filter(cur.Text)
+	if before != after && !numbersWithin(before, after, t.NumericThreshold) {
+		c.TextChanged = true
+		c.Diff = UnifiedDiff(before, after, prev.Time.Format(time.RFC3339), cur.Time.Format(time.RFC3339), 3)
+		c.Words = WordDiff(before, after)
+	}
+	if len(prev.Screenshot) > 0 && len(cur.Screenshot) > 0 {
+		c.VisualScore, err = VisualDiff(prev.Screenshot, cur.Screenshot)
+		if err != nil {
+			return nil, err
+		}
+		c.VisualChanged = c.VisualScore > t.VisualThreshold
+	}
+	if !c.TextChanged && !c.VisualChanged {
+		return nil, nil
+	}
+	if err := m.store.Save(ctx, cur); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// snapshot reads the current text and screenshot of a target.
+func (m *Monitor) snapshot(ctx context.Context, t *target) (*Snapshot, error) {
+	s := &Snapshot{Target: t.ID(), URL: t.URL, Time: time.Now()}
+	switch t.Source {
+	case SourceScrape:
+		res, err := m.client.Scrape(ctx, snapapi.ScrapeParams{URL: t.URL, Selector: t.Selector, Format: "text"})
+		if err != nil {
+			return nil, err
+		}
+		s.Text = res.Data
+		if res.URL != "" {
+			s.URL = res.URL
+		}
+	default:
+		res, err := m.client.Extract(ctx, snapapi.ExtractParams{URL: t.URL, Selector: t.Selector, Format: "text"})
+		if err != nil {
+			return nil, err
+		}
+		s.Text = res.Content
+		if res.URL != "" {
+			s.URL = res.URL
+		}
+	}
+	if t.Screenshot {
+		p := t.ScreenshotParams
+		p.URL = t.URL
+		if p.Format == "" {
+			p.Format = "png"
+		}
+		img, err := m.client.Screenshot(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		s.Screenshot = img
+	}
+	return s, nil
+}
+
+// filter removes the target's Ignore matches from text and normalizes its
+// whitespace: runs of spaces collapse, lines are trimmed and blank lines
+// are dropped.
+func (t *target) filter(text string) string {
+	for _, re := range t.ignore {
+		text = re.ReplaceAllString(text, "")
+	}
+	var lines []string
+	for _, l := range strings.Split(text, "\n") {
+		if l = strings.Join(strings.Fields(l), " "); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var numberRE = regexp.MustCompile(`\d[\d,]*(?:\.\d+)?`)
+
+// numbersWithin reports whether a and b differ only in numbers, each moving
+// by at most threshold relative to its value in a.
+func numbersWithin(a, b string, threshold float64) bool {
+	if threshold <= 0 {
+		return false
+	}
+	if numberRE.ReplaceAllString(a, "#") != numberRE.ReplaceAllString(b, "#") {
+		return false
+	}
+	na, nb := numberRE.FindAllString(a, -1), numberRE.FindAllString(b, -1)
+	for i := range na {
+		x, errX := strconv.ParseFloat(strings.ReplaceAll(na[i], ",", ""), 64)
+		y, errY := strconv.ParseFloat(strings.ReplaceAll(nb[i], ",", ""), 64)
+		if errX != nil || errY != nil {
+			if na[i] != nb[i] {
+				return false
+			}
+			continue
+		}
+		if x == y {
+			continue
+		}
+		if x == 0 || math.Abs(y-x)/math.Abs(x) > threshold {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,295 @@
+package monitor_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	snapapi "github.com/Sleywill/snapapi-go"
+	"github.com/Sleywill/snapapi-go/monitor"
+)
+
+// fakeAPI serves Extract, Scrape and Screenshot responses whose content the
+// test can change between checks.
+type fakeAPI struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	text     string
+	boxWidth int // width of the black box drawn on the screenshot
+	bodies   []map[string]interface{}
+}
+
+func newFakeAPI(t *testing.T, text string) *fakeAPI {
+	t.Helper()
+	f := &fakeAPI{text: text}
+	f.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.bodies = append(f.bodies, body)
+		switch r.URL.Path {
+		case "/v1/extract":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "url": body["url"], "data": f.text, "type": "text"})
+		case "/v1/scrape":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"results": []map[string]interface{}{{"page": 1, "url": body["url"], "data": f.text}},
+			})
+		case "/v1/screenshot":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(testPNG(100, 100, f.boxWidth))
+		}
+	}))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeAPI) set(text string, boxWidth int) {
+	f.mu.Lock()
+	f.text, f.boxWidth = text, boxWidth
+	f.mu.Unlock()
+}
+
+func (f *fakeAPI) client() *snapapi.Client {
+	return snapapi.New("test-key", snapapi.WithBaseURL(f.srv.URL), snapapi.WithRetries(0))
+}
+
+// testPNG returns a white w x h image with a black box boxWidth pixels wide
+// and 10 pixels high in the top left corner.
+func testPNG(w, h, boxWidth int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if x < boxWidth && y < 10 {
+				c = color.RGBA{0, 0, 0, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func TestWordDiff(t *testing.T) {
+	got := monitor.WordDiff("Pro plan $10 per month", "Pro plan $12 per month billed yearly")
+	want := []monitor.Edit{
+		{Op: monitor.Equal, Text: "Pro plan"},
+		{Op: monitor.Delete, Text: "$10"},
+		{Op: monitor.Insert, Text: "$12"},
+		{Op: monitor.Equal, Text: "per month"},
+		{Op: monitor.Insert, Text: "billed yearly"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WordDiff() = %+v, want %+v", got, want)
+	}
+	if got := monitor.WordDiff("a b", "a b"); len(got) != 1 || got[0].Op != monitor.Equal {
+		t.Errorf("WordDiff(equal) = %+v", got)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	b := "one\ntwo\n3\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+	want := `--- old
++++ new
+@@ -2,3 +2,3 @@
+ two
+-three
++3
+ four
+@@ -10 +10,2 @@
+ ten
++eleven
+`
+	if got := monitor.UnifiedDiff(a, b, "old", "new", 1); got != want {
+		t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, want)
+	}
+	if got := monitor.UnifiedDiff(a, a, "old", "new", 3); got != "" {
+		t.Errorf("UnifiedDiff(equal) = %q", got)
+	}
+	if got := monitor.UnifiedDiff("", "x\n", "old", "new", 3); got != "--- old\n+++ new\n@@ -0,0 +1 @@\n+x\n" {
+		t.Errorf("UnifiedDiff(empty) = %q", got)
+	}
+}
+
+func TestVisualDiff(t *testing.T) {
+	score, err := monitor.VisualDiff(testPNG(100, 100, 0), testPNG(100, 100, 50))
+	if err != nil || score != 0.05 {
+		t.Errorf("VisualDiff() = %v, %v; want 0.05", score, err)
+	}
+	// The extra 20 rows of the taller image count as changed.
+	score, err = monitor.VisualDiff(testPNG(100, 100, 0), testPNG(100, 120, 0))
+	if err != nil || score != 20.0/120 {
+		t.Errorf("VisualDiff(sizes) = %v, %v", score, err)
+	}
+	if _, err := monitor.VisualDiff([]byte("not an image"), testPNG(1, 1, 0)); err == nil {
+		t.Error("VisualDiff(invalid) error = nil")
+	}
+}
+
+func TestMonitor_Check(t *testing.T) {
+	api := newFakeAPI(t, "Pro plan\n$10.00 per month\nUpdated 5 minutes ago")
+	m := monitor.New(api.client(), nil)
+	err := m.Add(monitor.Target{
+		URL:              "https://example.com/pricing",
+		Selector:         ".pricing",
+		Screenshot:       true,
+		Ignore:           []string{`Updated \d+ minutes ago`},
+		NumericThreshold: 0.05,
+	})
+	if err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+	var handled []monitor.Change
+	m.OnChange(func(_ context.Context, c monitor.Change) error {
+		handled = append(handled, c)
+		return nil
+	})
+	ctx := context.Background()
+	check := func(step string) []monitor.Change {
+		t.Helper()
+		changes, err := m.Check(ctx)
+		if err != nil {
+			t.Fatalf("%s: Check() error: %v", step, err)
+		}
+		return changes
+	}
+
+	if changes := check("baseline"); len(changes) != 0 {
+		t.Fatalf("baseline: changes = %+v", changes)
+	}
+	// Ignored text, a small price move and a few changed pixels are noise.
+	api.set("Pro plan\n  $10.20 per month\n\nUpdated 9 minutes ago", 5)
+	if changes := check("noise"); len(changes) != 0 {
+		t.Fatalf("noise: changes = %+v", changes)
+	}
+
+	api.set("Pro plan\n$12.00 per month\nUpdated 1 minutes ago", 0)
+	changes := check("price")
+	if len(changes) != 1 || len(handled) != 1 {
+		t.Fatalf("price: changes = %+v, handled %d", changes, len(handled))
+	}
+	c := changes[0]
+	if !c.TextChanged || c.VisualChanged || c.Target.ID() != "https://example.com/pricing .pricing" {
+		t.Errorf("price: change = %+v", c)
+	}
+	if !strings.Contains(c.Diff, "-$10.00 per month\n+$12.00 per month\n") || strings.Contains(c.Diff, "Updated") {
+		t.Errorf("price: Diff =\n%s", c.Diff)
+	}
+	if got := c.Added(); !reflect.DeepEqual(got, []string{"$12.00"}) {
+		t.Errorf("price: Added() = %v", got)
+	}
+	if got := c.Removed(); !reflect.DeepEqual(got, []string{"$10.00"}) {
+		t.Errorf("price: Removed() = %v", got)
+	}
+
+	api.set("Pro plan\n$12.00 per month", 100)
+	changes = check("visual")
+	if len(changes) != 1 || changes[0].TextChanged || !changes[0].VisualChanged || changes[0].VisualScore != 0.1 {
+		t.Fatalf("visual: changes = %+v", changes)
+	}
+
+	api.mu.Lock()
+	body := api.bodies[0]
+	api.mu.Unlock()
+	if body["selector"] != ".pricing" || body["format"] != "text" {
+		t.Errorf("extract request = %v", body)
+	}
+}
+
+func TestMonitor_Errors(t *testing.T) {
+	api := newFakeAPI(t, "a")
+	m := monitor.New(api.client(), nil)
+	if err := m.Add(monitor.Target{URL: "https://example.com", Ignore: []string{"("}}); err == nil {
+		t.Error("Add(invalid Ignore) error = nil")
+	}
+	if err := m.Add(monitor.Target{}); err == nil {
+		t.Error("Add(no URL) error = nil")
+	}
+	if err := m.Add(monitor.Target{Name: "scraped", URL: "https://example.com", Source: monitor.SourceScrape}); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+	handlerErr := errors.New("webhook down")
+	m.OnChange(func(context.Context, monitor.Change) error { return handlerErr })
+
+	if _, err := m.Check(context.Background()); err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	api.set("b", 0)
+	changes, err := m.Check(context.Background())
+	if len(changes) != 1 || !errors.Is(err, handlerErr) || !strings.Contains(err.Error(), "scraped") {
+		t.Errorf("Check() = %+v, %v", changes, err)
+	}
+}
+
+func TestMonitor_Run(t *testing.T) {
+	api := newFakeAPI(t, "a")
+	m := monitor.New(api.client(), nil)
+	if err := m.Add(monitor.Target{URL: "https://example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.OnChange(func(context.Context, monitor.Change) error {
+		cancel()
+		return nil
+	})
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx, 10*time.Millisecond, nil) }()
+	time.Sleep(30 * time.Millisecond)
+	api.set("b", 0)
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Run() = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop after a change")
+	}
+}
+
+func TestDirStore(t *testing.T) {
+	store := monitor.DirStore(t.TempDir())
+	ctx := context.Background()
+	if s, err := store.Latest(ctx, "https://example.com/a"); s != nil || err != nil {
+		t.Fatalf("Latest(empty) = %v, %v", s, err)
+	}
+	t0 := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, text := range []string{"first", "second"} {
+		s := &monitor.Snapshot{Target: "https://example.com/a", URL: "https://example.com/a", Time: t0.Add(time.Duration(i) * time.Minute), Text: text}
+		if i == 1 {
+			s.Screenshot = []byte("png bytes")
+		}
+		if err := store.Save(ctx, s); err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+	}
+	if err := store.Save(ctx, &monitor.Snapshot{Target: "other", Time: t0, Text: "x"}); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := store.Latest(ctx, "https://example.com/a")
+	if err != nil || latest.Text != "second" || string(latest.Screenshot) != "png bytes" || !latest.Time.Equal(t0.Add(time.Minute)) {
+		t.Errorf("Latest() = %+v, %v", latest, err)
+	}
+	history, err := store.History(ctx, "https://example.com/a")
+	if err != nil || len(history) != 2 || history[0].Text != "first" || history[0].Screenshot != nil {
+		t.Errorf("History() = %+v, %v", history, err)
+	}
+}
@@ -0,0 +1,190 @@
+package monitor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Snapshot is the recorded state of a target at one point in time.
+type Snapshot struct {
+	// Target is the ID of the target (see Target.ID).
+	Target string `json:"target"`
+	// URL is the final URL the content was read from.
+	URL string `json:"url"`
+	// Time is when the snapshot was taken.
+	Time time.Time `json:"time"`
+	// Text is the page (or selected element) text, before noise filtering.
+	Text string `json:"text"`
+	// Screenshot is the encoded screenshot, if the target takes one.
+	Screenshot []byte `json:"-"`
+}
+
+// Store keeps the snapshot history of each target.
+type Store interface {
+	// Latest returns the most recent snapshot of target, or nil if there
+	// is none.
+	Latest(ctx context.Context, target string) (*Snapshot, error)
+	// Save adds a snapshot to the history of s.Target.
+	Save(ctx context.Context, s *Snapshot) error
+	// History returns the snapshots of target, oldest first.
+	History(ctx context.Context, target string) ([]Snapshot, error)
+}
+
+// MemoryStore is a Store that keeps snapshots in memory. The zero value is
+// ready to use.
+type MemoryStore struct {
+	mu        sync.Mutex
+	snapshots map[string][]Snapshot
+}
+
+// Latest implements Store.
+func (m *MemoryStore) Latest(_ context.Context, target string) (*Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := m.snapshots[target]
+	if len(h) == 0 {
+		return nil, nil
+	}
+	s := h[len(h)-1]
+	return &s, nil
+}
+
+// Save implements Store.
+func (m *MemoryStore) Save(_ context.Context, s *Snapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.snapshots == nil {
+		m.snapshots = make(map[string][]Snapshot)
+	}
+	m.snapshots[s.Target] = append(m.snapshots[s.Target], *s)
+	return nil
+}
+
+// History implements Store.
+func (m *MemoryStore) History(_ context.Context, target string) ([]Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Snapshot(nil), m.snapshots[target]...), nil
+}
+
+// DirStore is a Store that writes snapshots under a directory, one
+// subdirectory per target. Each snapshot is a JSON file named after its
+// time, with the screenshot stored next to it.
+//
+//	store := monitor.DirStore("./snapshots")
+type DirStore string
+
+// Latest implements Store.
+func (d DirStore) Latest(_ context.Context, target string) (*Snapshot, error) {
+	names, err := d.list(target)
+	if err != nil || len(names) == 0 {
+		return nil, err
+	}
+	return d.load(target, names[len(names)-1])
+}
+
+// Save implements Store.
+func (d DirStore) Save(_ context.Context, s *Snapshot) error {
+	dir := d.dir(s.Target)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("monitor: %w", err)
+	}
+	name := s.Time.UTC().Format("20060102T150405.000000000Z")
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("monitor: %w", err)
+	}
+	if len(s.Screenshot) > 0 {
+		if err := os.WriteFile(filepath.Join(dir, name+".img"), s.Screenshot, 0o644); err != nil {
+			return fmt.Errorf("monitor: %w", err)
+		}
+	}
+	// Write the JSON last: a snapshot exists once its JSON file does.
+	if err := os.WriteFile(filepath.Join(dir, name+".json"), data, 0o644); err != nil {
+		return fmt.Errorf("monitor: %w", err)
+	}
+	return nil
+}
+
+// History implements Store.
+func (d DirStore) History(_ context.Context, target string) ([]Snapshot, error) {
+	names, err := d.list(target)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Snapshot, 0, len(names))
+	for _, n := range names {
+		s, err := d.load(target, n)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, nil
+}
+
+// dir returns the directory of a target: a readable prefix of its ID plus
+// a hash, since IDs are usually URLs.
+func (d DirStore) dir(target string) string {
+	var b strings.Builder
+	for _, r := range target {
+		if b.Len() >= 40 {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	sum := sha256.Sum256([]byte(target))
+	return filepath.Join(string(d), b.String()+"-"+hex.EncodeToString(sum[:6]))
+}
+
+// list returns the snapshot names of a target, oldest first.
+func (d DirStore) list(target string) ([]string, error) {
+	entries, err := os.ReadDir(d.dir(target))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("monitor: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if n := e.Name(); strings.HasSuffix(n, ".json") {
+			names = append(names, strings.TrimSuffix(n, ".json"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (d DirStore) load(target, name string) (*Snapshot, error) {
+	dir := d.dir(target)
+	data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("monitor: %w", err)
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("monitor: snapshot %s: %w", name, err)
+	}
+	img, err := os.ReadFile(filepath.Join(dir, name+".img"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("monitor: %w", err)
+	}
+	s.Screenshot = img
+	return &s, nil
+}
@@ -0,0 +1,63 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoders for screenshots in any supported format
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// pixelTolerance is the per-channel difference (out of 0xffff) below which
+// two pixels count as equal, so compression noise and anti-aliasing do not
+// register as changes.
+const pixelTolerance = 0x1800
+
+// VisualDiff compares two encoded images (PNG, JPEG or GIF) and returns the
+// fraction of pixels that differ, from 0 (identical) to 1. When the sizes
+// differ, the area covered by only one image counts as changed.
+//
+//	score, err := monitor.VisualDiff(before, after)
+//	if err == nil && score > 0.01 {
+//	    fmt.Printf("%.1f%% of the page changed\n", score*100)
+//	}
+func VisualDiff(a, b []byte) (float64, error) {
+	ia, _, err := image.Decode(bytes.NewReader(a))
+	if err != nil {
+		return 0, fmt.Errorf("monitor: decode previous screenshot: %w", err)
+	}
+	ib, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return 0, fmt.Errorf("monitor: decode screenshot: %w", err)
+	}
+	return imageDiff(ia, ib), nil
+}
+
+func imageDiff(a, b image.Image) float64 {
+	ra, rb := a.Bounds(), b.Bounds()
+	w, h := max(ra.Dx(), rb.Dx()), max(ra.Dy(), rb.Dy())
+	total := w * h
+	if total == 0 {
+		return 0
+	}
+	ow, oh := min(ra.Dx(), rb.Dx()), min(ra.Dy(), rb.Dy())
+	changed := total - ow*oh
+	for y := 0; y < oh; y++ {
+		for x := 0; x < ow; x++ {
+			r1, g1, b1, a1 := a.At(ra.Min.X+x, ra.Min.Y+y).RGBA()
+			r2, g2, b2, a2 := b.At(rb.Min.X+x, rb.Min.Y+y).RGBA()
+			if far(r1, r2) || far(g1, g2) || far(b1, b2) || far(a1, a2) {
+				changed++
+			}
+		}
+	}
+	return float64(changed) / float64(total)
+}
+
+func far(x, y uint32) bool {
+	if x > y {
+		return x-y > pixelTolerance
+	}
+	return y-x > pixelTolerance
+}
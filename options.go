@@ -52,3 +52,48 @@ func WithHTTPClient(hc *http.Client) Option {
 		c.httpClient = hc
 	}
 }
+
+// WithCredentials sets the CredentialProvider consulted for the API key on
+// every request, replacing the key passed to New.
+//
+//	client := snapapi.New("", snapapi.WithCredentials(snapapi.NewFileCredentials("/run/secrets/snapapi")))
+func WithCredentials(p CredentialProvider) Option {
+	return func(c *Client) {
+		c.creds = p
+	}
+}
+
+// WithAuthMode selects which header(s) or query parameter carry the API key.
+// Default is AuthDefault (X-Api-Key and Authorization: Bearer).
+//
+//	client := snapapi.New("sk_...", snapapi.WithAuthMode(snapapi.AuthQueryParam))
+func WithAuthMode(m AuthMode) Option {
+	return func(c *Client) {
+		c.authMode = m
+	}
+}
+
+// WithBudget attaches a Budget that refuses or throttles billable requests
+// before the account quota is exceeded.
+//
+//	budget, err := snapapi.NewBudget(snapapi.BudgetOptions{SoftThreshold: 0.8})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	client := snapapi.New("sk_...", snapapi.WithBudget(budget))
+func WithBudget(b *Budget) Option {
+	return func(c *Client) {
+		c.budget = b
+	}
+}
+
+// WithRecorder reports every call made by the client to r, e.g. a
+// JSONLRecorder, for local usage accounting and auditing.
+//
+//	rec, _ := snapapi.NewJSONLRecorder(snapapi.JSONLRecorderOptions{Dir: "audit", Daily: true})
+//	client := snapapi.New("sk_...", snapapi.WithRecorder(rec))
+func WithRecorder(r Recorder) Option {
+	return func(c *Client) {
+		c.recorder = r
+	}
+}
@@ -62,7 +62,7 @@ func (c *Client) PDF(ctx context.Context, p PDFParams) ([]byte, error) {
 	if p.MarginRight != "" {
 		body["margin_right"] = p.MarginRight
 	}
-	return c.doRaw(ctx, http.MethodPost, "/v1/screenshot", body)
+	return c.doBillable(ctx, "pdf", http.MethodPost, "/v1/screenshot", body)
 }
 
 // GeneratePDF is an alias for PDF, provided for convenience.
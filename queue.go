@@ -0,0 +1,647 @@
+package snapapi
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// QueueState is the persisted state of a queued job.
+type QueueState string
+
+// Queue job states. Jobs that were running when the process stopped are
+// pending again when the queue is reopened.
+const (
+	QueuePending QueueState = "pending"
+	QueueRunning QueueState = "running"
+	QueueDone    QueueState = "done"
+	QueueDead    QueueState = "dead"
+)
+
+// QueueJob is a snapshot of one job in a Queue.
+type QueueJob struct {
+	// ID is the canonical hash of the job's kind and params. Adding the same
+	// params twice yields the same ID.
+	ID string
+	// Kind is "screenshot", "pdf", "video", "scrape" or "extract".
+	Kind string
+	// Params is the job's ScreenshotParams, PDFParams, VideoParams,
+	// ScrapeParams or ExtractParams value.
+	Params interface{}
+	// Priority orders pending jobs; higher runs first.
+	Priority int
+	// State is the job's current state.
+	State QueueState
+	// Attempts is the number of failed attempts so far.
+	Attempts int
+	// LastError is the error from the most recent failed attempt.
+	LastError string
+	// AddedAt is when the job was first added.
+	AddedAt time.Time
+}
+
+// QueueStats counts the jobs in a Queue by state.
+type QueueStats struct {
+	Pending int
+	Running int
+	Done    int
+	Dead    int
+}
+
+// QueueOptions configures a Queue.
+type QueueOptions struct {
+	// MaxAttempts is the number of failed attempts after which a job is
+	// dead-lettered. Validation errors dead-letter a job immediately.
+	// Default: 3.
+	MaxAttempts int
+	// Concurrency is the number of jobs Run executes at once. Default: 4.
+	Concurrency int
+	// PerItemTimeout bounds each attempt, including client retries. Zero
+	// means no timeout.
+	PerItemTimeout time.Duration
+	// Sync fsyncs the journal after every write. Slower, but no completed
+	// job is lost on power failure (a process crash loses nothing either
+	// way).
+	Sync bool
+}
+
+// QueueHandler receives each successful job's result during Run, e.g. to
+// write the capture to disk. The job is only marked done once the handler
+// returns nil; an error counts as a failed attempt.
+type QueueHandler func(ctx context.Context, job QueueJob, res BatchResult) error
+
+// Queue is a durable, resumable job queue for large capture runs. Jobs and
+// their state changes are appended to a journal file on local disk, so a run
+// that is interrupted can be restarted without redoing completed jobs.
+//
+//	q, err := snapapi.OpenQueue("captures.journal", snapapi.QueueOptions{MaxAttempts: 5})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer q.Close()
+//	for _, u := range urls {
+//	    q.Add(snapapi.ScreenshotParams{URL: u, FullPage: true}, 0) // no-op if already queued
+//	}
+//	err = q.Run(ctx, client, func(ctx context.Context, job snapapi.QueueJob, res snapapi.BatchResult) error {
+//	    return os.WriteFile(filepath.Join("out", job.ID+".png"), res.Data, 0644)
+//	})
+type Queue struct {
+	opts QueueOptions
+	path string
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	f       queueJournal
+	jobs    map[string]*queueJob
+	pending queueHeap
+	seq     int64
+	running int
+	err     error
+	closed  bool
+}
+
+// queueJournal is the journal file; tests substitute a failing writer.
+type queueJournal interface {
+	io.Writer
+	Sync() error
+	Close() error
+}
+
+type queueJob struct {
+	QueueJob
+	seq   int64
+	index int // position in Queue.pending, -1 if not pending
+}
+
+// queueRecord is one line of the journal.
+type queueRecord struct {
+	// Op is "add", "done", "fail", "dead" or "requeue".
+	Op       string          `json:"op"`
+	ID       string          `json:"id"`
+	Kind     string          `json:"kind,omitempty"`
+	Priority int             `json:"priority,omitempty"`
+	Params   json.RawMessage `json:"params,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Time     time.Time       `json:"time"`
+	// State and Attempts are set on add records written by Compact.
+	State    QueueState `json:"state,omitempty"`
+	Attempts int        `json:"attempts,omitempty"`
+}
+
+// OpenQueue opens the queue journaled at path, creating it if needed, and
+// replays it. Jobs left running by a previous process become pending.
+func OpenQueue(path string, opts QueueOptions) (*Queue, error) {
+	if path == "" {
+		return nil, &APIError{Code: ErrInvalidParams, Message: "path is required", StatusCode: 400}
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	q := &Queue{opts: opts, path: path, jobs: make(map[string]*queueJob)}
+	q.cond = sync.NewCond(&q.mu)
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("snapapi: open queue journal: %w", err)
+	}
+	if err := q.replay(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	q.f = f
+	return q, nil
+}
+
+// replay applies every journal record in f and leaves f positioned for
+// appending.
+func (q *Queue) replay(f *os.File) error {
+	r := bufio.NewReader(f)
+	var last byte
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			last = line[len(line)-1]
+			var rec queueRecord
+			// A malformed line is a write torn by a crash; skip it.
+			if json.Unmarshal(line, &rec) == nil {
+				q.apply(rec)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("snapapi: read queue journal: %w", err)
+		}
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("snapapi: read queue journal: %w", err)
+	}
+	if last != 0 && last != '\n' {
+		// Terminate a torn final line so the next record starts cleanly.
+		if _, err := f.Write([]byte{'\n'}); err != nil {
+			return fmt.Errorf("snapapi: write queue journal: %w", err)
+		}
+	}
+	return nil
+}
+
+// apply updates in-memory state for one journal record.
+func (q *Queue) apply(rec queueRecord) {
+	if rec.Op == "add" {
+		if _, ok := q.jobs[rec.ID]; ok {
+			return
+		}
+		params, err := decodeBatchParams(rec.Kind, rec.Params)
+		if err != nil {
+			return
+		}
+		state := rec.State
+		if state == "" || state == QueueRunning {
+			state = QueuePending
+		}
+		q.seq++
+		j := &queueJob{
+			QueueJob: QueueJob{
+				ID: rec.ID, Kind: rec.Kind, Params: params, Priority: rec.Priority,
+				State: state, Attempts: rec.Attempts, LastError: rec.Error, AddedAt: rec.Time,
+			},
+			seq:   q.seq,
+			index: -1,
+		}
+		q.jobs[rec.ID] = j
+		if state == QueuePending {
+			heap.Push(&q.pending, j)
+		}
+		return
+	}
+
+	j, ok := q.jobs[rec.ID]
+	if !ok {
+		return
+	}
+	switch rec.Op {
+	case "done":
+		q.setState(j, QueueDone)
+	case "fail":
+		j.Attempts++
+		j.LastError = rec.Error
+		q.seq++
+		j.seq = q.seq
+		q.setState(j, QueuePending)
+	case "dead":
+		j.Attempts++
+		j.LastError = rec.Error
+		q.setState(j, QueueDead)
+	case "requeue":
+		j.Attempts = 0
+		q.setState(j, QueuePending)
+	}
+}
+
+// setState moves j to state, keeping the pending heap in sync. q.mu must be
+// held (or the queue not yet shared).
+func (q *Queue) setState(j *queueJob, state QueueState) {
+	if j.index >= 0 {
+		heap.Remove(&q.pending, j.index)
+	}
+	j.State = state
+	if state == QueuePending {
+		heap.Push(&q.pending, j)
+	}
+}
+
+// Add queues a job for params, which must be a ScreenshotParams, PDFParams,
+// VideoParams, ScrapeParams or ExtractParams (or a pointer to one). It
+// returns the job's ID and whether it was newly added; params already in
+// the queue, in any state, are not added again.
+func (q *Queue) Add(params interface{}, priority int) (string, bool, error) {
+	kind := batchKind(params)
+	if kind == "" {
+		return "", false, &APIError{
+			Code:       ErrInvalidParams,
+			Message:    fmt.Sprintf("unsupported queue params type %T", params),
+			StatusCode: 400,
+		}
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return "", false, fmt.Errorf("snapapi: marshal queue params: %w", err)
+	}
+	id := paramsHash(struct {
+		Kind   string          `json:"kind"`
+		Params json.RawMessage `json:"params"`
+	}{kind, raw})
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return "", false, errQueueClosed
+	}
+	if _, ok := q.jobs[id]; ok {
+		return id, false, nil
+	}
+	rec := queueRecord{Op: "add", ID: id, Kind: kind, Priority: priority, Params: raw, Time: time.Now().UTC()}
+	if err := q.write(rec); err != nil {
+		return "", false, err
+	}
+	q.apply(rec)
+	q.cond.Broadcast()
+	return id, true, nil
+}
+
+// Get returns a snapshot of the job with the given ID.
+func (q *Queue) Get(id string) (QueueJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return QueueJob{}, false
+	}
+	return j.QueueJob, true
+}
+
+// Stats counts jobs by state.
+func (q *Queue) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var s QueueStats
+	for _, j := range q.jobs {
+		switch j.State {
+		case QueuePending:
+			s.Pending++
+		case QueueRunning:
+			s.Running++
+		case QueueDone:
+			s.Done++
+		case QueueDead:
+			s.Dead++
+		}
+	}
+	return s
+}
+
+// DeadLetters returns the jobs that were dead-lettered, oldest first.
+func (q *Queue) DeadLetters() []QueueJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var out []*queueJob
+	for _, j := range q.jobs {
+		if j.State == QueueDead {
+			out = append(out, j)
+		}
+	}
+	sortQueueJobs(out)
+	jobs := make([]QueueJob, len(out))
+	for i, j := range out {
+		jobs[i] = j.QueueJob
+	}
+	return jobs
+}
+
+// RequeueDead makes every dead-lettered job pending again with its attempt
+// count reset, and returns how many were requeued.
+func (q *Queue) RequeueDead() (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, j := range q.jobs {
+		if j.State != QueueDead {
+			continue
+		}
+		rec := queueRecord{Op: "requeue", ID: j.ID, Time: time.Now().UTC()}
+		if err := q.write(rec); err != nil {
+			return n, err
+		}
+		q.apply(rec)
+		n++
+	}
+	q.cond.Broadcast()
+	return n, nil
+}
+
+// Run executes pending jobs through c, highest priority first, until none
+// remain or ctx is cancelled. Successful results are passed to handle (which
+// may be nil). Failed jobs are retried later in the run, behind other jobs
+// of the same priority, until MaxAttempts is reached.
+//
+// If ctx is cancelled, jobs in flight are left pending for the next Run and
+// ctx.Err() is returned. If the journal cannot be written, Run stops taking
+// jobs and returns the write error; a job whose outcome could not be
+// recorded stays running until the queue is reopened, so it is not captured
+// again in this process.
+func (q *Queue) Run(ctx context.Context, c *Client, handle QueueHandler) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return errQueueClosed
+	}
+	q.mu.Unlock()
+
+	stop := context.AfterFunc(ctx, func() {
+		q.mu.Lock()
+		q.cond.Broadcast()
+		q.mu.Unlock()
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	for w := 0; w < q.opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				j := q.take(ctx)
+				if j == nil {
+					return
+				}
+				res := c.runBatchItem(ctx, 0, BatchItem{ID: j.ID, Params: j.Params}, q.opts.PerItemTimeout)
+				err := res.Err
+				if err == nil && handle != nil {
+					err = handle(ctx, *j, res)
+				}
+				q.finish(ctx, j.ID, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return q.err
+}
+
+// take blocks until a pending job is available and marks it running. It
+// returns nil once the queue has drained, ctx is cancelled or a journal
+// write has failed.
+func (q *Queue) take(ctx context.Context) *QueueJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		if ctx.Err() != nil || q.closed || q.err != nil {
+			return nil
+		}
+		if q.pending.Len() > 0 {
+			j := heap.Pop(&q.pending).(*queueJob)
+			j.State = QueueRunning
+			q.running++
+			snapshot := j.QueueJob
+			return &snapshot
+		}
+		if q.running == 0 {
+			return nil
+		}
+		q.cond.Wait()
+	}
+}
+
+// finish records the outcome of a running job.
+func (q *Queue) finish(ctx context.Context, id string, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.cond.Broadcast()
+	q.running--
+	j := q.jobs[id]
+
+	rec := queueRecord{ID: id, Time: time.Now().UTC()}
+	switch {
+	case err == nil:
+		rec.Op = "done"
+	case ctx.Err() != nil:
+		// Interrupted, not failed: leave it for the next Run.
+		q.setState(j, QueuePending)
+		return
+	case errors.Is(err, ErrValidation) || j.Attempts+1 >= q.opts.MaxAttempts:
+		rec.Op, rec.Error = "dead", err.Error()
+	default:
+		rec.Op, rec.Error = "fail", err.Error()
+	}
+	if werr := q.write(rec); werr != nil {
+		// The outcome is lost. Leave the job running so this process does
+		// not capture it again; write has set q.err, which stops Run, and
+		// the job is pending again when the journal is replayed.
+		return
+	}
+	q.apply(rec)
+}
+
+// Compact rewrites the journal with one record per job, dropping the
+// history of state changes. It must not be called during Run.
+func (q *Queue) Compact() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return errQueueClosed
+	}
+	all := make([]*queueJob, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		all = append(all, j)
+	}
+	sortQueueJobs(all)
+
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), ".snapapi-queue-*")
+	if err != nil {
+		return fmt.Errorf("snapapi: compact queue journal: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, j := range all {
+		raw, err := json.Marshal(j.Params)
+		if err != nil {
+			tmp.Close()
+			return fmt.Errorf("snapapi: compact queue journal: %w", err)
+		}
+		state := j.State
+		if state == QueueRunning {
+			state = QueuePending
+		}
+		rec := queueRecord{
+			Op: "add", ID: j.ID, Kind: j.Kind, Priority: j.Priority, Params: raw,
+			Error: j.LastError, Time: j.AddedAt, State: state, Attempts: j.Attempts,
+		}
+		if err := enc.Encode(rec); err != nil {
+			tmp.Close()
+			return fmt.Errorf("snapapi: compact queue journal: %w", err)
+		}
+	}
+	if err := w.Flush(); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("snapapi: compact queue journal: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), q.path); err != nil {
+		return fmt.Errorf("snapapi: compact queue journal: %w", err)
+	}
+	f, err := os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("snapapi: reopen queue journal: %w", err)
+	}
+	q.f.Close()
+	q.f = f
+	return nil
+}
+
+// Close closes the journal. A Run in progress stops taking new jobs.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	q.cond.Broadcast()
+	return q.f.Close()
+}
+
+var errQueueClosed = errors.New("snapapi: queue is closed")
+
+// write appends rec to the journal. q.mu must be held.
+func (q *Queue) write(rec queueRecord) error {
+	if q.closed {
+		return errQueueClosed
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("snapapi: encode queue record: %w", err)
+	}
+	line = append(line, '\n')
+	if _, err = q.f.Write(line); err == nil && q.opts.Sync {
+		err = q.f.Sync()
+	}
+	if err != nil {
+		err = fmt.Errorf("snapapi: write queue journal: %w", err)
+		if q.err == nil {
+			q.err = err
+		}
+		return err
+	}
+	return nil
+}
+
+// decodeBatchParams decodes params journaled for a job of the given kind.
+func decodeBatchParams(kind string, raw json.RawMessage) (interface{}, error) {
+	var err error
+	switch kind {
+	case "screenshot":
+		var p ScreenshotParams
+		err = json.Unmarshal(raw, &p)
+		return p, err
+	case "pdf":
+		var p PDFParams
+		err = json.Unmarshal(raw, &p)
+		return p, err
+	case "video":
+		var p VideoParams
+		err = json.Unmarshal(raw, &p)
+		return p, err
+	case "scrape":
+		var p ScrapeParams
+		err = json.Unmarshal(raw, &p)
+		return p, err
+	case "extract":
+		var p ExtractParams
+		err = json.Unmarshal(raw, &p)
+		return p, err
+	}
+	return nil, fmt.Errorf("snapapi: unknown job kind %q", kind)
+}
+
+func sortQueueJobs(jobs []*queueJob) {
+	sort.Slice(jobs, func(i, j int) bool {
+		a, b := jobs[i], jobs[j]
+		if !a.AddedAt.Equal(b.AddedAt) {
+			return a.AddedAt.Before(b.AddedAt)
+		}
+		return a.seq < b.seq
+	})
+}
+
+// queueHeap orders pending jobs by priority (highest first), then by
+// sequence (oldest first).
+type queueHeap []*queueJob
+
+func (h queueHeap) Len() int { return len(h) }
+func (h queueHeap) Less(i, j int) bool {
+	if h[i].Priority != h[j].Priority {
+		return h[i].Priority > h[j].Priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h queueHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *queueHeap) Push(x interface{}) {
+	j := x.(*queueJob)
+	j.index = len(*h)
+	*h = append(*h, j)
+}
+func (h *queueHeap) Pop() interface{} {
+	old := *h
+	j := old[len(old)-1]
+	old[len(old)-1] = nil
+	j.index = -1
+	*h = old[:len(old)-1]
+	return j
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
@@ -11,22 +12,72 @@ import (
 	"time"
 )
 
-// doRaw executes a request and returns the raw response body.
-// Retries on transient errors (5xx, 429, network failures) with exponential
-// back-off. When the server sends a Retry-After header that value is used as
-// the wait duration instead of the computed back-off.
+// callStats collects per-call details for the Recorder.
+type callStats struct {
+	attempts int
+	status   int
+}
+
+// doRaw executes a request and returns the raw response body. Every call is
+// reported to the client's Recorder, if any.
 func (c *Client) doRaw(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+	return c.doBillable(ctx, "", method, path, body)
+}
+
+// doBillable is doRaw for a request that consumes quota. endpoint names it
+// for the client's Budget ("screenshot", "pdf", ...), which is checked
+// before anything is sent; "" means the request is not billable.
+func (c *Client) doBillable(ctx context.Context, endpoint, method, path string, body interface{}) ([]byte, error) {
+	var stats callStats
+	start := time.Now()
+	data, err := c.doBudgeted(ctx, endpoint, method, path, body, &stats)
+	if c.recorder != nil {
+		c.record(ctx, endpoint, method, path, body, data, err, stats, start)
+	}
+	return data, err
+}
+
+// doBudgeted runs doRetry under the client's Budget.
+func (c *Client) doBudgeted(ctx context.Context, endpoint, method, path string, body interface{}, stats *callStats) ([]byte, error) {
+	if c.budget == nil || endpoint == "" {
+		return c.doRetry(ctx, method, path, body, stats)
+	}
+	release, err := c.budget.acquire(ctx, c, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.doRetry(ctx, method, path, body, stats)
+	release(err)
+	return data, err
+}
+
+// doRetry executes a request, retrying on transient errors (5xx, 429, network
+// failures) with exponential back-off. When the server sends a Retry-After
+// header that value is used as the wait duration instead of the computed
+// back-off.
+func (c *Client) doRetry(ctx context.Context, method, path string, body interface{}, stats *callStats) ([]byte, error) {
 	var (
 		lastErr error
 		delay   = c.retryDelay
 	)
 	attempts := c.retries + 1
+	failovers := 0
 	for i := 0; i < attempts; i++ {
-		data, err := c.roundTrip(ctx, method, path, body)
+		data, status, err := c.roundTrip(ctx, method, path, body)
+		stats.attempts++
+		stats.status = status
 		if err == nil {
 			return data, nil
 		}
 
+		// With a KeyPool, quota and rate-limit errors switch to the next key
+		// immediately without consuming a retry.
+		if pool, ok := c.creds.(*KeyPool); ok && failovers < pool.size() && isKeyExhausted(err) && pool.hasAvailable() {
+			failovers++
+			i--
+			continue
+		}
+
 		// Determine whether this attempt is retryable.
 		var apiErr *APIError
 		if !isRetryable(err, &apiErr) || i >= attempts-1 {
@@ -57,7 +108,13 @@ func (c *Client) doRaw(ctx context.Context, method, path string, body interface{
 
 // doJSON is like doRaw but JSON-unmarshals the response into dst.
 func (c *Client) doJSON(ctx context.Context, method, path string, body interface{}, dst interface{}) error {
-	data, err := c.doRaw(ctx, method, path, body)
+	return c.doBillableJSON(ctx, "", method, path, body, dst)
+}
+
+// doBillableJSON is doJSON for a request that consumes quota; see
+// doBillable.
+func (c *Client) doBillableJSON(ctx context.Context, endpoint, method, path string, body interface{}, dst interface{}) error {
+	data, err := c.doBillable(ctx, endpoint, method, path, body)
 	if err != nil {
 		return err
 	}
@@ -70,29 +127,67 @@ func (c *Client) doJSON(ctx context.Context, method, path string, body interface
 	return nil
 }
 
-// roundTrip performs a single HTTP request/response cycle.
-func (c *Client) roundTrip(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+// roundTrip performs a single HTTP request/response cycle, resolving the API
+// key from the client's CredentialProvider.
+// It returns the response body and HTTP status code (0 if no response was
+// received).
+func (c *Client) roundTrip(ctx context.Context, method, path string, body interface{}) ([]byte, int, error) {
+	apiKey, pinned := ctx.Value(pinnedKeyCtx{}).(string)
+	if !pinned {
+		var err error
+		if apiKey, err = c.creds.APIKey(ctx); err != nil {
+			var apiErr *APIError
+			if errors.As(err, &apiErr) {
+				return nil, 0, apiErr
+			}
+			return nil, 0, &APIError{Code: ErrUnauthorized, Message: "resolve credentials: " + err.Error()}
+		}
+	}
+	data, status, err := c.send(ctx, method, path, body, apiKey)
+	if pool, ok := c.creds.(*KeyPool); ok && !pinned {
+		pool.observe(ctx, c, apiKey, err)
+	}
+	return data, status, err
+}
+
+// isKeyExhausted reports whether err means the API key used is out of quota
+// or rate limited.
+func isKeyExhausted(err error) bool {
+	return errors.Is(err, ErrQuota) || errors.Is(err, ErrRateLimit)
+}
+
+// send performs one HTTP request authenticated with apiKey.
+func (c *Client) send(ctx context.Context, method, path string, body interface{}, apiKey string) ([]byte, int, error) {
 	var bodyReader io.Reader
 	if body != nil {
 		b, err := json.Marshal(body)
 		if err != nil {
-			return nil, fmt.Errorf("snapapi: marshal request: %w", err)
+			return nil, 0, fmt.Errorf("snapapi: marshal request: %w", err)
 		}
 		bodyReader = bytes.NewReader(b)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bodyReader)
 	if err != nil {
-		return nil, fmt.Errorf("snapapi: build request: %w", err)
+		return nil, 0, fmt.Errorf("snapapi: build request: %w", err)
+	}
+	if c.authMode&AuthAPIKeyHeader != 0 {
+		req.Header.Set("X-Api-Key", apiKey)
+	}
+	if c.authMode&AuthBearer != 0 {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	if c.authMode&AuthQueryParam != 0 {
+		q := req.URL.Query()
+		q.Set("access_key", apiKey)
+		req.URL.RawQuery = q.Encode()
 	}
-	req.Header.Set("X-Api-Key", c.apiKey)
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", userAgent)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, &APIError{
+		return nil, 0, &APIError{
 			Code:    ErrConnectionError,
 			Message: err.Error(),
 		}
@@ -101,11 +196,11 @@ func (c *Client) roundTrip(ctx context.Context, method, path string, body interf
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("snapapi: read response: %w", err)
+		return nil, resp.StatusCode, fmt.Errorf("snapapi: read response: %w", err)
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, parseAPIError(respBody, resp.StatusCode, resp.Header)
+		return nil, resp.StatusCode, parseAPIError(respBody, resp.StatusCode, resp.Header)
 	}
-	return respBody, nil
+	return respBody, resp.StatusCode, nil
 }
@@ -0,0 +1,142 @@
+package snapapi
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robotsRules are the robots.txt rules that apply to one user agent.
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	// err is set when robots.txt could not be fetched. The rules then
+	// disallow everything, as RFC 9309 requires for an unreachable file.
+	err error
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// parseRobots parses a robots.txt body (RFC 9309) and returns the group for
+// userAgent, falling back to the "*" group.
+func parseRobots(body, userAgent string) *robotsRules {
+	type group struct {
+		agents []string
+		rules  robotsRules
+	}
+	var (
+		groups  []*group
+		cur     *group
+		inRules bool
+	)
+	sc := bufio.NewScanner(strings.NewReader(body))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if cur == nil || inRules {
+				cur = &group{}
+				groups = append(groups, cur)
+				inRules = false
+			}
+			cur.agents = append(cur.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if cur == nil {
+				continue
+			}
+			inRules = true
+			if value == "" {
+				// "Disallow:" with no path allows everything.
+				continue
+			}
+			cur.rules.rules = append(cur.rules.rules, robotsRule{allow: key == "allow", pattern: value})
+		case "crawl-delay":
+			if cur == nil {
+				continue
+			}
+			inRules = true
+			if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+				cur.rules.crawlDelay = time.Duration(secs * float64(time.Second))
+			}
+		}
+	}
+
+	ua := strings.ToLower(userAgent)
+	var fallback *robotsRules
+	for _, g := range groups {
+		for _, a := range g.agents {
+			if a == "*" {
+				if fallback == nil {
+					fallback = &g.rules
+				}
+			} else if ua != "" && strings.Contains(ua, a) {
+				return &g.rules
+			}
+		}
+	}
+	if fallback != nil {
+		return fallback
+	}
+	return &robotsRules{}
+}
+
+// allowed reports whether path (including any query) may be fetched. The
+// longest matching rule wins; Allow wins ties.
+func (r *robotsRules) allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+	best, allow := -1, true
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		n := len(rule.pattern)
+		if n > best || (n == best && rule.allow) {
+			best, allow = n, rule.allow
+		}
+	}
+	return allow
+}
+
+// robotsMatch matches path against a robots.txt pattern, where "*" matches
+// any sequence and a trailing "$" anchors the end.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for _, part := range parts[1:] {
+		i := strings.Index(path[pos:], part)
+		if i < 0 {
+			return false
+		}
+		pos += i + len(part)
+	}
+	if !anchored {
+		return true
+	}
+	if len(parts) > 1 {
+		// The last segment may match later in the path; anchor it to the end.
+		return strings.HasSuffix(path, parts[len(parts)-1])
+	}
+	return pos == len(path)
+}
@@ -28,18 +28,25 @@ type ScrapeParams struct {
 	AccessKey string `json:"access_key,omitempty"`
 }
 
-// scrapeResultItem is a single page result within the API's results array.
-type scrapeResultItem struct {
-	Page int    `json:"page"`
-	URL  string `json:"url"`
+// ScrapePage is a single page result: one item of the API's results array,
+// or one page of a paginated scrape (see ScrapePages).
+type ScrapePage struct {
+	// Page is the 1-based page number.
+	Page int `json:"page"`
+	// URL is the final URL of the page after any redirects.
+	URL string `json:"url"`
+	// Data contains the scraped content of the page.
 	Data string `json:"data"`
+	// Status is the HTTP status code the page was served with, if the API
+	// reported it.
+	Status int `json:"status,omitempty"`
 }
 
 // scrapeAPIResponse is the raw shape the SnapAPI server returns for /v1/scrape.
 // The API wraps results in a "results" array (one item per scraped page).
 type scrapeAPIResponse struct {
-	Success bool               `json:"success"`
-	Results []scrapeResultItem `json:"results"`
+	Success bool         `json:"success"`
+	Results []ScrapePage `json:"results"`
 }
 
 // ScrapeResult is the structured response from the scrape endpoint.
@@ -52,7 +59,7 @@ type ScrapeResult struct {
 	// Status is the HTTP status code of the scraped page.
 	Status int `json:"status"`
 	// AllResults holds all page results when multi-page scraping was requested.
-	AllResults []scrapeResultItem `json:"-"`
+	AllResults []ScrapePage `json:"-"`
 }
 
 // Scrape fetches text or structured content from a URL.
@@ -66,13 +73,14 @@ func (c *Client) Scrape(ctx context.Context, p ScrapeParams) (*ScrapeResult, err
 	// The API wraps results in {"success":true,"results":[{"page":N,"url":"...","data":"..."}]}.
 	// We unwrap to a flat ScrapeResult for backward compatibility.
 	var raw scrapeAPIResponse
-	if err := c.doJSON(ctx, http.MethodPost, "/v1/scrape", p, &raw); err != nil {
+	if err := c.doBillableJSON(ctx, "scrape", http.MethodPost, "/v1/scrape", p, &raw); err != nil {
 		return nil, err
 	}
 	result := &ScrapeResult{AllResults: raw.Results}
 	if len(raw.Results) > 0 {
 		result.Data = raw.Results[0].Data
 		result.URL = raw.Results[0].URL
+		result.Status = raw.Results[0].Status
 	}
 	return result, nil
 }
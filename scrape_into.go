@@ -0,0 +1,664 @@
+package snapapi
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScrapeInto fills dst from pageURL in a single request. The `snap` struct
+// tags of T (see DecodeHTML) are sent to the API as ScrapeParams.Selectors,
+// and the JSON values it returns for them are converted to the field types.
+// If any field uses attr=, html or tag, which need the element itself rather
+// than its text, or is a slice of structs, whose items must each be decoded
+// within their own element, the page's HTML is scraped instead and decoded
+// with DecodeHTML.
+//
+//	type Product struct {
+//	    Name   string    `snap:"h1.title"`
+//	    Price  float64   `snap:".price,attr=data-value"`
+//	    Image  *url.URL  `snap:"img.hero,attr=src"`
+//	    Tags   []string  `snap:".tags li"`
+//	    Posted time.Time `snap:"time.published"`
+//	    Specs  []struct {
+//	        Key   string `snap:"th"`
+//	        Value string `snap:"td"`
+//	    } `snap:"table.specs tr"`
+//	}
+//	var p Product
+//	err := snapapi.ScrapeInto(ctx, client, "https://shop.example.com/item/42", &p)
+//
+// The selectors of nested struct fields are combined with their parent's
+// (".seller .name").
+func ScrapeInto[T any](ctx context.Context, c *Client, pageURL string, dst *T) error {
+	if dst == nil {
+		return &APIError{Code: ErrInvalidParams, Message: "dst is required", StatusCode: 400}
+	}
+	t := reflect.TypeOf(dst).Elem()
+	if t.Kind() != reflect.Struct {
+		return &APIError{Code: ErrInvalidParams, Message: fmt.Sprintf("ScrapeInto needs a struct type, not %s", t), StatusCode: 400}
+	}
+	plan := selectorPlan{selectors: make(map[string]string)}
+	if err := plan.add(t, "", "", t.Name()); err != nil {
+		return err
+	}
+	if len(plan.selectors) == 0 && !plan.html {
+		return &APIError{Code: ErrInvalidParams, Message: t.String() + " has no snap-tagged fields", StatusCode: 400}
+	}
+
+	if plan.html {
+		res, err := c.Scrape(ctx, ScrapeParams{URL: pageURL, Format: "html"})
+		if err != nil {
+			return err
+		}
+		base := pageURL
+		if res.URL != "" {
+			base = res.URL
+		}
+		return DecodeHTML(res.Data, base, dst)
+	}
+
+	res, err := c.Scrape(ctx, ScrapeParams{URL: pageURL, Format: "json", Selectors: plan.selectors})
+	if err != nil {
+		return err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(res.Data), &raw); err != nil {
+		return fmt.Errorf("snapapi: decode scrape response: %w", err)
+	}
+	values := make(map[string][]string, len(raw))
+	for key, msg := range raw {
+		if values[key], err = selectedValues(msg); err != nil {
+			return fmt.Errorf("snapapi: decode scrape response: %s: %w", key, err)
+		}
+	}
+
+	base := pageURL
+	if res.URL != "" {
+		base = res.URL
+	}
+	d := &htmlDecoder{}
+	if u, err := url.Parse(base); err == nil {
+		d.base = u
+	}
+	return d.fillSelected(values, reflect.ValueOf(dst).Elem(), "", t.Name())
+}
+
+// selectorPlan is how ScrapeInto requests a struct type: the Selectors to
+// send, keyed by dotted field path, or the whole HTML if html is set.
+type selectorPlan struct {
+	selectors map[string]string
+	html      bool
+}
+
+// add validates the fields of struct type t and adds their selectors, keyed
+// after prefix. scope is the selector of the enclosing struct field.
+func (p *selectorPlan) add(t reflect.Type, prefix, scope, path string) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		raw, ok := f.Tag.Lookup("snap")
+		if !ok || raw == "-" || !f.IsExported() {
+			continue
+		}
+		tag := parseSnapTag(raw)
+		key, fieldPath := prefix+f.Name, path+"."+f.Name
+		invalid := func(msg string) error {
+			return &APIError{Code: ErrInvalidParams, Message: "ScrapeInto: " + fieldPath + ": " + msg, StatusCode: 400}
+		}
+		if tag.attr != "" || tag.html || tag.tag {
+			// The API returns element text only.
+			p.html = true
+		}
+		sel := scopeSelector(scope, tag.selector)
+		if sel == "" {
+			return invalid("no selector")
+		}
+		if _, err := compileCSS(sel); err != nil {
+			return invalid(err.Error())
+		}
+
+		ft := derefType(f.Type)
+		if ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8 {
+			if et := derefType(ft.Elem()); isNestedStruct(et) {
+				// Separate selector lists for each field cannot tell
+				// which item a value belongs to.
+				p.html = true
+				if err := p.add(et, key+".", sel, fieldPath); err != nil {
+					return err
+				}
+				continue
+			}
+		} else if isNestedStruct(ft) {
+			if err := p.add(ft, key+".", sel, fieldPath); err != nil {
+				return err
+			}
+			continue
+		}
+		p.selectors[key] = sel
+	}
+	return nil
+}
+
+// fillSelected stores the selected values in the fields of struct sv.
+func (d *htmlDecoder) fillSelected(values map[string][]string, sv reflect.Value, prefix, path string) error {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		raw, ok := f.Tag.Lookup("snap")
+		if !ok || raw == "-" || !f.IsExported() {
+			continue
+		}
+		tag := parseSnapTag(raw)
+		key, fieldPath := prefix+f.Name, path+"."+f.Name
+		fv := sv.Field(i)
+
+		if t := derefType(fv.Type()); isNestedStruct(t) {
+			target := fv
+			if fv.Kind() == reflect.Ptr {
+				if !anySelected(values, key+".") {
+					continue
+				}
+				fv.Set(reflect.New(t))
+				target = fv.Elem()
+			}
+			if err := d.fillSelected(values, target, key+".", fieldPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		vals := values[key]
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			et := fv.Type().Elem()
+			out := reflect.MakeSlice(fv.Type(), 0, len(vals))
+			for j, s := range vals {
+				ev := reflect.New(et).Elem()
+				target := ev
+				if et.Kind() == reflect.Ptr {
+					ev.Set(reflect.New(et.Elem()))
+					target = ev.Elem()
+				}
+				if err := d.setSelected(target, s, tag, fmt.Sprintf("%s[%d]", fieldPath, j)); err != nil {
+					return err
+				}
+				out = reflect.Append(out, ev)
+			}
+			fv.Set(out)
+			continue
+		}
+
+		if len(vals) > 0 {
+			target := fv
+			if fv.Kind() == reflect.Ptr {
+				fv.Set(reflect.New(fv.Type().Elem()))
+				target = fv.Elem()
+			}
+			if err := d.setSelected(target, vals[0], tag, fieldPath); err != nil {
+				return err
+			}
+		} else if fv.Kind() == reflect.Bool {
+			fv.SetBool(false)
+		}
+	}
+	return nil
+}
+
+// setSelected stores one selected value in v.
+func (d *htmlDecoder) setSelected(v reflect.Value, s string, tag snapTag, path string) error {
+	if v.Kind() == reflect.Bool {
+		v.SetBool(true)
+		return nil
+	}
+	return d.setText(v, s, tag, path)
+}
+
+// selectedValues returns the values the API returned for one selector: a
+// string, a number or boolean, or an array of them. null is no match.
+func selectedValues(msg json.RawMessage) ([]string, error) {
+	var list []json.RawMessage
+	if err := json.Unmarshal(msg, &list); err != nil {
+		list = []json.RawMessage{msg}
+	}
+	var out []string
+	for _, m := range list {
+		var v interface{}
+		if err := json.Unmarshal(m, &v); err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case nil:
+		case string:
+			out = append(out, strings.TrimSpace(v))
+		case float64, bool:
+			out = append(out, string(m))
+		default:
+			return nil, fmt.Errorf("unexpected value %s", m)
+		}
+	}
+	return out, nil
+}
+
+// anySelected reports whether any field under prefix matched.
+func anySelected(values map[string][]string, prefix string) bool {
+	for key, vals := range values {
+		if strings.HasPrefix(key, prefix) && len(vals) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeSelector returns the selector for sel within the elements matched by
+// scope: "table tr" and "th, td" give "table tr th, table tr td". An empty
+// sel keeps the scope.
+func scopeSelector(scope, sel string) string {
+	if scope == "" || sel == "" {
+		return scope + sel
+	}
+	var out []string
+	for _, a := range splitSelectorList(scope) {
+		for _, b := range splitSelectorList(sel) {
+			out = append(out, a+" "+b)
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+// splitSelectorList splits a selector list at the commas that are not
+// inside brackets, parentheses or quotes.
+func splitSelectorList(sel string) []string {
+	var (
+		parts []string
+		depth int
+		quote byte
+		start int
+	)
+	for i := 0; i < len(sel); i++ {
+		switch c := sel[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(sel[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(sel[start:]))
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// DecodeHTML decodes an HTML document into the struct pointed to by v, using
+// CSS selectors given in `snap` struct tags:
+//
+//	`snap:"SELECTOR[,attr=NAME][,html][,tag][,layout=LAYOUT]"`
+//
+// Each field takes the first element matching SELECTOR (slices take every
+// match). By default the value is the element's whitespace-collapsed text;
+// attr=NAME uses an attribute instead, html uses the inner HTML and tag the
+// lowercase element name (useful with selector lists such as "h1, h2, h3").
+// Values are converted to the field type:
+//
+//   - string; href and src attributes are resolved against pageURL
+//   - int, uint and float kinds, ignoring currency symbols, spaces and
+//     thousands separators ("$1,299.00" is 1299); a fractional value such
+//     as "4.99" is an error for int and uint fields
+//   - bool: true if the selector matches anything
+//   - time.Time, parsed with layout=LAYOUT or common formats (RFC 3339,
+//     "2006-01-02", "January 2, 2006", ...); a <time datetime> attribute is
+//     used when present
+//   - url.URL or *url.URL, resolved against pageURL
+//   - any encoding.TextUnmarshaler
+//   - nested structs, whose fields are selected within the matched element
+//     (an empty SELECTOR keeps the current scope)
+//   - slices and pointers of all of the above; pointers stay nil when
+//     nothing matches
+//
+// Fields without a snap tag, or tagged `snap:"-"`, are left untouched.
+func DecodeHTML(htmlStr, pageURL string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return &APIError{Code: ErrInvalidParams, Message: "DecodeHTML needs a non-nil pointer to a struct", StatusCode: 400}
+	}
+	var base *url.URL
+	if pageURL != "" {
+		var err error
+		if base, err = url.Parse(pageURL); err != nil {
+			return &APIError{Code: ErrInvalidParams, Message: "invalid page URL: " + err.Error(), StatusCode: 400}
+		}
+	}
+	d := &htmlDecoder{base: base}
+	return d.decodeStruct(parseHTML(htmlStr), rv.Elem(), rv.Elem().Type().Name())
+}
+
+type htmlDecoder struct {
+	base *url.URL
+}
+
+// snapTag is a parsed `snap` struct tag.
+type snapTag struct {
+	selector string
+	attr     string
+	html     bool
+	tag      bool
+	layout   string
+}
+
+func parseSnapTag(tag string) snapTag {
+	var t snapTag
+	var sel []string
+	for _, part := range strings.Split(tag, ",") {
+		p := strings.TrimSpace(part)
+		switch {
+		case strings.HasPrefix(p, "attr="):
+			t.attr = strings.TrimPrefix(p, "attr=")
+		case strings.HasPrefix(p, "layout="):
+			t.layout = strings.TrimPrefix(p, "layout=")
+		case p == "html":
+			t.html = true
+		case p == "tag":
+			t.tag = true
+		default:
+			// Part of a selector list such as "h1, h2".
+			sel = append(sel, part)
+		}
+	}
+	t.selector = strings.TrimSpace(strings.Join(sel, ","))
+	return t
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	urlType             = reflect.TypeOf(url.URL{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func (d *htmlDecoder) decodeStruct(scope *htmlNode, sv reflect.Value, path string) error {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		raw, ok := f.Tag.Lookup("snap")
+		if !ok || raw == "-" || !f.IsExported() {
+			continue
+		}
+		tag := parseSnapTag(raw)
+		fieldPath := path + "." + f.Name
+		var sel cssSelector
+		if tag.selector != "" {
+			var err error
+			if sel, err = compileCSS(tag.selector); err != nil {
+				return fmt.Errorf("snapapi: decode %s: %w", fieldPath, err)
+			}
+		}
+
+		fv := sv.Field(i)
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			var matches []*htmlNode
+			if sel == nil {
+				matches = []*htmlNode{scope}
+			} else {
+				matches = querySelectorAll(scope, sel)
+			}
+			out := reflect.MakeSlice(fv.Type(), 0, len(matches))
+			for j, m := range matches {
+				ev := reflect.New(fv.Type().Elem()).Elem()
+				if err := d.decodeValue(m, ev, tag, fmt.Sprintf("%s[%d]", fieldPath, j)); err != nil {
+					return err
+				}
+				out = reflect.Append(out, ev)
+			}
+			fv.Set(out)
+			continue
+		}
+
+		m := scope
+		if sel != nil {
+			m = querySelector(scope, sel)
+		}
+		if m == nil {
+			if fv.Kind() == reflect.Bool {
+				fv.SetBool(false)
+			}
+			continue
+		}
+		if err := d.decodeValue(m, fv, tag, fieldPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeValue stores the value of element n in v.
+func (d *htmlDecoder) decodeValue(n *htmlNode, v reflect.Value, tag snapTag, path string) error {
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := d.decodeValue(n, p.Elem(), tag, path); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	s := d.text(n, tag)
+	switch {
+	case v.Type() == timeType:
+		if tag.attr == "" && !tag.html && !tag.tag && n.hasAttr("datetime") {
+			s = n.attr("datetime")
+		}
+	case isNestedStruct(v.Type()):
+		return d.decodeStruct(n, v, path)
+	case v.Kind() == reflect.Bool:
+		v.SetBool(true)
+		return nil
+	}
+	return d.setText(v, s, tag, path)
+}
+
+// isNestedStruct reports whether t is decoded field by field rather than
+// from a single value.
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && t != urlType && !reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// setText converts the text value s to v's type and stores it in v, which
+// is not a pointer, bool or nested struct. Empty values leave times, URLs
+// and numbers at their zero value.
+func (d *htmlDecoder) setText(v reflect.Value, s string, tag snapTag, path string) error {
+	t := v.Type()
+	if t == timeType {
+		if s == "" {
+			return nil
+		}
+		tm, err := parseLooseTime(s, tag.layout)
+		if err != nil {
+			return fmt.Errorf("snapapi: decode %s: %w", path, err)
+		}
+		v.Set(reflect.ValueOf(tm))
+		return nil
+	}
+	if t == urlType {
+		if s == "" {
+			return nil
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return fmt.Errorf("snapapi: decode %s: %w", path, err)
+		}
+		if d.base != nil {
+			u = d.base.ResolveReference(u)
+		}
+		v.Set(reflect.ValueOf(*u))
+		return nil
+	}
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("snapapi: decode %s: %w", path, err)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		switch tag.attr {
+		case "href", "src", "action", "poster":
+			s = d.resolve(s)
+		}
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			return nil
+		}
+		f, err := parseWholeNumber(s, path)
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(int64(f)) {
+			return fmt.Errorf("snapapi: decode %s: %s overflows %s", path, cleanNumber(s), t)
+		}
+		v.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			return nil
+		}
+		f, err := parseWholeNumber(s, path)
+		if err != nil {
+			return err
+		}
+		if f < 0 {
+			return fmt.Errorf("snapapi: decode %s: invalid number %q", path, s)
+		}
+		if v.OverflowUint(uint64(f)) {
+			return fmt.Errorf("snapapi: decode %s: %s overflows %s", path, cleanNumber(s), t)
+		}
+		v.SetUint(uint64(f))
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			return nil
+		}
+		f, err := parseNumber(s, path)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("snapapi: decode %s: unsupported field type %s", path, t)
+	}
+	return nil
+}
+
+// text returns the raw value of n selected by tag.
+func (d *htmlDecoder) text(n *htmlNode, tag snapTag) string {
+	switch {
+	case tag.attr != "":
+		return strings.TrimSpace(n.attr(tag.attr))
+	case tag.html:
+		return strings.TrimSpace(n.innerHTML())
+	case tag.tag:
+		return n.tag
+	}
+	return n.innerText()
+}
+
+func (d *htmlDecoder) resolve(s string) string {
+	if d.base == nil || s == "" {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	return d.base.ResolveReference(u).String()
+}
+
+// cleanNumber extracts the first number from a displayed value, dropping
+// currency symbols and thousands separators: "-$1,299.00 incl. VAT" is
+// "-1299.00".
+func cleanNumber(s string) string {
+	first := strings.IndexFunc(s, func(r rune) bool { return r >= '0' && r <= '9' })
+	if first < 0 {
+		return ""
+	}
+	var b strings.Builder
+	if strings.Contains(s[:first], "-") {
+		b.WriteByte('-')
+	}
+	for _, r := range s[first:] {
+		if r >= '0' && r <= '9' || r == '.' {
+			b.WriteRune(r)
+		} else if r != ',' {
+			break
+		}
+	}
+	return strings.TrimRight(b.String(), ".")
+}
+
+// parseNumber parses the first number in a displayed value (see
+// cleanNumber).
+func parseNumber(s, path string) (float64, error) {
+	n := cleanNumber(s)
+	f, err := strconv.ParseFloat(n, 64)
+	if err != nil || n == "" {
+		return 0, fmt.Errorf("snapapi: decode %s: invalid number %q", path, s)
+	}
+	return f, nil
+}
+
+// parseWholeNumber is parseNumber for integer fields: "1,024.00" is 1024,
+// but "4.99" is an error rather than being truncated.
+func parseWholeNumber(s, path string) (float64, error) {
+	f, err := parseNumber(s, path)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("snapapi: decode %s: %q is not a whole number", path, s)
+	}
+	return f, nil
+}
+
+var looseTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"01/02/2006",
+}
+
+// parseLooseTime parses s with layout, or with common date formats.
+func parseLooseTime(s, layout string) (time.Time, error) {
+	if layout != "" {
+		return time.Parse(layout, s)
+	}
+	for _, l := range looseTimeLayouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised time %q", s)
+}
@@ -0,0 +1,269 @@
+package snapapi
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultNextSelector finds the next page when neither NextSelector nor
+// URLPattern is set.
+const defaultNextSelector = `link[rel~=next], a[rel~=next]`
+
+// ScrapePagesParams describes a paginated scrape. The embedded ScrapeParams
+// are used for every page; only the URL changes from page to page.
+//
+// Pages are found in one of two ways:
+//
+//   - URLPattern: a URL containing "{page}", which is replaced by StartPage,
+//     StartPage+1, ... (for example "https://shop.example.com/search?q=tv&page={page}")
+//   - NextSelector: a CSS selector for the "next page" link on each page,
+//     starting at URL. Pages are then scraped as HTML so the link can be found.
+//
+// When neither is set, <link rel="next"> and <a rel="next"> are followed.
+type ScrapePagesParams struct {
+	ScrapeParams
+
+	// NextSelector is a CSS selector for the link to the next page; its
+	// href is resolved against the current page URL.
+	NextSelector string
+	// URLPattern is a page URL template containing "{page}". It replaces
+	// ScrapeParams.URL.
+	URLPattern string
+	// StartPage is the first page number substituted into URLPattern.
+	// Default: 1.
+	StartPage int
+	// ItemSelector is an optional CSS selector for the items listed on each
+	// page (e.g. "li.product"). A page on which it matches nothing is taken
+	// to be past the last page and ends iteration. Setting it scrapes pages
+	// as HTML so the items can be found.
+	ItemSelector string
+	// MaxPages caps the number of pages scraped. Default: 10.
+	MaxPages int
+	// Delay is the pause between page requests.
+	Delay time.Duration
+}
+
+// ScrapePageIterator yields the pages of a paginated scrape one at a time,
+// scraping each page when Next is called. Iteration stops after MaxPages,
+// when there is no next page, when a page repeats an earlier URL or the
+// previous page's content, when ItemSelector matches nothing, when a
+// URLPattern page after the first was served with HTTP 404 (as reported in
+// ScrapeResult.Status), or on the first error:
+//
+//	it := client.ScrapePages(ctx, snapapi.ScrapePagesParams{
+//	    ScrapeParams: snapapi.ScrapeParams{URL: "https://shop.example.com/tvs", Format: "html"},
+//	    NextSelector: "a.pagination-next",
+//	    MaxPages:     20,
+//	    Delay:        time.Second,
+//	})
+//	for it.Next() {
+//	    page := it.Page()
+//	    fmt.Println(page.Page, page.URL, len(page.Data))
+//	}
+//	if err := it.Err(); err != nil {
+//	    log.Fatal(err)
+//	}
+type ScrapePageIterator struct {
+	ctx   context.Context
+	c     *Client
+	p     ScrapePagesParams
+	next  cssSelector
+	items cssSelector
+
+	started bool
+	done    bool
+	nextURL string
+	count   int
+	seen    map[string]bool
+	prev    string
+	page    ScrapePage
+	err     error
+}
+
+// ScrapePages returns an iterator over the pages of a paginated listing.
+// Parameter errors are reported by Err after the first call to Next.
+func (c *Client) ScrapePages(ctx context.Context, p ScrapePagesParams) *ScrapePageIterator {
+	return &ScrapePageIterator{ctx: ctx, c: c, p: p, seen: make(map[string]bool)}
+}
+
+// ScrapeAllPages scrapes every page of a paginated listing and returns them in
+// order. On error, the pages scraped so far are returned with it.
+//
+//	pages, err := client.ScrapeAllPages(ctx, snapapi.ScrapePagesParams{
+//	    ScrapeParams: snapapi.ScrapeParams{Format: "text"},
+//	    URLPattern:   "https://example.com/blog/page/{page}",
+//	    MaxPages:     5,
+//	})
+func (c *Client) ScrapeAllPages(ctx context.Context, p ScrapePagesParams) ([]ScrapePage, error) {
+	it := c.ScrapePages(ctx, p)
+	var pages []ScrapePage
+	for it.Next() {
+		pages = append(pages, it.Page())
+	}
+	return pages, it.Err()
+}
+
+// Next scrapes the next page. It returns false when iteration is over; check
+// Err to tell completion from failure.
+func (it *ScrapePageIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	if !it.started {
+		it.started = true
+		if err := it.init(); err != nil {
+			return it.fail(err)
+		}
+	}
+	if it.count >= it.p.MaxPages || it.nextURL == "" {
+		it.done = true
+		return false
+	}
+
+	if it.count > 0 && it.p.Delay > 0 {
+		select {
+		case <-it.ctx.Done():
+			return it.fail(it.ctx.Err())
+		case <-time.After(it.p.Delay):
+		}
+	}
+
+	sp := it.p.ScrapeParams
+	sp.URL = it.nextURL
+	it.seen[it.nextURL] = true
+	res, err := it.c.Scrape(it.ctx, sp)
+	if err != nil {
+		return it.fail(err)
+	}
+	if it.p.URLPattern != "" && it.count > 0 && res.Status == http.StatusNotFound {
+		// Numbered past the last page.
+		it.done = true
+		return false
+	}
+	finalURL := res.URL
+	if finalURL == "" {
+		finalURL = sp.URL
+	}
+	if it.count > 0 && ((finalURL != sp.URL && it.seen[finalURL]) || res.Data == it.prev) {
+		// Redirected back to an earlier page, or past the last page.
+		it.done = true
+		return false
+	}
+	if it.items != nil && querySelector(parseHTML(res.Data), it.items) == nil {
+		// An empty listing: past the last page.
+		it.done = true
+		return false
+	}
+	it.seen[finalURL] = true
+	it.prev = res.Data
+	it.count++
+
+	number := it.count
+	if it.p.URLPattern != "" {
+		number = it.p.StartPage + it.count - 1
+	}
+	it.page = ScrapePage{Page: number, URL: finalURL, Data: res.Data, Status: res.Status}
+	it.nextURL = it.advance(finalURL, res.Data)
+	return true
+}
+
+// Page returns the page scraped by the last successful call to Next.
+func (it *ScrapePageIterator) Page() ScrapePage { return it.page }
+
+// Err returns the error that stopped iteration, if any.
+func (it *ScrapePageIterator) Err() error { return it.err }
+
+func (it *ScrapePageIterator) fail(err error) bool {
+	it.err = err
+	it.done = true
+	return false
+}
+
+// init validates the parameters, applies defaults and sets the first URL.
+func (it *ScrapePageIterator) init() error {
+	p := &it.p
+	if p.MaxPages <= 0 {
+		p.MaxPages = 10
+	}
+	if p.StartPage <= 0 {
+		p.StartPage = 1
+	}
+	if p.ItemSelector != "" {
+		items, err := compileCSS(p.ItemSelector)
+		if err != nil {
+			return &APIError{Code: ErrInvalidParams, Message: "invalid ItemSelector: " + err.Error(), StatusCode: 400}
+		}
+		it.items = items
+		p.Format = "html"
+	}
+	if p.URLPattern != "" {
+		if p.NextSelector != "" {
+			return &APIError{Code: ErrInvalidParams, Message: "set either NextSelector or URLPattern, not both", StatusCode: 400}
+		}
+		if !strings.Contains(p.URLPattern, "{page}") {
+			return &APIError{Code: ErrInvalidParams, Message: `URLPattern must contain "{page}"`, StatusCode: 400}
+		}
+		it.nextURL = pageURL(p.URLPattern, p.StartPage)
+		return nil
+	}
+	if p.URL == "" {
+		return &APIError{Code: ErrInvalidParams, Message: "URL or URLPattern is required", StatusCode: 400}
+	}
+	sel := p.NextSelector
+	if sel == "" {
+		sel = defaultNextSelector
+	}
+	next, err := compileCSS(sel)
+	if err != nil {
+		return &APIError{Code: ErrInvalidParams, Message: "invalid NextSelector: " + err.Error(), StatusCode: 400}
+	}
+	it.next = next
+	// The next link can only be found in the page's HTML.
+	p.Format = "html"
+	it.nextURL = p.URL
+	return nil
+}
+
+// advance returns the URL of the page after current, or "".
+func (it *ScrapePageIterator) advance(current, data string) string {
+	if it.p.URLPattern != "" {
+		return pageURL(it.p.URLPattern, it.p.StartPage+it.count)
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return ""
+	}
+	doc := parseHTML(data)
+	if b := doc.find("base"); b != nil && b.hasAttr("href") {
+		if href, err := url.Parse(strings.TrimSpace(b.attr("href"))); err == nil {
+			base = base.ResolveReference(href)
+		}
+	}
+	link := querySelector(doc, it.next)
+	if link == nil {
+		return ""
+	}
+	href := strings.TrimSpace(link.attr("href"))
+	if href == "" || strings.HasPrefix(href, "#") {
+		return ""
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	abs := base.ResolveReference(ref)
+	abs.Fragment = ""
+	if (abs.Scheme != "http" && abs.Scheme != "https") || it.seen[abs.String()] {
+		return ""
+	}
+	return abs.String()
+}
+
+// pageURL substitutes page into a URLPattern.
+func pageURL(pattern string, page int) string {
+	return strings.ReplaceAll(pattern, "{page}", strconv.Itoa(page))
+}
@@ -163,7 +163,7 @@ func (c *Client) Screenshot(ctx context.Context, p ScreenshotParams) ([]byte, er
 	if p.URL == "" {
 		return nil, &APIError{Code: ErrInvalidParams, Message: "URL is required", StatusCode: 400}
 	}
-	return c.doRaw(ctx, http.MethodPost, "/v1/screenshot", p)
+	return c.doBillable(ctx, "screenshot", http.MethodPost, "/v1/screenshot", p)
 }
 
 // ScreenshotToFile captures a screenshot and writes it directly to a file.
@@ -226,7 +226,7 @@ func (c *Client) ScreenshotToStorage(ctx context.Context, p ScreenshotToStorageP
 		return nil, &APIError{Code: ErrInvalidParams, Message: "URL is required", StatusCode: 400}
 	}
 	var result StorageCapture
-	if err := c.doJSON(ctx, http.MethodPost, "/v1/screenshot/storage", p, &result); err != nil {
+	if err := c.doBillableJSON(ctx, "screenshot", http.MethodPost, "/v1/screenshot/storage", p, &result); err != nil {
 		return nil, err
 	}
 	return &result, nil
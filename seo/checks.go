@@ -0,0 +1,340 @@
+package seo
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	snapapi "github.com/Sleywill/snapapi-go"
+)
+
+// pageDoc holds the parts of a page the checks look at.
+type pageDoc struct {
+	Headings []struct {
+		Tag  string `snap:",tag"`
+		Text string `snap:""`
+	} `snap:"h1, h2, h3, h4, h5, h6"`
+	Images      []string `snap:"img,attr=src"`
+	MissingAlt  []string `snap:"img:not([alt]),attr=src"`
+	Links       []string `snap:"a[href], area[href],attr=href"`
+	Active      []string `snap:"script[src], iframe[src], embed[src],attr=src"`
+	Stylesheets []string `snap:"link[rel~=stylesheet i][href],attr=href"`
+	Passive     []string `snap:"img[src], video[src], audio[src], source[src], track[src],attr=src"`
+	Viewport    bool     `snap:"meta[name=viewport i]"`
+}
+
+// hreflangRe matches a language code with optional script or region, such as
+// "en", "en-GB", "zh-Hant" or "es-419".
+var hreflangRe = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z]{4})?(-([a-zA-Z]{2}|[0-9]{3}))?$`)
+
+// analyzePage runs the checks that need only the page's HTML.
+func analyzePage(pageURL, html string, opts Options) (*PageReport, error) {
+	meta, err := snapapi.ParsePageMetadata(html, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	var doc pageDoc
+	if err := snapapi.DecodeHTML(html, pageURL, &doc); err != nil {
+		return nil, err
+	}
+	pr := &PageReport{
+		URL:         pageURL,
+		Title:       meta.Title,
+		Description: meta.Description,
+		Canonical:   meta.Canonical,
+		Images:      len(doc.Images),
+		meta:        meta,
+	}
+	for _, h := range doc.Headings {
+		level, _ := strconv.Atoi(strings.TrimPrefix(h.Tag, "h"))
+		pr.Headings = append(pr.Headings, Heading{Level: level, Text: h.Text})
+	}
+
+	checkLength(pr, CheckTitle, "title", meta.Title, opts.TitleMin, opts.TitleMax, SeverityError)
+	checkLength(pr, CheckDescription, "meta description", meta.Description, opts.DescriptionMin, opts.DescriptionMax, SeverityWarning)
+	checkHeadings(pr)
+	if len(doc.MissingAlt) > 0 {
+		pr.add(CheckImageAlt, SeverityWarning, fmt.Sprintf("%d of %d images have no alt attribute", len(doc.MissingAlt), len(doc.Images)), doc.MissingAlt...)
+	}
+	checkCanonical(pr, meta)
+	checkHreflang(pr, meta)
+	if meta.Robots.NoIndex {
+		pr.add(CheckRobots, SeverityWarning, "page is noindex and will not appear in search results")
+	}
+	if meta.Robots.NoFollow {
+		pr.add(CheckRobots, SeverityInfo, "page is nofollow; its links are not followed by search engines")
+	}
+	if !doc.Viewport {
+		pr.add(CheckViewport, SeverityWarning, "no <meta name=\"viewport\">; the page is not mobile friendly")
+	}
+	checkMixedContent(pr, doc)
+	checkOpenGraph(pr, meta)
+	pr.internal = internalLinks(pageURL, doc.Links)
+	pr.Links = len(pr.internal)
+	return pr, nil
+}
+
+func checkLength(pr *PageReport, check, name, value string, min, max int, missing Severity) {
+	n := utf8.RuneCountInString(value)
+	switch {
+	case n == 0:
+		pr.add(check, missing, fmt.Sprintf("%s is missing", name))
+	case n < min:
+		pr.add(check, SeverityWarning, fmt.Sprintf("%s is too short: %d characters (minimum %d)", name, n, min))
+	case n > max:
+		pr.add(check, SeverityWarning, fmt.Sprintf("%s is too long: %d characters (maximum %d)", name, n, max))
+	}
+}
+
+func checkHeadings(pr *PageReport) {
+	var h1, empty, skipped []string
+	prev := 0
+	for _, h := range pr.Headings {
+		if h.Level == 1 {
+			h1 = append(h1, h.Text)
+		}
+		if h.Text == "" {
+			empty = append(empty, fmt.Sprintf("h%d", h.Level))
+		}
+		if prev > 0 && h.Level > prev+1 {
+			skipped = append(skipped, fmt.Sprintf("h%d -> h%d %q", prev, h.Level, h.Text))
+		}
+		prev = h.Level
+	}
+	switch {
+	case len(h1) == 0:
+		pr.add(CheckHeadings, SeverityError, "page has no h1")
+	case len(h1) > 1:
+		pr.add(CheckHeadings, SeverityWarning, fmt.Sprintf("page has %d h1 headings", len(h1)), h1...)
+	}
+	if len(skipped) > 0 {
+		pr.add(CheckHeadings, SeverityWarning, "heading levels are skipped", skipped...)
+	}
+	if len(empty) > 0 {
+		pr.add(CheckHeadings, SeverityWarning, fmt.Sprintf("%d headings are empty", len(empty)), empty...)
+	}
+}
+
+func checkCanonical(pr *PageReport, meta *snapapi.PageMetadata) {
+	if meta.Canonical == "" {
+		pr.add(CheckCanonical, SeverityInfo, "no canonical URL")
+		return
+	}
+	c, err := url.Parse(meta.Canonical)
+	if err != nil || !c.IsAbs() {
+		pr.add(CheckCanonical, SeverityError, fmt.Sprintf("canonical URL %q is invalid", meta.Canonical))
+		return
+	}
+	p, _ := url.Parse(pr.URL)
+	switch {
+	case p != nil && !strings.EqualFold(c.Host, p.Host):
+		pr.add(CheckCanonical, SeverityWarning, fmt.Sprintf("canonical URL points to another host: %s", meta.Canonical))
+	case sameURL(meta.Canonical, pr.URL):
+	default:
+		pr.add(CheckCanonical, SeverityInfo, fmt.Sprintf("page is canonicalised to %s", meta.Canonical))
+	}
+}
+
+func checkHreflang(pr *PageReport, meta *snapapi.PageMetadata) {
+	if len(meta.Alternates) == 0 {
+		return
+	}
+	var invalid, dup []string
+	seen := make(map[string]bool)
+	self, xDefault := false, false
+	for _, a := range meta.Alternates {
+		lang := strings.ToLower(a.Lang)
+		if lang == "x-default" {
+			xDefault = true
+		} else if !hreflangRe.MatchString(a.Lang) {
+			invalid = append(invalid, a.Lang)
+		}
+		if seen[lang] {
+			dup = append(dup, a.Lang)
+		}
+		seen[lang] = true
+		if sameURL(a.URL, pr.URL) || (meta.Canonical != "" && sameURL(a.URL, meta.Canonical)) {
+			self = true
+		}
+	}
+	if len(invalid) > 0 {
+		pr.add(CheckHreflang, SeverityWarning, "invalid hreflang codes", invalid...)
+	}
+	if len(dup) > 0 {
+		pr.add(CheckHreflang, SeverityWarning, "hreflang codes are listed more than once", dup...)
+	}
+	if !self {
+		pr.add(CheckHreflang, SeverityWarning, "hreflang alternates do not include the page itself")
+	}
+	if !xDefault {
+		pr.add(CheckHreflang, SeverityInfo, "no x-default hreflang alternate")
+	}
+}
+
+func checkMixedContent(pr *PageReport, doc pageDoc) {
+	if !strings.HasPrefix(pr.URL, "https:") {
+		return
+	}
+	insecure := func(urls []string) []string {
+		var out []string
+		for _, u := range urls {
+			if strings.HasPrefix(strings.ToLower(u), "http:") && !containsString(out, u) {
+				out = append(out, u)
+			}
+		}
+		return out
+	}
+	if active := insecure(append(append([]string(nil), doc.Active...), doc.Stylesheets...)); len(active) > 0 {
+		pr.add(CheckMixedContent, SeverityError, "scripts, styles or frames are loaded over HTTP and will be blocked", active...)
+	}
+	if passive := insecure(doc.Passive); len(passive) > 0 {
+		pr.add(CheckMixedContent, SeverityWarning, "media is loaded over HTTP", passive...)
+	}
+}
+
+func checkOpenGraph(pr *PageReport, meta *snapapi.PageMetadata) {
+	var missing []string
+	if meta.OpenGraph.Title == "" {
+		missing = append(missing, "og:title")
+	}
+	if meta.OpenGraph.Description == "" {
+		missing = append(missing, "og:description")
+	}
+	if len(meta.OpenGraph.Images) == 0 || meta.OpenGraph.Images[0].URL == "" {
+		missing = append(missing, "og:image")
+	} else {
+		img := meta.OpenGraph.Images[0]
+		pr.OGImage = &ImageInfo{URL: img.URL, Width: img.Width, Height: img.Height}
+	}
+	if len(missing) > 0 {
+		pr.add(CheckOpenGraph, SeverityWarning, "OpenGraph tags are missing", missing...)
+	}
+	if meta.Twitter.Card == "" {
+		pr.add(CheckOpenGraph, SeverityInfo, "no twitter:card; link previews on X/Twitter fall back to OpenGraph")
+	}
+}
+
+// checkOGImageSize reports an og:image below the sizes social networks need.
+func checkOGImageSize(pr *PageReport) {
+	img := pr.OGImage
+	switch {
+	case img.Width == 0 || img.Height == 0:
+	case img.Width < 200 || img.Height < 200:
+		pr.add(CheckOpenGraph, SeverityError, fmt.Sprintf("og:image is %dx%d; at least 200x200 is required", img.Width, img.Height), img.URL)
+	case img.Width < 600 || img.Height < 315:
+		pr.add(CheckOpenGraph, SeverityWarning, fmt.Sprintf("og:image is %dx%d; 1200x630 is recommended", img.Width, img.Height), img.URL)
+	}
+}
+
+// siteChecks runs the checks that compare pages.
+func siteChecks(pages []PageReport) []Issue {
+	var issues []Issue
+	duplicates := func(check, name string, value func(*PageReport) string) {
+		byValue := make(map[string][]string)
+		for i := range pages {
+			if v := value(&pages[i]); v != "" {
+				byValue[v] = append(byValue[v], pages[i].URL)
+			}
+		}
+		keys := make([]string, 0, len(byValue))
+		for k := range byValue {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if urls := byValue[k]; len(urls) > 1 {
+				issues = append(issues, Issue{Check: check, Severity: SeverityWarning, URL: urls[0],
+					Message: fmt.Sprintf("%d pages share the %s %q", len(urls), name, k), Details: urls})
+			}
+		}
+	}
+	duplicates(CheckDuplicateTitle, "title", func(p *PageReport) string { return p.Title })
+	duplicates(CheckDuplicateDescription, "meta description", func(p *PageReport) string { return p.Description })
+
+	// hreflang alternates must link back to each other.
+	byURL := make(map[string]*PageReport)
+	for i := range pages {
+		byURL[normalize(pages[i].URL)] = &pages[i]
+		if pages[i].Canonical != "" {
+			byURL[normalize(pages[i].Canonical)] = &pages[i]
+		}
+	}
+	for i := range pages {
+		p := &pages[i]
+		var missing []string
+		for _, a := range p.meta.Alternates {
+			other, ok := byURL[normalize(a.URL)]
+			if !ok || other == p {
+				continue
+			}
+			back := false
+			for _, b := range other.meta.Alternates {
+				if sameURL(b.URL, p.URL) || (p.Canonical != "" && sameURL(b.URL, p.Canonical)) {
+					back = true
+					break
+				}
+			}
+			if !back {
+				missing = append(missing, a.URL)
+			}
+		}
+		if len(missing) > 0 {
+			p.add(CheckHreflang, SeverityWarning, "hreflang alternates do not link back to this page", missing...)
+		}
+	}
+	return issues
+}
+
+// internalLinks returns the distinct http(s) links to pageURL's host, without
+// fragments and excluding the page itself.
+func internalLinks(pageURL string, links []string) []string {
+	p, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	self := normalize(pageURL)
+	var out []string
+	seen := make(map[string]bool)
+	for _, l := range links {
+		u, err := url.Parse(l)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !strings.EqualFold(u.Host, p.Host) {
+			continue
+		}
+		s := normalize(l)
+		if s == self || seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out
+}
+
+// normalize returns u without its fragment.
+func normalize(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	parsed.Fragment = ""
+	if parsed.Path == "" {
+		parsed.Path = "/"
+	}
+	parsed.Host = strings.ToLower(parsed.Host)
+	return parsed.String()
+}
+
+func sameURL(a, b string) bool { return normalize(a) == normalize(b) }
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package seo
+
+import (
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoders for og:image dimensions
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	snapapi "github.com/Sleywill/snapapi-go"
+)
+
+// checkLinks checks the status of the distinct internal links of pages,
+// adding a CheckBrokenLinks issue to each page that links to a broken URL.
+func checkLinks(ctx context.Context, c *snapapi.Client, pages []PageReport, opts Options) []LinkStatus {
+	byURL := make(map[string]*LinkStatus)
+	var order []string
+	for _, p := range pages {
+		for _, l := range p.internal {
+			st, ok := byURL[l]
+			if !ok {
+				if len(order) >= opts.MaxLinks {
+					continue
+				}
+				st = &LinkStatus{URL: l}
+				byURL[l] = st
+				order = append(order, l)
+			}
+			st.Pages = append(st.Pages, p.URL)
+		}
+	}
+
+	checks := c.CheckLinks(ctx, order, snapapi.LinkCheckOptions{
+		Concurrency: opts.Concurrency,
+		HTTPClient:  opts.HTTPClient,
+		UserAgent:   opts.UserAgent,
+	})
+	for _, r := range checks {
+		st := byURL[r.URL]
+		st.Status = r.Status
+		if r.Err != nil {
+			st.Status, st.Error = 0, r.Err.Error()
+		}
+	}
+
+	links := make([]LinkStatus, 0, len(order))
+	for _, u := range order {
+		links = append(links, *byURL[u])
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].URL < links[j].URL })
+
+	for i := range pages {
+		var broken []string
+		for _, l := range pages[i].internal {
+			st, ok := byURL[l]
+			if !ok || !st.Broken() {
+				continue
+			}
+			if st.Error != "" {
+				broken = append(broken, fmt.Sprintf("%s (%s)", st.URL, st.Error))
+			} else {
+				broken = append(broken, fmt.Sprintf("%s (%d)", st.URL, st.Status))
+			}
+		}
+		if len(broken) > 0 {
+			pages[i].add(CheckBrokenLinks, SeverityError, fmt.Sprintf("%d broken internal links", len(broken)), broken...)
+		}
+	}
+	return links
+}
+
+// request sends a request and passes the response body to read, if set.
+func request(ctx context.Context, opts Options, method, u string, read func(io.Reader)) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", opts.UserAgent)
+	resp, err := opts.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if read != nil && resp.StatusCode < 400 {
+		read(resp.Body)
+	}
+	return resp.StatusCode, nil
+}
+
+// checkOGImages downloads each page's og:image to check that it loads and to
+// measure it when the page does not declare its size.
+func checkOGImages(ctx context.Context, pages []PageReport, opts Options) {
+	parallel(len(pages), opts.Concurrency, func(i int) {
+		pr := &pages[i]
+		if pr.OGImage == nil {
+			return
+		}
+		img := pr.OGImage
+		status, err := request(ctx, opts, http.MethodGet, img.URL, func(r io.Reader) {
+			if cfg, _, err := image.DecodeConfig(r); err == nil {
+				img.Width, img.Height = cfg.Width, cfg.Height
+			}
+		})
+		img.Status = status
+		switch {
+		case err != nil:
+			pr.add(CheckOpenGraph, SeverityError, fmt.Sprintf("og:image could not be loaded: %v", err), img.URL)
+		case status >= 400:
+			pr.add(CheckOpenGraph, SeverityError, fmt.Sprintf("og:image returned status %d", status), img.URL)
+		default:
+			checkOGImageSize(pr)
+		}
+	})
+}
+
+// parallel calls fn(0) ... fn(n-1) on up to limit goroutines.
+func parallel(n, limit int, fn func(i int)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, limit)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,79 @@
+package seo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"io"
+)
+
+// WriteJSON writes the report as indented JSON. Screenshot data is omitted.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteHTML writes the report as a self-contained HTML page, with
+// screenshots embedded as images.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, r)
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"count": func(r *Report, s string) int { return r.Count(Severity(s)) },
+	"dataURL": func(b []byte) template.URL {
+		return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(b))
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>SEO report</title>
+<style>
+body { font: 14px/1.5 system-ui, sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0; }
+table { border-collapse: collapse; width: 100%; margin: 1em 0; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #ddd; vertical-align: top; }
+.error { color: #b00020; font-weight: bold; }
+.warning { color: #a15c00; font-weight: bold; }
+.info { color: #1a5fb4; }
+.summary span { margin-right: 1.5em; }
+.page { border-top: 2px solid #444; margin-top: 2em; }
+.shots img { max-height: 320px; margin-right: 1em; border: 1px solid #ccc; }
+ul.details { margin: 0; padding-left: 1.2em; color: #555; }
+</style>
+</head>
+<body>
+<h1>SEO report</h1>
+<p>Generated {{.Generated.Format "2006-01-02 15:04 MST"}} &middot; {{len .Pages}} pages</p>
+<p class="summary"><span class="error">{{count . "error"}} errors</span><span class="warning">{{count . "warning"}} warnings</span><span class="info">{{count . "info"}} notices</span></p>
+{{if .Issues}}
+<h2>Site-wide issues</h2>
+{{template "issues" .Issues}}
+{{end}}
+{{range .Pages}}
+<section class="page">
+<h2><a href="{{.URL}}">{{.URL}}</a></h2>
+<table>
+<tr><th>Title</th><td>{{.Title}}</td></tr>
+<tr><th>Description</th><td>{{.Description}}</td></tr>
+<tr><th>Canonical</th><td>{{.Canonical}}</td></tr>
+<tr><th>Words</th><td>{{.WordCount}}</td></tr>
+<tr><th>Images</th><td>{{.Images}}</td></tr>
+<tr><th>Internal links</th><td>{{.Links}}</td></tr>
+{{with .OGImage}}<tr><th>og:image</th><td><a href="{{.URL}}">{{.URL}}</a>{{if .Width}} ({{.Width}}&times;{{.Height}}){{end}}</td></tr>{{end}}
+{{if .Headings}}<tr><th>Headings</th><td>{{range .Headings}}<div style="margin-left: {{.Level}}em">h{{.Level}} {{.Text}}</div>{{end}}</td></tr>{{end}}
+</table>
+{{if .Issues}}{{template "issues" .Issues}}{{else}}<p>No issues.</p>{{end}}
+{{if .Screenshots}}<div class="shots">{{range .Screenshots}}<figure style="display:inline-block"><img src="{{dataURL .Data}}" alt="{{.Device}} screenshot"><figcaption>{{.Device}} ({{.Width}}&times;{{.Height}})</figcaption></figure>{{end}}</div>{{end}}
+</section>
+{{end}}
+</body>
+</html>
+{{define "issues"}}<table>
+<tr><th>Severity</th><th>Check</th><th>Issue</th></tr>
+{{range .}}<tr><td class="{{.Severity}}">{{.Severity}}</td><td>{{.Check}}</td><td>{{.Message}}{{if .Details}}<ul class="details">{{range .Details}}<li>{{.}}</li>{{end}}</ul>{{end}}</td></tr>
+{{end}}</table>{{end}}
+`))
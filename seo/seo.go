@@ -0,0 +1,390 @@
+// Package seo audits web pages for common search engine optimisation
+// problems. Pages are rendered with SnapAPI's Scrape endpoint (or found with
+// Crawl), word counts come from Extract and, optionally, desktop and mobile
+// screenshots are attached to the report.
+//
+//	client := snapapi.New(os.Getenv("SNAPAPI_KEY"))
+//	report, err := seo.Audit(ctx, client, "https://example.com", seo.Options{Screenshots: true})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(report.Count(seo.SeverityError), "errors")
+//	f, _ := os.Create("seo.html")
+//	defer f.Close()
+//	report.WriteHTML(f)
+package seo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	snapapi "github.com/Sleywill/snapapi-go"
+)
+
+// Severity ranks an Issue.
+type Severity string
+
+// Severity levels, from most to least serious.
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+func (s Severity) rank() int {
+	switch s {
+	case SeverityError:
+		return 0
+	case SeverityWarning:
+		return 1
+	}
+	return 2
+}
+
+// Check names, used in Issue.Check.
+const (
+	CheckFetch                = "fetch"
+	CheckTitle                = "title"
+	CheckDescription          = "description"
+	CheckHeadings             = "headings"
+	CheckImageAlt             = "image-alt"
+	CheckCanonical            = "canonical"
+	CheckHreflang             = "hreflang"
+	CheckRobots               = "robots"
+	CheckViewport             = "viewport"
+	CheckMixedContent         = "mixed-content"
+	CheckBrokenLinks          = "broken-links"
+	CheckWordCount            = "word-count"
+	CheckOpenGraph            = "open-graph"
+	CheckScreenshot           = "screenshot"
+	CheckDuplicateTitle       = "duplicate-title"
+	CheckDuplicateDescription = "duplicate-description"
+)
+
+// Issue is a single finding.
+type Issue struct {
+	// Check is the name of the check that raised the issue (see the Check
+	// constants).
+	Check    string   `json:"check"`
+	Severity Severity `json:"severity"`
+	// URL is the page the issue was found on.
+	URL     string `json:"url"`
+	Message string `json:"message"`
+	// Details lists the offending items, such as image or link URLs.
+	Details []string `json:"details,omitempty"`
+}
+
+// Heading is an h1-h6 element of a page, in document order.
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+}
+
+// LinkStatus is the result of checking one link.
+type LinkStatus struct {
+	URL string `json:"url"`
+	// Status is the HTTP status code, or 0 if the request failed.
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Pages are the audited pages that link to URL.
+	Pages []string `json:"pages"`
+}
+
+// Broken reports whether the link failed or returned a 4xx/5xx status.
+func (l LinkStatus) Broken() bool { return l.Status == 0 || l.Status >= 400 }
+
+// ImageInfo describes a page's og:image.
+type ImageInfo struct {
+	URL    string `json:"url"`
+	Status int    `json:"status,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// Screenshot is a capture of the page. Data is not included in JSON reports.
+type Screenshot struct {
+	// Device is "desktop" or "mobile".
+	Device string `json:"device"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Data   []byte `json:"-"`
+}
+
+// PageReport is the audit result for one page.
+type PageReport struct {
+	URL         string       `json:"url"`
+	Title       string       `json:"title"`
+	Description string       `json:"description,omitempty"`
+	Canonical   string       `json:"canonical,omitempty"`
+	Headings    []Heading    `json:"headings,omitempty"`
+	WordCount   int          `json:"wordCount"`
+	Images      int          `json:"images"`
+	Links       int          `json:"internalLinks"`
+	OGImage     *ImageInfo   `json:"ogImage,omitempty"`
+	Screenshots []Screenshot `json:"screenshots,omitempty"`
+	Issues      []Issue      `json:"issues,omitempty"`
+	meta        *snapapi.PageMetadata
+	internal    []string
+}
+
+// Report is the result of an audit.
+type Report struct {
+	Generated time.Time    `json:"generated"`
+	Pages     []PageReport `json:"pages"`
+	// Issues are site-wide findings, such as duplicate titles.
+	Issues []Issue `json:"issues,omitempty"`
+	// Links holds every internal link that was checked.
+	Links []LinkStatus `json:"links,omitempty"`
+}
+
+// AllIssues returns the issues of every page and the site-wide issues,
+// most severe first.
+func (r *Report) AllIssues() []Issue {
+	var all []Issue
+	for _, p := range r.Pages {
+		all = append(all, p.Issues...)
+	}
+	all = append(all, r.Issues...)
+	sortIssues(all)
+	return all
+}
+
+// Count returns the number of issues with severity s.
+func (r *Report) Count(s Severity) int {
+	n := 0
+	for _, i := range r.AllIssues() {
+		if i.Severity == s {
+			n++
+		}
+	}
+	return n
+}
+
+// BrokenLinks returns the checked links that are broken.
+func (r *Report) BrokenLinks() []LinkStatus {
+	var out []LinkStatus
+	for _, l := range r.Links {
+		if l.Broken() {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// Options configures an audit. The zero value uses the defaults below.
+type Options struct {
+	// TitleMin and TitleMax bound the title length in characters.
+	// Defaults: 30 and 60.
+	TitleMin, TitleMax int
+	// DescriptionMin and DescriptionMax bound the meta description length
+	// in characters. Defaults: 70 and 160.
+	DescriptionMin, DescriptionMax int
+	// MinWords is the word count below which a page is reported as thin
+	// content. Default: 300.
+	MinWords int
+	// SkipExtract skips the Extract call used for word counts.
+	SkipExtract bool
+	// SkipLinks skips checking the status of internal links.
+	SkipLinks bool
+	// SkipOGImage skips downloading og:image to check its dimensions.
+	SkipOGImage bool
+	// Screenshots attaches desktop and mobile screenshots to each page.
+	// This costs two Screenshot calls per page.
+	Screenshots bool
+	// MaxLinks caps the number of distinct internal links checked.
+	// Default: 500.
+	MaxLinks int
+	// Concurrency is the number of parallel link and image checks.
+	// Default: 8.
+	Concurrency int
+	// HTTPClient is used for link and image checks. Default: a client with
+	// a 15s timeout.
+	HTTPClient *http.Client
+	// UserAgent is sent with link and image checks. Default: "snapapi-seo".
+	UserAgent string
+}
+
+func (o *Options) defaults() {
+	if o.TitleMin <= 0 {
+		o.TitleMin = 30
+	}
+	if o.TitleMax <= 0 {
+		o.TitleMax = 60
+	}
+	if o.DescriptionMin <= 0 {
+		o.DescriptionMin = 70
+	}
+	if o.DescriptionMax <= 0 {
+		o.DescriptionMax = 160
+	}
+	if o.MinWords <= 0 {
+		o.MinWords = 300
+	}
+	if o.MaxLinks <= 0 {
+		o.MaxLinks = 500
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 8
+	}
+	if o.HTTPClient == nil {
+		o.HTTPClient = &http.Client{Timeout: 15 * time.Second}
+	}
+	if o.UserAgent == "" {
+		o.UserAgent = "snapapi-seo"
+	}
+}
+
+// Audit scrapes pageURL and audits it.
+func Audit(ctx context.Context, c *snapapi.Client, pageURL string, opts Options) (*Report, error) {
+	res, err := c.Scrape(ctx, snapapi.ScrapeParams{URL: pageURL, Format: "html"})
+	if err != nil {
+		return nil, err
+	}
+	final := pageURL
+	if res.URL != "" {
+		final = res.URL
+	}
+	return run(ctx, c, []page{{url: final, html: res.Data}}, nil, opts)
+}
+
+// AuditCrawl crawls a site from seed (see snapapi.Client.Crawl) and audits
+// every page it finds. Besides the per-page checks it reports duplicate
+// titles and descriptions and hreflang alternates without return links.
+// Pages that could not be fetched are reported as CheckFetch errors.
+func AuditCrawl(ctx context.Context, c *snapapi.Client, seed string, crawl snapapi.CrawlOptions, opts Options) (*Report, error) {
+	var (
+		mu    sync.Mutex
+		pages []page
+	)
+	res, err := c.Crawl(ctx, seed, crawl, func(ctx context.Context, p *snapapi.CrawlPage) error {
+		mu.Lock()
+		pages = append(pages, page{url: p.URL, html: p.HTML})
+		mu.Unlock()
+		return nil
+	})
+	if err != nil && len(pages) == 0 {
+		return nil, err
+	}
+	var failed []Issue
+	if res != nil {
+		for u, ferr := range res.Failed {
+			failed = append(failed, Issue{Check: CheckFetch, Severity: SeverityError, URL: u, Message: fmt.Sprintf("page could not be fetched: %v", ferr)})
+		}
+	}
+	report, rerr := run(ctx, c, pages, failed, opts)
+	if rerr != nil {
+		return nil, rerr
+	}
+	return report, err
+}
+
+type page struct {
+	url, html string
+}
+
+func run(ctx context.Context, c *snapapi.Client, pages []page, siteIssues []Issue, opts Options) (*Report, error) {
+	opts.defaults()
+	report := &Report{Generated: time.Now().UTC(), Issues: siteIssues}
+	for _, p := range pages {
+		pr, err := analyzePage(p.url, p.html, opts)
+		if err != nil {
+			return nil, err
+		}
+		report.Pages = append(report.Pages, *pr)
+	}
+	sort.Slice(report.Pages, func(i, j int) bool { return report.Pages[i].URL < report.Pages[j].URL })
+
+	for i := range report.Pages {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pr := &report.Pages[i]
+		if !opts.SkipExtract {
+			wordCount(ctx, c, pr, opts)
+		}
+		if opts.Screenshots {
+			screenshots(ctx, c, pr)
+		}
+	}
+	if opts.SkipOGImage {
+		// Only the declared og:image:width and og:image:height are known.
+		for i := range report.Pages {
+			if report.Pages[i].OGImage != nil {
+				checkOGImageSize(&report.Pages[i])
+			}
+		}
+	} else {
+		checkOGImages(ctx, report.Pages, opts)
+	}
+	if !opts.SkipLinks {
+		report.Links = checkLinks(ctx, c, report.Pages, opts)
+	}
+	report.Issues = append(report.Issues, siteChecks(report.Pages)...)
+
+	for i := range report.Pages {
+		sortIssues(report.Pages[i].Issues)
+	}
+	sortIssues(report.Issues)
+	return report, ctx.Err()
+}
+
+func sortIssues(issues []Issue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Severity.rank() != b.Severity.rank() {
+			return a.Severity.rank() < b.Severity.rank()
+		}
+		if a.URL != b.URL {
+			return a.URL < b.URL
+		}
+		return a.Check < b.Check
+	})
+}
+
+// wordCount sets the page's word count from Extract.
+func wordCount(ctx context.Context, c *snapapi.Client, pr *PageReport, opts Options) {
+	res, err := c.Extract(ctx, snapapi.ExtractParams{URL: pr.URL, Format: "text"})
+	if err != nil {
+		pr.add(CheckWordCount, SeverityInfo, fmt.Sprintf("word count unavailable: %v", err))
+		return
+	}
+	pr.WordCount = res.WordCount
+	if pr.WordCount < opts.MinWords {
+		pr.add(CheckWordCount, SeverityWarning, fmt.Sprintf("thin content: %d words (minimum %d)", pr.WordCount, opts.MinWords))
+	}
+}
+
+// screenshotDevices are the viewports captured when Options.Screenshots is set.
+var screenshotDevices = []struct {
+	name          string
+	width, height int
+	mobile        bool
+}{
+	{"desktop", 1280, 800, false},
+	{"mobile", 390, 844, true},
+}
+
+func screenshots(ctx context.Context, c *snapapi.Client, pr *PageReport) {
+	for _, d := range screenshotDevices {
+		data, err := c.Screenshot(ctx, snapapi.ScreenshotParams{
+			URL:      pr.URL,
+			Format:   "png",
+			Width:    d.width,
+			Height:   d.height,
+			IsMobile: d.mobile,
+		})
+		if err != nil {
+			pr.add(CheckScreenshot, SeverityInfo, fmt.Sprintf("%s screenshot failed: %v", d.name, err))
+			continue
+		}
+		pr.Screenshots = append(pr.Screenshots, Screenshot{Device: d.name, Width: d.width, Height: d.height, Data: data})
+	}
+}
+
+func (pr *PageReport) add(check string, sev Severity, msg string, details ...string) {
+	pr.Issues = append(pr.Issues, Issue{Check: check, Severity: sev, URL: pr.URL, Message: msg, Details: details})
+}
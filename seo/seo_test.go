@@ -0,0 +1,262 @@
+package seo_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	snapapi "github.com/Sleywill/snapapi-go"
+	"github.com/Sleywill/snapapi-go/seo"
+)
+
+// site serves the audited pages (for link and og:image checks) and a fake
+// SnapAPI that renders them.
+type site struct {
+	web, api *httptest.Server
+	pages    map[string]string // path -> HTML
+	words    int
+}
+
+func newSite(t *testing.T, pages map[string]string) *site {
+	t.Helper()
+	s := &site{pages: pages, words: 500}
+	s.web = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/og.png":
+			img := image.NewRGBA(image.Rect(0, 0, 400, 210))
+			w.Header().Set("Content-Type", "image/png")
+			_ = png.Encode(w, img)
+		case r.URL.Path == "/head-not-allowed":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case s.pages[r.URL.Path] != "":
+			_, _ = w.Write([]byte(s.pages[r.URL.Path]))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.web.Close)
+	s.api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		u, _ := body["url"].(string)
+		path := strings.TrimPrefix(u, s.web.URL)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/scrape":
+			html, ok := s.pages[path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error":"NOT_FOUND","message":"not found"}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"results": []map[string]interface{}{{"page": 1, "url": u, "data": html}},
+			})
+		case "/v1/extract":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true, "url": u, "data": strings.Repeat("word ", s.words), "type": "text",
+			})
+		case "/v1/screenshot":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("\x89PNG-" + fmt.Sprint(body["width"])))
+		}
+	}))
+	t.Cleanup(s.api.Close)
+	return s
+}
+
+func (s *site) client() *snapapi.Client {
+	return snapapi.New("test-key", snapapi.WithBaseURL(s.api.URL), snapapi.WithRetries(0))
+}
+
+func issuesFor(r *seo.Report, check string) []seo.Issue {
+	var out []seo.Issue
+	for _, i := range r.AllIssues() {
+		if i.Check == check {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+const goodPage = `<!doctype html><html lang="en"><head>
+<title>A well sized title for the home page</title>
+<meta name="description" content="A meta description that is long enough to be useful to searchers, and short.">
+<meta name="viewport" content="width=device-width">
+<link rel="canonical" href="/">
+<meta property="og:title" content="Home"><meta property="og:description" content="Home page">
+<meta property="og:image" content="/og.png">
+<meta name="twitter:card" content="summary">
+</head><body>
+<h1>Home</h1><h2>Section</h2><h3>Sub</h3>
+<img src="/a.png" alt="A"><img src="/b.png" alt="">
+<a href="/about">About</a> <a href="/missing">Gone</a> <a href="/head-not-allowed">HEAD</a>
+<a href="#top">Top</a> <a href="https://other.example/">Elsewhere</a> <a href="mailto:x@example.com">Mail</a>
+</body></html>`
+
+const badPage = `<html><head><title>Short</title>
+<meta name="robots" content="noindex">
+<link rel="canonical" href="https://other.example/about">
+<script src="http://cdn.example/app.js"></script>
+</head><body>
+<h2>No h1</h2><h4>Skipped</h4><h3> </h3>
+<img src="http://cdn.example/photo.jpg">
+</body></html>`
+
+func TestAudit(t *testing.T) {
+	s := newSite(t, map[string]string{"/": goodPage, "/about": badPage})
+	report, err := seo.Audit(context.Background(), s.client(), s.web.URL+"/", seo.Options{Screenshots: true, HTTPClient: s.web.Client()})
+	if err != nil {
+		t.Fatalf("Audit() error: %v", err)
+	}
+	if len(report.Pages) != 1 {
+		t.Fatalf("Pages = %d", len(report.Pages))
+	}
+	p := report.Pages[0]
+	if p.WordCount != 500 || p.Images != 2 || p.Links != 3 || len(p.Headings) != 3 || p.Headings[2] != (seo.Heading{Level: 3, Text: "Sub"}) {
+		t.Errorf("page = %+v", p)
+	}
+	if p.OGImage == nil || p.OGImage.Width != 400 || p.OGImage.Height != 210 || p.OGImage.Status != 200 {
+		t.Errorf("OGImage = %+v", p.OGImage)
+	}
+	if len(p.Screenshots) != 2 || p.Screenshots[1].Device != "mobile" || string(p.Screenshots[1].Data) != "\x89PNG-390" {
+		t.Errorf("Screenshots = %+v", p.Screenshots)
+	}
+
+	broken := report.BrokenLinks()
+	if len(broken) != 1 || broken[0].Status != 404 || !strings.HasSuffix(broken[0].URL, "/missing") {
+		t.Errorf("BrokenLinks() = %+v (links %+v)", broken, report.Links)
+	}
+	if issues := issuesFor(report, seo.CheckBrokenLinks); len(issues) != 1 || issues[0].Severity != seo.SeverityError {
+		t.Errorf("broken-links issues = %+v", issues)
+	}
+	if issues := issuesFor(report, seo.CheckOpenGraph); len(issues) != 1 || issues[0].Severity != seo.SeverityWarning ||
+		!strings.Contains(issues[0].Message, "400x210") {
+		t.Errorf("open-graph issues = %+v", issues)
+	}
+	for _, check := range []string{seo.CheckTitle, seo.CheckDescription, seo.CheckHeadings, seo.CheckImageAlt,
+		seo.CheckCanonical, seo.CheckViewport, seo.CheckMixedContent, seo.CheckWordCount} {
+		if issues := issuesFor(report, check); len(issues) > 0 {
+			t.Errorf("unexpected %s issues: %+v", check, issues)
+		}
+	}
+}
+
+func TestAudit_BadPage(t *testing.T) {
+	s := newSite(t, map[string]string{"/about": badPage})
+	s.words = 40
+	report, err := seo.Audit(context.Background(), s.client(), s.web.URL+"/about", seo.Options{SkipLinks: true, HTTPClient: s.web.Client()})
+	if err != nil {
+		t.Fatalf("Audit() error: %v", err)
+	}
+	want := map[string]seo.Severity{
+		seo.CheckTitle:        seo.SeverityWarning,
+		seo.CheckDescription:  seo.SeverityWarning,
+		seo.CheckHeadings:     seo.SeverityError,
+		seo.CheckImageAlt:     seo.SeverityWarning,
+		seo.CheckCanonical:    seo.SeverityWarning,
+		seo.CheckRobots:       seo.SeverityWarning,
+		seo.CheckViewport:     seo.SeverityWarning,
+		seo.CheckMixedContent: seo.SeverityError,
+		seo.CheckWordCount:    seo.SeverityWarning,
+		seo.CheckOpenGraph:    seo.SeverityWarning,
+	}
+	for check, sev := range want {
+		issues := issuesFor(report, check)
+		if len(issues) == 0 || issues[0].Severity != sev {
+			t.Errorf("%s: issues = %+v, want first severity %s", check, issues, sev)
+		}
+	}
+	mixed := issuesFor(report, seo.CheckMixedContent)
+	if len(mixed) != 2 || mixed[0].Details[0] != "http://cdn.example/app.js" || mixed[1].Details[0] != "http://cdn.example/photo.jpg" {
+		t.Errorf("mixed-content issues = %+v", mixed)
+	}
+	headings := issuesFor(report, seo.CheckHeadings)
+	if len(headings) != 3 || !strings.Contains(headings[1].Details[0], "h2 -> h4") {
+		t.Errorf("headings issues = %+v", headings)
+	}
+	if report.Count(seo.SeverityError) == 0 {
+		t.Error("Count(error) = 0")
+	}
+}
+
+func TestAuditCrawl(t *testing.T) {
+	page := func(title, alternates, links string) string {
+		return `<html><head><title>` + title + `</title>` + alternates + `</head><body><h1>x</h1>` + links + `</body></html>`
+	}
+	s := newSite(t, map[string]string{
+		"/":   page("Same title", `<link rel="alternate" hreflang="en" href="/"><link rel="alternate" hreflang="de" href="/de">`, `<a href="/de">de</a><a href="/b">b</a><a href="/c">c</a>`),
+		"/de": page("Deutsch", `<link rel="alternate" hreflang="de" href="/de"><link rel="alternate" hreflang="EN_us" href="/">`, ``),
+		"/b":  page("Same title", ``, `<a href="/">home</a>`),
+	})
+	seed := s.web.URL + "/"
+	report, err := seo.AuditCrawl(context.Background(), s.client(), seed, snapapi.CrawlOptions{IgnoreRobots: true},
+		seo.Options{SkipExtract: true, SkipLinks: true, SkipOGImage: true})
+	if err != nil {
+		t.Fatalf("AuditCrawl() error: %v", err)
+	}
+	if len(report.Pages) != 3 {
+		t.Fatalf("Pages = %d", len(report.Pages))
+	}
+	if dup := issuesFor(report, seo.CheckDuplicateTitle); len(dup) != 1 || len(dup[0].Details) != 2 {
+		t.Errorf("duplicate-title issues = %+v", dup)
+	}
+	if fetch := issuesFor(report, seo.CheckFetch); len(fetch) != 1 || !strings.HasSuffix(fetch[0].URL, "/c") {
+		t.Errorf("fetch issues = %+v", fetch)
+	}
+	var invalid, noReturn bool
+	for _, i := range issuesFor(report, seo.CheckHreflang) {
+		if strings.HasPrefix(i.Message, "invalid hreflang") && i.Details[0] == "EN_us" {
+			invalid = true
+		}
+		if strings.Contains(i.Message, "do not link back") {
+			noReturn = true
+		}
+	}
+	// "/" and "/de" link to each other, so there are no missing return links.
+	if !invalid || noReturn {
+		t.Errorf("hreflang issues = %+v", issuesFor(report, seo.CheckHreflang))
+	}
+}
+
+func TestReport_Render(t *testing.T) {
+	s := newSite(t, map[string]string{"/": goodPage})
+	report, err := seo.Audit(context.Background(), s.client(), s.web.URL+"/",
+		seo.Options{Screenshots: true, SkipLinks: true, HTTPClient: s.web.Client()})
+	if err != nil {
+		t.Fatalf("Audit() error: %v", err)
+	}
+
+	var js bytes.Buffer
+	if err := report.WriteJSON(&js); err != nil {
+		t.Fatalf("WriteJSON() error: %v", err)
+	}
+	var decoded seo.Report
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil || len(decoded.Pages) != 1 || decoded.Pages[0].Title != report.Pages[0].Title {
+		t.Errorf("JSON round trip: %v, %+v", err, decoded)
+	}
+	if strings.Contains(js.String(), "iVBOR") || strings.Contains(js.String(), `"data"`) {
+		t.Error("screenshot data should not be in the JSON report")
+	}
+
+	var html bytes.Buffer
+	if err := report.WriteHTML(&html); err != nil {
+		t.Fatalf("WriteHTML() error: %v", err)
+	}
+	out := html.String()
+	for _, want := range []string{"<h1>SEO report</h1>", "A well sized title for the home page", "data:image/png;base64,", "mobile (390&times;844)", `class="warning"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML report is missing %q", want)
+		}
+	}
+}
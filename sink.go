@@ -0,0 +1,275 @@
+package snapapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sink is a destination for captured files. Implementations must be safe for
+// concurrent use.
+//
+// The built-in sinks are FileSink (local directory) and S3Sink (any
+// S3-compatible object store). Use SinkFunc to plug in anything else.
+type Sink interface {
+	// Put stores the content read from r under key.
+	Put(ctx context.Context, key string, r io.Reader, meta SinkMetadata) error
+}
+
+// SinkFunc adapts an ordinary function to the Sink interface.
+//
+//	sink := snapapi.SinkFunc(func(ctx context.Context, key string, r io.Reader, meta snapapi.SinkMetadata) error {
+//	    _, err := io.Copy(w, r)
+//	    return err
+//	})
+type SinkFunc func(ctx context.Context, key string, r io.Reader, meta SinkMetadata) error
+
+// Put calls f(ctx, key, r, meta).
+func (f SinkFunc) Put(ctx context.Context, key string, r io.Reader, meta SinkMetadata) error {
+	return f(ctx, key, r, meta)
+}
+
+// SinkMetadata describes a capture handed to a Sink.
+type SinkMetadata struct {
+	// ContentType is the MIME type of the capture (e.g. "image/png").
+	ContentType string
+	// Size is the length of the content in bytes.
+	Size int64
+	// Kind is the capture type: "screenshot", "pdf" or "video".
+	Kind string
+	// SourceURL is the page that was captured, if any.
+	SourceURL string
+	// CapturedAt is when the capture completed.
+	CapturedAt time.Time
+}
+
+// SinkResult is returned by CaptureTo after a capture has been stored.
+type SinkResult struct {
+	// Key is the expanded key the capture was stored under.
+	Key string
+	// Size is the number of bytes stored.
+	Size int64
+	// ContentType is the MIME type of the stored capture.
+	ContentType string
+}
+
+// DefaultKeyTemplate is the key template CaptureTo uses when none is given.
+const DefaultKeyTemplate = "{kind}/{host}/{date}/{hash}.{ext}"
+
+// CaptureTo captures a page and stores the result in sink. params must be a
+// ScreenshotParams, PDFParams or VideoParams (or a pointer to one).
+//
+// keyTemplate is expanded with the following placeholders; an empty template
+// means DefaultKeyTemplate:
+//
+//	{kind}   screenshot, pdf or video
+//	{host}   host name of the captured URL ("html" for raw HTML input)
+//	{path}   URL path with "/" replaced by "_" ("index" for the root)
+//	{date}   capture date, YYYY-MM-DD (UTC)
+//	{time}   capture time, HHMMSS (UTC)
+//	{unix}   capture time as Unix seconds
+//	{hash}   first 16 hex characters of the SHA-256 of the params
+//	{ext}    file extension derived from the output format
+//
+// For example:
+//
+//	res, err := client.CaptureTo(ctx, snapapi.NewFileSink("./captures"),
+//	    "{host}/{date}/{hash}.png",
+//	    snapapi.ScreenshotParams{URL: "https://example.com", FullPage: true},
+//	)
+//	fmt.Println(res.Key) // example.com/2026-03-23/3f2a9c0d1b7e4a55.png
+func (c *Client) CaptureTo(ctx context.Context, sink Sink, keyTemplate string, params interface{}) (*SinkResult, error) {
+	if sink == nil {
+		return nil, &APIError{Code: ErrInvalidParams, Message: "sink is required", StatusCode: 400}
+	}
+	var (
+		data   []byte
+		err    error
+		kind   string
+		format string
+		source string
+	)
+	switch p := params.(type) {
+	case *ScreenshotParams:
+		return c.CaptureTo(ctx, sink, keyTemplate, *p)
+	case *PDFParams:
+		return c.CaptureTo(ctx, sink, keyTemplate, *p)
+	case *VideoParams:
+		return c.CaptureTo(ctx, sink, keyTemplate, *p)
+	case ScreenshotParams:
+		kind, format, source = "screenshot", p.Format, p.URL
+		if format == "" {
+			format = "png"
+		}
+		data, err = c.Screenshot(ctx, p)
+	case PDFParams:
+		kind, format, source = "pdf", "pdf", p.URL
+		data, err = c.PDF(ctx, p)
+	case VideoParams:
+		kind, format, source = "video", p.Format, p.URL
+		if format == "" {
+			format = "mp4"
+		}
+		data, err = c.Video(ctx, p)
+	default:
+		return nil, &APIError{
+			Code:       ErrInvalidParams,
+			Message:    fmt.Sprintf("unsupported capture params type %T", params),
+			StatusCode: 400,
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if keyTemplate == "" {
+		keyTemplate = DefaultKeyTemplate
+	}
+	key := ExpandKeyTemplate(keyTemplate, KeyTemplateData{
+		Kind:       kind,
+		URL:        source,
+		Format:     format,
+		ParamsHash: paramsHash(params),
+		Time:       now,
+	})
+	meta := SinkMetadata{
+		ContentType: contentTypeForFormat(format),
+		Size:        int64(len(data)),
+		Kind:        kind,
+		SourceURL:   source,
+		CapturedAt:  now,
+	}
+	if err := sink.Put(ctx, key, bytes.NewReader(data), meta); err != nil {
+		return nil, fmt.Errorf("snapapi: store capture %q: %w", key, err)
+	}
+	return &SinkResult{Key: key, Size: meta.Size, ContentType: meta.ContentType}, nil
+}
+
+// KeyTemplateData holds the values substituted by ExpandKeyTemplate.
+type KeyTemplateData struct {
+	// Kind is the capture type ("screenshot", "pdf", "video").
+	Kind string
+	// URL is the captured page URL. Empty for raw HTML input.
+	URL string
+	// Format is the output format ("png", "pdf", "mp4", ...).
+	Format string
+	// ParamsHash is a stable hash of the capture parameters.
+	ParamsHash string
+	// Time is the capture time.
+	Time time.Time
+}
+
+// ExpandKeyTemplate replaces the placeholders documented on CaptureTo with
+// values from d. Unknown placeholders are left untouched.
+func ExpandKeyTemplate(tmpl string, d KeyTemplateData) string {
+	host, urlPath := "html", "index"
+	if u, err := url.Parse(d.URL); err == nil && u.Host != "" {
+		host = u.Hostname()
+		if p := strings.Trim(u.Path, "/"); p != "" {
+			urlPath = strings.ReplaceAll(p, "/", "_")
+		}
+	}
+	t := d.Time.UTC()
+	return strings.NewReplacer(
+		"{kind}", d.Kind,
+		"{host}", host,
+		"{path}", urlPath,
+		"{date}", t.Format("2006-01-02"),
+		"{time}", t.Format("150405"),
+		"{unix}", strconv.FormatInt(t.Unix(), 10),
+		"{hash}", d.ParamsHash,
+		"{ext}", extensionForFormat(d.Format),
+	).Replace(tmpl)
+}
+
+// paramsHash returns the first 16 hex characters of the SHA-256 of the JSON
+// encoding of v. encoding/json sorts map keys, so equal params hash equally.
+func paramsHash(v interface{}) string {
+	b, _ := json.Marshal(v)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+func extensionForFormat(format string) string {
+	switch format {
+	case "jpeg":
+		return "jpg"
+	case "":
+		return "bin"
+	}
+	return format
+}
+
+func contentTypeForFormat(format string) string {
+	switch format {
+	case "png", "jpeg", "webp", "avif", "gif":
+		return "image/" + format
+	case "jpg":
+		return "image/jpeg"
+	case "pdf":
+		return "application/pdf"
+	case "mp4", "webm":
+		return "video/" + format
+	}
+	return "application/octet-stream"
+}
+
+// ---------------------------------------------------------------------------
+// FileSink
+// ---------------------------------------------------------------------------
+
+// FileSink stores captures as files under a local directory, using the key
+// as a "/"-separated relative path.
+type FileSink struct {
+	// Dir is the root directory. It is created on first use.
+	Dir string
+}
+
+// NewFileSink returns a Sink that writes captures beneath dir.
+//
+//	sink := snapapi.NewFileSink("./captures")
+func NewFileSink(dir string) *FileSink {
+	return &FileSink{Dir: dir}
+}
+
+// Put writes r to Dir/key. The file is written to a temporary name and
+// renamed into place, so readers never observe a partial capture.
+func (s *FileSink) Put(ctx context.Context, key string, r io.Reader, meta SinkMetadata) error {
+	if !fs.ValidPath(key) || key == "." {
+		return fmt.Errorf("snapapi: invalid sink key %q", key)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	dst := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("snapapi: create dir for %q: %w", key, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".snapapi-*")
+	if err != nil {
+		return fmt.Errorf("snapapi: create temp file: %w", err)
+	}
+	_, err = io.Copy(tmp, r)
+	err = errors.Join(err, tmp.Chmod(0644), tmp.Close())
+	if err == nil {
+		err = os.Rename(tmp.Name(), dst)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("snapapi: write file %q: %w", dst, err)
+	}
+	return nil
+}
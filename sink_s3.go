@@ -0,0 +1,242 @@
+package snapapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config configures an S3Sink.
+type S3Config struct {
+	// Endpoint is the base URL of the object store, e.g.
+	// "https://s3.eu-west-1.amazonaws.com" or "http://localhost:9000".
+	Endpoint string
+	// Region is the signing region. Default: "us-east-1".
+	Region string
+	// Bucket is the destination bucket. Required.
+	Bucket string
+	// AccessKeyID and SecretAccessKey are the signing credentials. Required.
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is the optional token for temporary credentials.
+	SessionToken string
+	// PathStyle addresses the bucket as Endpoint/Bucket/key instead of
+	// Bucket.Endpoint/key. Most self-hosted stores (MinIO, Ceph) need this.
+	PathStyle bool
+	// KeyPrefix is prepended to every key.
+	KeyPrefix string
+	// HTTPClient is used for uploads. Default: http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// S3Sink uploads captures to an S3-compatible object store with AWS
+// Signature Version 4 signed PUT requests. It uses only the standard library.
+type S3Sink struct {
+	cfg S3Config
+	now func() time.Time
+}
+
+// NewS3Sink returns a Sink that uploads to the bucket described by cfg.
+//
+//	sink := snapapi.NewS3Sink(snapapi.S3Config{
+//	    Endpoint:        "https://s3.eu-west-1.amazonaws.com",
+//	    Region:          "eu-west-1",
+//	    Bucket:          "captures",
+//	    AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+//	    SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+//	})
+func NewS3Sink(cfg S3Config) *S3Sink {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	return &S3Sink{cfg: cfg, now: time.Now}
+}
+
+// s3ErrorResponse is the XML error body returned by S3-compatible stores.
+type s3ErrorResponse struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// Put uploads the content read from r as the object KeyPrefix+key. The
+// content type and capture metadata are stored as object headers
+// (x-amz-meta-kind, x-amz-meta-source-url, x-amz-meta-captured-at).
+func (s *S3Sink) Put(ctx context.Context, key string, r io.Reader, meta SinkMetadata) error {
+	if s.cfg.Bucket == "" || s.cfg.Endpoint == "" {
+		return fmt.Errorf("snapapi: s3 sink: endpoint and bucket are required")
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("snapapi: s3 sink: read content: %w", err)
+	}
+	endpoint, err := url.Parse(s.cfg.Endpoint)
+	if err != nil {
+		return fmt.Errorf("snapapi: s3 sink: invalid endpoint: %w", err)
+	}
+	objectPath := "/" + awsURIEncode(s.cfg.KeyPrefix+key, false)
+	host := endpoint.Host
+	if s.cfg.PathStyle {
+		objectPath = "/" + awsURIEncode(s.cfg.Bucket, true) + objectPath
+	} else {
+		host = s.cfg.Bucket + "." + host
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint.Scheme+"://"+host+objectPath, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("snapapi: s3 sink: build request: %w", err)
+	}
+	// Keep the encoded path exactly as signed.
+	req.URL.RawPath = objectPath
+	if meta.ContentType != "" {
+		req.Header.Set("Content-Type", meta.ContentType)
+	}
+	if meta.Kind != "" {
+		req.Header.Set("X-Amz-Meta-Kind", meta.Kind)
+	}
+	if meta.SourceURL != "" {
+		req.Header.Set("X-Amz-Meta-Source-Url", meta.SourceURL)
+	}
+	if !meta.CapturedAt.IsZero() {
+		req.Header.Set("X-Amz-Meta-Captured-At", meta.CapturedAt.UTC().Format(time.RFC3339))
+	}
+	if s.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.cfg.SessionToken)
+	}
+	signV4(req, body, s.cfg.AccessKeyID, s.cfg.SecretAccessKey, s.cfg.Region, "s3", s.now())
+
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("snapapi: s3 sink: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		var e s3ErrorResponse
+		if xml.Unmarshal(respBody, &e) == nil && e.Code != "" {
+			return fmt.Errorf("snapapi: s3 sink: HTTP %d: %s: %s", resp.StatusCode, e.Code, e.Message)
+		}
+		return fmt.Errorf("snapapi: s3 sink: HTTP %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+// signV4 adds AWS Signature Version 4 headers (X-Amz-Date,
+// X-Amz-Content-Sha256 and Authorization) to req. All headers already set on
+// req, plus Host, are signed.
+func signV4(req *http.Request, body []byte, accessKey, secretKey, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature,
+	))
+}
+
+// canonicalQuery encodes query parameters sorted by key, as SigV4 requires.
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, awsURIEncode(k, true)+"="+awsURIEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsURIEncode percent-encodes s as described in the SigV4 specification:
+// every byte except A-Z, a-z, 0-9, '-', '.', '_' and '~' is encoded, and '/'
+// is encoded only if encodeSlash is true.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case 'A' <= ch && ch <= 'Z', 'a' <= ch && ch <= 'z', '0' <= ch && ch <= '9',
+			ch == '-', ch == '.', ch == '_', ch == '~':
+			b.WriteByte(ch)
+		case ch == '/' && !encodeSlash:
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,257 @@
+package snapapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSitemapSize caps the size of one sitemap file, both as downloaded and
+// after decompression. The sitemap protocol limits files to 50 MB.
+var maxSitemapSize int64 = 64 << 20
+
+// SitemapURL is one <url> entry of a sitemap.
+type SitemapURL struct {
+	// Loc is the page URL.
+	Loc string
+	// LastMod is the parsed <lastmod>, or zero if absent or unparsable.
+	LastMod time.Time
+	// ChangeFreq is the raw <changefreq> value, e.g. "weekly".
+	ChangeFreq string
+	// Priority is the <priority> value; 0.5 (the protocol default) if absent.
+	Priority float64
+}
+
+// SitemapFilter selects which sitemap URLs are returned or captured.
+type SitemapFilter struct {
+	// Include keeps only URLs matching at least one of these regular
+	// expressions. Empty keeps every URL.
+	Include []string
+	// Exclude drops URLs matching any of these regular expressions.
+	Exclude []string
+	// ModifiedSince drops URLs whose lastmod is before this time. URLs
+	// without a lastmod are kept.
+	ModifiedSince time.Time
+	// MinPriority drops URLs with a lower priority.
+	MinPriority float64
+	// MaxURLs caps the number of URLs returned, in sitemap order. Zero means
+	// no limit.
+	MaxURLs int
+	// MaxSitemaps caps the number of sitemap files fetched when following a
+	// sitemap index. Default: 100.
+	MaxSitemaps int
+}
+
+// SitemapOptions configures CaptureSitemap.
+type SitemapOptions struct {
+	SitemapFilter
+	BatchOptions
+	// Params, if set, builds the job for each URL instead of the screenshot
+	// template, e.g. to extract or PDF every page. It must return a value
+	// accepted by Batch.
+	Params func(u SitemapURL) interface{}
+}
+
+// FetchSitemap fetches the sitemap or sitemap index at sitemapURL, following
+// index entries and decompressing gzipped files, and returns the URLs that
+// pass filter, deduplicated, in sitemap order.
+//
+//	urls, err := client.FetchSitemap(ctx, "https://example.com/sitemap.xml", snapapi.SitemapFilter{
+//	    Include:       []string{`/blog/`},
+//	    ModifiedSince: time.Now().AddDate(0, 0, -7),
+//	})
+func (c *Client) FetchSitemap(ctx context.Context, sitemapURL string, filter SitemapFilter) ([]SitemapURL, error) {
+	if sitemapURL == "" {
+		return nil, &APIError{Code: ErrInvalidParams, Message: "sitemap URL is required", StatusCode: 400}
+	}
+	include, err := compilePatterns(filter.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compilePatterns(filter.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	maxSitemaps := filter.MaxSitemaps
+	if maxSitemaps <= 0 {
+		maxSitemaps = 100
+	}
+
+	var (
+		out     []SitemapURL
+		seen    = make(map[string]bool)
+		visited = make(map[string]bool)
+		queue   = []string{sitemapURL}
+	)
+	for len(queue) > 0 {
+		loc := queue[0]
+		queue = queue[1:]
+		if visited[loc] {
+			continue
+		}
+		if len(visited) >= maxSitemaps {
+			break
+		}
+		visited[loc] = true
+
+		doc, err := c.fetchSitemap(ctx, loc)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range doc.Sitemaps {
+			if s := strings.TrimSpace(s.Loc); s != "" {
+				queue = append(queue, s)
+			}
+		}
+		for _, e := range doc.URLs {
+			u := e.parse()
+			if u.Loc == "" || seen[u.Loc] || !filter.keep(u, include, exclude) {
+				continue
+			}
+			seen[u.Loc] = true
+			out = append(out, u)
+			if filter.MaxURLs > 0 && len(out) >= filter.MaxURLs {
+				return out, nil
+			}
+		}
+	}
+	return out, nil
+}
+
+// CaptureSitemap fetches a sitemap with FetchSitemap and captures every
+// selected URL concurrently, returning one BatchResult per URL (with ID set
+// to the URL) in sitemap order, as Batch does. Each job is a copy of
+// template with URL set, unless opts.Params is given.
+//
+//	results, err := client.CaptureSitemap(ctx, "https://example.com/sitemap.xml",
+//	    snapapi.ScreenshotParams{Format: "png", FullPage: true},
+//	    snapapi.SitemapOptions{
+//	        SitemapFilter: snapapi.SitemapFilter{MinPriority: 0.5},
+//	        BatchOptions:  snapapi.BatchOptions{Concurrency: 8},
+//	    })
+//
+// To extract or PDF pages instead, set Params:
+//
+//	opts.Params = func(u snapapi.SitemapURL) interface{} {
+//	    return snapapi.ExtractParams{URL: u.Loc, Format: "markdown"}
+//	}
+func (c *Client) CaptureSitemap(ctx context.Context, sitemapURL string, template ScreenshotParams, opts SitemapOptions) ([]BatchResult, error) {
+	urls, err := c.FetchSitemap(ctx, sitemapURL, opts.SitemapFilter)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]BatchItem, len(urls))
+	for i, u := range urls {
+		var params interface{}
+		if opts.Params != nil {
+			params = opts.Params(u)
+		} else {
+			p := template
+			p.URL = u.Loc
+			params = p
+		}
+		items[i] = BatchItem{ID: u.Loc, Params: params}
+	}
+	return c.Batch(ctx, items, opts.BatchOptions)
+}
+
+// sitemapDocument is either a <urlset> or a <sitemapindex>.
+type sitemapDocument struct {
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+	Priority   string `xml:"priority"`
+}
+
+// fetchSitemap downloads and parses one sitemap file.
+func (c *Client) fetchSitemap(ctx context.Context, loc string) (*sitemapDocument, error) {
+	data, err := c.downloadLimited(ctx, loc, maxSitemapSize)
+	if err != nil {
+		return nil, fmt.Errorf("snapapi: fetch sitemap %s: %w", loc, err)
+	}
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("snapapi: decompress sitemap %s: %w", loc, err)
+		}
+		defer zr.Close()
+		if data, err = io.ReadAll(io.LimitReader(zr, maxSitemapSize+1)); err != nil {
+			return nil, fmt.Errorf("snapapi: decompress sitemap %s: %w", loc, err)
+		}
+		if int64(len(data)) > maxSitemapSize {
+			return nil, fmt.Errorf("snapapi: decompress sitemap %s: larger than %d bytes", loc, maxSitemapSize)
+		}
+	}
+	var doc sitemapDocument
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("snapapi: parse sitemap %s: %w", loc, err)
+	}
+	return &doc, nil
+}
+
+func (e sitemapEntry) parse() SitemapURL {
+	u := SitemapURL{
+		Loc:        strings.TrimSpace(e.Loc),
+		ChangeFreq: strings.TrimSpace(e.ChangeFreq),
+		Priority:   0.5,
+	}
+	if p, err := strconv.ParseFloat(strings.TrimSpace(e.Priority), 64); err == nil {
+		u.Priority = p
+	}
+	lastmod := strings.TrimSpace(e.LastMod)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, lastmod); err == nil {
+			u.LastMod = t
+			break
+		}
+	}
+	return u
+}
+
+func (f SitemapFilter) keep(u SitemapURL, include, exclude []*regexp.Regexp) bool {
+	if u.Priority < f.MinPriority {
+		return false
+	}
+	if !f.ModifiedSince.IsZero() && !u.LastMod.IsZero() && u.LastMod.Before(f.ModifiedSince) {
+		return false
+	}
+	for _, re := range exclude {
+		if re.MatchString(u.Loc) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, re := range include {
+		if re.MatchString(u.Loc) {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePatterns compiles URL filter patterns.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	out := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, &APIError{Code: ErrInvalidParams, Message: fmt.Sprintf("invalid URL pattern %q: %v", p, err), StatusCode: 400}
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
@@ -51,11 +51,14 @@ import (
 //
 // The client is safe for concurrent use by multiple goroutines.
 type Client struct {
-	apiKey     string
+	creds      CredentialProvider
+	authMode   AuthMode
 	baseURL    string
 	httpClient *http.Client
 	retries    int
 	retryDelay time.Duration
+	budget     *Budget
+	recorder   Recorder
 
 	// Sub-namespace accessors. Populated by New().
 	Storage   *StorageNamespace
@@ -65,6 +68,7 @@ type Client struct {
 }
 
 // New creates a new SnapAPI client with the given API key.
+// Use WithCredentials instead to supply keys dynamically.
 //
 //	client := snapapi.New("sk_...",
 //	    snapapi.WithTimeout(45*time.Second),
@@ -72,8 +76,9 @@ type Client struct {
 //	)
 func New(apiKey string, opts ...Option) *Client {
 	c := &Client{
-		apiKey:  apiKey,
-		baseURL: defaultBaseURL,
+		creds:    StaticCredentials(apiKey),
+		authMode: AuthDefault,
+		baseURL:  defaultBaseURL,
 		httpClient: &http.Client{
 			Timeout: defaultTimeout,
 		},
@@ -137,7 +142,7 @@ func (c *Client) Analyze(ctx context.Context, p AnalyzeParams) (*AnalyzeResult,
 		return nil, &APIError{Code: ErrInvalidParams, Message: "URL is required", StatusCode: 400}
 	}
 	var result AnalyzeResult
-	if err := c.doJSON(ctx, http.MethodPost, "/v1/analyze", p, &result); err != nil {
+	if err := c.doBillableJSON(ctx, "analyze", http.MethodPost, "/v1/analyze", p, &result); err != nil {
 		return nil, err
 	}
 	return &result, nil
@@ -215,6 +220,8 @@ type StorageItem struct {
 	ContentType string `json:"content_type"`
 	// CreatedAt is the ISO 8601 creation timestamp.
 	CreatedAt string `json:"created_at"`
+	// ETag is an opaque content version identifier, if the server provides one.
+	ETag string `json:"etag,omitempty"`
 }
 
 // StorageListResult is the paginated response from Storage.List.
@@ -512,6 +519,27 @@ type APIKey struct {
 	LastUsedAt string `json:"last_used_at,omitempty"`
 	// ExpiresAt is the optional ISO 8601 expiry timestamp.
 	ExpiresAt string `json:"expires_at,omitempty"`
+	// Scopes lists the permissions granted to the key (e.g. "screenshot",
+	// "storage:read"). Empty means unrestricted.
+	Scopes []string `json:"scopes,omitempty"`
+	// Restrictions limits where and how often the key may be used.
+	Restrictions *APIKeyRestrictions `json:"restrictions,omitempty"`
+}
+
+// APIKeyRestrictions limits where and how often an API key may be used.
+type APIKeyRestrictions struct {
+	// AllowedEndpoints is an allowlist of API paths (e.g. "/v1/screenshot").
+	// Empty means all endpoints.
+	AllowedEndpoints []string `json:"allowed_endpoints,omitempty"`
+	// AllowedIPs is an allowlist of client IP addresses or CIDR ranges.
+	// Empty means any address.
+	AllowedIPs []string `json:"allowed_ips,omitempty"`
+	// RateLimitPerMinute caps the key's request rate. Zero means the
+	// account default.
+	RateLimitPerMinute int `json:"rate_limit_per_minute,omitempty"`
+	// MonthlyQuota caps the number of requests per billing period. Zero
+	// means the account quota.
+	MonthlyQuota int `json:"monthly_quota,omitempty"`
 }
 
 // CreateAPIKeyParams are the parameters for APIKeys.Create.
@@ -520,6 +548,23 @@ type CreateAPIKeyParams struct {
 	Name string `json:"name"`
 	// ExpiresAt is an optional ISO 8601 expiry timestamp.
 	ExpiresAt string `json:"expires_at,omitempty"`
+	// Scopes lists the permissions granted to the key. Empty means unrestricted.
+	Scopes []string `json:"scopes,omitempty"`
+	// Restrictions limits where and how often the key may be used.
+	Restrictions *APIKeyRestrictions `json:"restrictions,omitempty"`
+}
+
+// UpdateAPIKeyParams are the parameters for APIKeys.Update.
+// Zero-valued fields are left unchanged.
+type UpdateAPIKeyParams struct {
+	// Name is a new human-readable label for the key.
+	Name string `json:"name,omitempty"`
+	// ExpiresAt is a new ISO 8601 expiry timestamp.
+	ExpiresAt string `json:"expires_at,omitempty"`
+	// Scopes replaces the key's scopes.
+	Scopes []string `json:"scopes,omitempty"`
+	// Restrictions replaces the key's restrictions.
+	Restrictions *APIKeyRestrictions `json:"restrictions,omitempty"`
 }
 
 // Create creates a new API key.
@@ -550,6 +595,159 @@ func (a *APIKeysNamespace) List(ctx context.Context) ([]APIKey, error) {
 	return result, nil
 }
 
+// Get returns metadata for a single API key by ID.
+//
+//	key, err := client.APIKeys.Get(ctx, "key_abc123")
+//	fmt.Println(key.LastUsedAt)
+func (a *APIKeysNamespace) Get(ctx context.Context, id string) (*APIKey, error) {
+	if id == "" {
+		return nil, &APIError{Code: ErrInvalidParams, Message: "id is required", StatusCode: 400}
+	}
+	var result APIKey
+	if err := a.c.doJSON(ctx, http.MethodGet, "/v1/api-keys/"+id, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Update changes the name, expiry, scopes or restrictions of an API key.
+//
+//	key, err := client.APIKeys.Update(ctx, "key_abc123", snapapi.UpdateAPIKeyParams{
+//	    Scopes: []string{"screenshot", "pdf"},
+//	    Restrictions: &snapapi.APIKeyRestrictions{
+//	        AllowedIPs:         []string{"203.0.113.0/24"},
+//	        RateLimitPerMinute: 60,
+//	    },
+//	})
+func (a *APIKeysNamespace) Update(ctx context.Context, id string, p UpdateAPIKeyParams) (*APIKey, error) {
+	if id == "" {
+		return nil, &APIError{Code: ErrInvalidParams, Message: "id is required", StatusCode: 400}
+	}
+	var result APIKey
+	if err := a.c.doJSON(ctx, http.MethodPatch, "/v1/api-keys/"+id, p, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RotateAPIKeyParams are the parameters for APIKeys.Rotate.
+type RotateAPIKeyParams struct {
+	// Name is the label for the successor key. Default: the old key's name.
+	Name string
+	// Persist is called with the successor key (including its raw Key value)
+	// and must store it wherever consumers read it from. If Persist returns
+	// an error the successor is revoked and the old key is left untouched.
+	// Required.
+	Persist func(ctx context.Context, key *APIKey) error
+	// GracePeriod is the longest Rotate waits after Persist before revoking
+	// the old key. Default: 5 minutes.
+	GracePeriod time.Duration
+	// QuietPeriod, if set, revokes the old key early once its LastUsedAt has
+	// not advanced for this long, i.e. once consumers have switched over.
+	QuietPeriod time.Duration
+	// PollInterval is how often the old key's LastUsedAt is checked while
+	// waiting for QuietPeriod. Default: 15 seconds.
+	PollInterval time.Duration
+}
+
+// Rotate replaces an API key without downtime. It creates a successor with
+// the old key's scopes and restrictions, hands it to p.Persist, waits until
+// the old key is no longer in use (see QuietPeriod) or GracePeriod elapses,
+// and then revokes the old key. It returns the successor key.
+//
+// If the context is cancelled while waiting, the old key is not revoked and
+// the successor is returned together with the context error.
+//
+//	newKey, err := client.APIKeys.Rotate(ctx, "key_abc123", snapapi.RotateAPIKeyParams{
+//	    Persist: func(ctx context.Context, k *snapapi.APIKey) error {
+//	        return secrets.Put(ctx, "snapapi-key", k.Key)
+//	    },
+//	    GracePeriod: 10 * time.Minute,
+//	    QuietPeriod: 2 * time.Minute,
+//	})
+func (a *APIKeysNamespace) Rotate(ctx context.Context, id string, p RotateAPIKeyParams) (*APIKey, error) {
+	if p.Persist == nil {
+		return nil, &APIError{Code: ErrInvalidParams, Message: "Persist callback is required", StatusCode: 400}
+	}
+	old, err := a.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	name := p.Name
+	if name == "" {
+		name = old.Name
+	}
+	successor, err := a.Create(ctx, CreateAPIKeyParams{
+		Name:         name,
+		ExpiresAt:    old.ExpiresAt,
+		Scopes:       old.Scopes,
+		Restrictions: old.Restrictions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Persist(ctx, successor); err != nil {
+		// Best effort: don't leave an orphaned key behind.
+		_ = a.Revoke(context.WithoutCancel(ctx), successor.ID)
+		return nil, fmt.Errorf("snapapi: persist rotated key: %w", err)
+	}
+
+	grace := p.GracePeriod
+	if grace <= 0 {
+		grace = 5 * time.Minute
+	}
+	if err := a.waitUnused(ctx, old, grace, p.QuietPeriod, p.PollInterval); err != nil {
+		return successor, err
+	}
+	if err := a.Revoke(ctx, old.ID); err != nil {
+		return successor, err
+	}
+	return successor, nil
+}
+
+// waitUnused blocks until key's LastUsedAt has been stable for quiet, or
+// until grace has elapsed, whichever comes first.
+func (a *APIKeysNamespace) waitUnused(ctx context.Context, key *APIKey, grace, quiet, poll time.Duration) error {
+	deadline := time.NewTimer(grace)
+	defer deadline.Stop()
+	if quiet <= 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return nil
+		}
+	}
+	if poll <= 0 {
+		poll = 15 * time.Second
+	}
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	lastUsed, stableSince := key.LastUsedAt, time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return nil
+		case <-ticker.C:
+		}
+		current, err := a.Get(ctx, key.ID)
+		if err != nil {
+			// Transient lookup failures just delay the decision; the grace
+			// period still bounds the wait.
+			continue
+		}
+		if current.LastUsedAt != lastUsed {
+			lastUsed, stableSince = current.LastUsedAt, time.Now()
+			continue
+		}
+		if time.Since(stableSince) >= quiet {
+			return nil
+		}
+	}
+}
+
 // Revoke permanently deletes an API key by ID.
 //
 //	err := client.APIKeys.Revoke(ctx, "key_abc123")
@@ -0,0 +1,370 @@
+package snapapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StorageFS exposes stored captures as a read-only io/fs file system.
+//
+// Keys are treated as "/"-separated paths, so a capture stored under
+// "reports/2026/home.png" appears as the file "home.png" inside the virtual
+// directories "reports" and "reports/2026". Directories exist only as long as
+// at least one key lives beneath them. If both "a" and "a/b" are stored, "a"
+// is a directory and the capture stored under "a" is not reachable.
+//
+// StorageFS implements fs.FS, fs.ReadDirFS, fs.StatFS and fs.ReadFileFS, so it
+// works with http.FS, fs.WalkDir, fs.Glob and anything else that accepts an
+// fs.FS. Every call reflects the live contents of storage; nothing is cached.
+//
+//	fsys := client.Storage.FS(ctx)
+//	http.Handle("/captures/", http.StripPrefix("/captures/", http.FileServer(http.FS(fsys))))
+type StorageFS struct {
+	s   *StorageNamespace
+	ctx context.Context
+}
+
+// FS returns a read-only fs.FS view of stored captures. The context is used
+// for every API call and download made through the returned file system,
+// since the fs interfaces have no way to pass one per call.
+//
+//	fsys := client.Storage.FS(ctx)
+//	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+//	    fmt.Println(p)
+//	    return err
+//	})
+func (s *StorageNamespace) FS(ctx context.Context) *StorageFS {
+	return &StorageFS{s: s, ctx: ctx}
+}
+
+// Compile-time interface checks.
+var (
+	_ fs.FS         = (*StorageFS)(nil)
+	_ fs.ReadDirFS  = (*StorageFS)(nil)
+	_ fs.StatFS     = (*StorageFS)(nil)
+	_ fs.ReadFileFS = (*StorageFS)(nil)
+)
+
+// Open opens the named file or directory.
+func (f *StorageFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	info, err := f.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if info.IsDir() {
+		entries, err := f.readDir(name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &storageDir{info: info, entries: entries}, nil
+	}
+	return &storageFile{fsys: f, info: info}, nil
+}
+
+// Stat returns the fs.FileInfo for the named file or directory without
+// downloading its content.
+func (f *StorageFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	info, err := f.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return info, nil
+}
+
+// ReadDir lists the named directory, sorted by file name.
+func (f *StorageFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	info, err := f.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	entries, err := f.readDir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return entries, nil
+}
+
+// ReadFile downloads and returns the content of the named capture.
+func (f *StorageFS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	info, err := f.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	}
+	data, err := f.s.c.download(f.ctx, info.item.URL)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+// stat resolves name to a file (an exact key match) or a virtual directory
+// (one or more keys beneath name + "/"). As in readDir, a directory shadows
+// a file of the same name.
+func (f *StorageFS) stat(name string) (*storageFileInfo, error) {
+	if name == "." {
+		return &storageFileInfo{name: ".", dir: true}, nil
+	}
+	items, err := f.s.listAll(f.ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	var file *storageFileInfo
+	for i := range items {
+		key := cleanStorageKey(items[i].Key)
+		if key == name {
+			file = newStorageFileInfo(path.Base(name), &items[i])
+		} else if strings.HasPrefix(key, name+"/") && fs.ValidPath(key) {
+			return &storageFileInfo{name: path.Base(name), dir: true}, nil
+		}
+	}
+	if file != nil {
+		return file, nil
+	}
+	return nil, fs.ErrNotExist
+}
+
+// readDir returns the immediate children of the directory name.
+func (f *StorageFS) readDir(name string) ([]fs.DirEntry, error) {
+	prefix := ""
+	if name != "." {
+		prefix = name + "/"
+	}
+	items, err := f.s.listAll(f.ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[string]*storageFileInfo)
+	for i := range items {
+		key := cleanStorageKey(items[i].Key)
+		if !strings.HasPrefix(key, prefix) || !fs.ValidPath(key) {
+			continue
+		}
+		rest := key[len(prefix):]
+		if child, _, isDir := strings.Cut(rest, "/"); isDir {
+			// A directory shadows a file of the same name.
+			children[child] = &storageFileInfo{name: child, dir: true}
+		} else if _, seen := children[rest]; !seen {
+			children[rest] = newStorageFileInfo(rest, &items[i])
+		}
+	}
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, info := range children {
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// listAll follows Storage.List pagination and returns every item whose key
+// starts with prefix.
+func (s *StorageNamespace) listAll(ctx context.Context, prefix string) ([]StorageItem, error) {
+	var items []StorageItem
+	for page := 1; ; page++ {
+		result, err := s.List(ctx, StorageListParams{Page: page, PerPage: 100, Prefix: prefix})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, result.Items...)
+		if !result.HasMore || len(result.Items) == 0 {
+			return items, nil
+		}
+	}
+}
+
+// cleanStorageKey strips a leading slash so keys map onto fs.ValidPath names.
+func cleanStorageKey(key string) string {
+	return strings.TrimPrefix(key, "/")
+}
+
+// storageFileInfo implements fs.FileInfo for stored captures and virtual
+// directories.
+type storageFileInfo struct {
+	name    string
+	dir     bool
+	item    *StorageItem
+	modTime time.Time
+}
+
+func newStorageFileInfo(name string, item *StorageItem) *storageFileInfo {
+	info := &storageFileInfo{name: name, item: item}
+	if t, err := time.Parse(time.RFC3339, item.CreatedAt); err == nil {
+		info.modTime = t
+	}
+	return info
+}
+
+func (i *storageFileInfo) Name() string       { return i.name }
+func (i *storageFileInfo) ModTime() time.Time { return i.modTime }
+func (i *storageFileInfo) IsDir() bool        { return i.dir }
+
+func (i *storageFileInfo) Size() int64 {
+	if i.item == nil {
+		return 0
+	}
+	return i.item.Size
+}
+
+func (i *storageFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// Sys returns the underlying *StorageItem for files and nil for directories.
+func (i *storageFileInfo) Sys() interface{} {
+	if i.item == nil {
+		return nil
+	}
+	return i.item
+}
+
+// storageFile is an open stored capture. Its content is downloaded on the
+// first Read, Seek or ReadAt.
+type storageFile struct {
+	fsys   *StorageFS
+	info   *storageFileInfo
+	r      *bytes.Reader
+	closed bool
+}
+
+func (f *storageFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *storageFile) load() error {
+	if f.closed {
+		return fs.ErrClosed
+	}
+	if f.r != nil {
+		return nil
+	}
+	data, err := f.fsys.s.c.download(f.fsys.ctx, f.info.item.URL)
+	if err != nil {
+		return err
+	}
+	f.r = bytes.NewReader(data)
+	return nil
+}
+
+func (f *storageFile) Read(p []byte) (int, error) {
+	if err := f.load(); err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.info.name, Err: err}
+	}
+	return f.r.Read(p)
+}
+
+func (f *storageFile) Seek(offset int64, whence int) (int64, error) {
+	if err := f.load(); err != nil {
+		return 0, &fs.PathError{Op: "seek", Path: f.info.name, Err: err}
+	}
+	return f.r.Seek(offset, whence)
+}
+
+func (f *storageFile) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.load(); err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.info.name, Err: err}
+	}
+	return f.r.ReadAt(p, off)
+}
+
+func (f *storageFile) Close() error {
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.info.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	return nil
+}
+
+// storageDir is an open virtual directory.
+type storageDir struct {
+	info    *storageFileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *storageDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *storageDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+func (d *storageDir) Close() error { return nil }
+
+func (d *storageDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
+
+// download fetches the content of a stored capture from its public URL.
+// The API key is deliberately not sent: the URL points at the storage CDN,
+// not the SnapAPI API.
+func (c *Client) download(ctx context.Context, url string) ([]byte, error) {
+	return c.downloadLimited(ctx, url, 0)
+}
+
+// downloadLimited is download for URLs outside the caller's control: a body
+// larger than limit bytes is an error. A limit of 0 means no limit.
+func (c *Client) downloadLimited(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, &APIError{Code: ErrInvalidParams, Message: "invalid download URL: " + err.Error()}
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, &APIError{Code: ErrConnectionError, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+	r := io.Reader(resp.Body)
+	if limit > 0 {
+		r = io.LimitReader(resp.Body, limit+1)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, &APIError{Code: ErrConnectionError, Message: "read download: " + err.Error()}
+	}
+	if resp.StatusCode >= 400 {
+		return nil, parseAPIError(body, resp.StatusCode, resp.Header)
+	}
+	if limit > 0 && int64(len(body)) > limit {
+		return nil, fmt.Errorf("snapapi: download is larger than %d bytes", limit)
+	}
+	return body, nil
+}
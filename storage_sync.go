@@ -0,0 +1,386 @@
+package snapapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syncManifestName is the file SyncToDir keeps in the target directory to
+// remember what it downloaded.
+const syncManifestName = ".snapapi-sync.json"
+
+// SyncOptions configures Storage.SyncToDir.
+type SyncOptions struct {
+	// Concurrency is the number of parallel downloads. Default: 4.
+	Concurrency int
+	// Delete removes local files that were downloaded by a previous sync but
+	// no longer exist remotely. Files SyncToDir did not create are never
+	// touched.
+	Delete bool
+}
+
+// SyncResult reports what Storage.SyncToDir did.
+type SyncResult struct {
+	// Downloaded lists the keys that were fetched in this run.
+	Downloaded []string
+	// Skipped lists the keys whose local copy was already up to date.
+	Skipped []string
+	// Deleted lists the keys whose local copy was removed (SyncOptions.Delete).
+	Deleted []string
+	// Failed maps keys to the error that prevented them from syncing.
+	Failed map[string]error
+	// Bytes is the total number of bytes downloaded.
+	Bytes int64
+}
+
+// syncEntry is the manifest record for one downloaded capture.
+type syncEntry struct {
+	Size      int64  `json:"size"`
+	ETag      string `json:"etag,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// SyncToDir mirrors every stored capture whose key starts with prefix into
+// dir, using the full key as the relative file path.
+//
+// The sync is incremental: a capture is skipped when the local file has the
+// same size and the same ETag (or creation time, if the server sends no ETag)
+// as recorded by the previous sync. A local file the manifest does not
+// record is downloaded again. Downloads are written to a temporary file and
+// renamed into place, so an interrupted sync leaves no partial files and
+// simply resumes where it stopped when run again.
+//
+// Per-key failures do not abort the sync; they are collected in
+// SyncResult.Failed and returned together as the error.
+//
+//	res, err := client.Storage.SyncToDir(ctx, "monitoring/", "./captures", snapapi.SyncOptions{Delete: true})
+//	fmt.Printf("%d downloaded, %d unchanged\n", len(res.Downloaded), len(res.Skipped))
+func (s *StorageNamespace) SyncToDir(ctx context.Context, prefix, dir string, opts SyncOptions) (*SyncResult, error) {
+	if dir == "" {
+		return nil, &APIError{Code: ErrInvalidParams, Message: "dir is required", StatusCode: 400}
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("snapapi: create sync dir %q: %w", dir, err)
+	}
+	manifest, err := readSyncManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.listAll(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{Failed: make(map[string]error)}
+	remote := make(map[string]bool, len(items))
+	var todo []StorageItem
+	for _, item := range items {
+		key := cleanStorageKey(item.Key)
+		if !fs.ValidPath(key) || key == syncManifestName {
+			result.Failed[item.Key] = fmt.Errorf("snapapi: key %q is not a valid relative path", item.Key)
+			continue
+		}
+		remote[key] = true
+		if syncUpToDate(dir, key, item, manifest[key]) {
+			result.Skipped = append(result.Skipped, key)
+			manifest[key] = syncEntry{Size: item.Size, ETag: item.ETag, CreatedAt: item.CreatedAt}
+			continue
+		}
+		todo = append(todo, item)
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		jobs = make(chan StorageItem)
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				key := cleanStorageKey(item.Key)
+				n, err := s.syncOne(ctx, dir, key, item.URL)
+				mu.Lock()
+				if err != nil {
+					result.Failed[key] = err
+				} else {
+					result.Downloaded = append(result.Downloaded, key)
+					result.Bytes += n
+					manifest[key] = syncEntry{Size: n, ETag: item.ETag, CreatedAt: item.CreatedAt}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, item := range todo {
+		select {
+		case jobs <- item:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if opts.Delete && ctx.Err() == nil {
+		for key := range manifest {
+			if remote[key] || !hasKeyPrefix(key, prefix) {
+				continue
+			}
+			err := os.Remove(filepath.Join(dir, filepath.FromSlash(key)))
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				result.Failed[key] = fmt.Errorf("snapapi: remove %q: %w", key, err)
+				continue
+			}
+			delete(manifest, key)
+			result.Deleted = append(result.Deleted, key)
+		}
+	}
+
+	sort.Strings(result.Downloaded)
+	sort.Strings(result.Skipped)
+	sort.Strings(result.Deleted)
+
+	errs := []error{writeSyncManifest(dir, manifest), ctx.Err()}
+	for _, key := range sortedKeys(result.Failed) {
+		errs = append(errs, fmt.Errorf("%s: %w", key, result.Failed[key]))
+	}
+	return result, errors.Join(errs...)
+}
+
+// syncOne downloads a single capture into dir/key via a temporary file.
+func (s *StorageNamespace) syncOne(ctx context.Context, dir, key, url string) (int64, error) {
+	data, err := s.c.download(ctx, url)
+	if err != nil {
+		return 0, err
+	}
+	dst := filepath.Join(dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return 0, fmt.Errorf("snapapi: create dir for %q: %w", key, err)
+	}
+	tmp := dst + ".part"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return 0, fmt.Errorf("snapapi: write file %q: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return 0, fmt.Errorf("snapapi: rename %q: %w", tmp, err)
+	}
+	return int64(len(data)), nil
+}
+
+// syncUpToDate reports whether the local copy of key matches item.
+func syncUpToDate(dir, key string, item StorageItem, prev syncEntry) bool {
+	info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(key)))
+	if err != nil || info.IsDir() || info.Size() != item.Size {
+		return false
+	}
+	if prev == (syncEntry{}) || prev.Size != item.Size {
+		// A file with no manifest entry was not created by SyncToDir, or
+		// its sync crashed before the manifest was written. Download it
+		// again rather than claim it.
+		return false
+	}
+	if item.ETag != "" || prev.ETag != "" {
+		return item.ETag == prev.ETag
+	}
+	return item.CreatedAt == prev.CreatedAt
+}
+
+func readSyncManifest(dir string) (map[string]syncEntry, error) {
+	manifest := make(map[string]syncEntry)
+	data, err := os.ReadFile(filepath.Join(dir, syncManifestName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("snapapi: read sync manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("snapapi: decode sync manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+func writeSyncManifest(dir string, manifest map[string]syncEntry) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("snapapi: encode sync manifest: %w", err)
+	}
+	dst := filepath.Join(dir, syncManifestName)
+	if err := os.WriteFile(dst+".part", data, 0644); err != nil {
+		return fmt.Errorf("snapapi: write sync manifest: %w", err)
+	}
+	if err := os.Rename(dst+".part", dst); err != nil {
+		return fmt.Errorf("snapapi: write sync manifest: %w", err)
+	}
+	return nil
+}
+
+func hasKeyPrefix(key, prefix string) bool {
+	return strings.HasPrefix(key, cleanStorageKey(prefix))
+}
+
+func sortedKeys(m map[string]error) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ---------------------------------------------------------------------------
+// Retention
+// ---------------------------------------------------------------------------
+
+// RetentionPolicy describes which stored captures Storage.Prune removes.
+// Rules are applied in order (age, then count per prefix, then total size)
+// and a capture is removed if any rule selects it. Zero-valued rules are
+// disabled.
+//
+// Captures whose CreatedAt cannot be parsed are never pruned.
+type RetentionPolicy struct {
+	// Prefix limits the policy to keys with this prefix.
+	Prefix string
+	// MaxAge removes captures older than this.
+	MaxAge time.Duration
+	// KeepPerPrefix keeps only the newest N captures in each directory
+	// (the key up to its last "/").
+	KeepPerPrefix int
+	// MaxTotalSize removes the oldest remaining captures until the total size
+	// of the kept captures is at most this many bytes.
+	MaxTotalSize int64
+	// DryRun reports what would be removed without deleting anything.
+	DryRun bool
+	// Now is the reference time for MaxAge. Default: time.Now().
+	Now time.Time
+}
+
+// PrunedItem is a capture selected for removal by Storage.Prune.
+type PrunedItem struct {
+	StorageItem
+	// Reason names the rule that selected the capture: "max_age",
+	// "keep_per_prefix" or "max_total_size".
+	Reason string `json:"reason"`
+}
+
+// PruneReport is the result of Storage.Prune.
+type PruneReport struct {
+	// Removed lists the captures that were (or, in a dry run, would be) deleted,
+	// oldest first. Captures whose deletion failed are counted as kept.
+	Removed []PrunedItem `json:"removed"`
+	// Kept is the number of captures left in place.
+	Kept int `json:"kept"`
+	// BytesFreed is the total size of the removed captures.
+	BytesFreed int64 `json:"bytes_freed"`
+	// DryRun is true if nothing was actually deleted.
+	DryRun bool `json:"dry_run"`
+}
+
+// Prune deletes stored captures according to a retention policy. Use
+// DryRun to preview the result first.
+//
+//	report, err := client.Storage.Prune(ctx, snapapi.RetentionPolicy{
+//	    Prefix:        "monitoring/",
+//	    MaxAge:        30 * 24 * time.Hour,
+//	    KeepPerPrefix: 100,
+//	    DryRun:        true,
+//	})
+//	fmt.Printf("would free %d bytes\n", report.BytesFreed)
+func (s *StorageNamespace) Prune(ctx context.Context, p RetentionPolicy) (*PruneReport, error) {
+	items, err := s.listAll(ctx, p.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	now := p.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	type dated struct {
+		item    StorageItem
+		created time.Time
+	}
+	report := &PruneReport{DryRun: p.DryRun}
+	var candidates []dated
+	for _, item := range items {
+		created, err := time.Parse(time.RFC3339, item.CreatedAt)
+		if err != nil {
+			report.Kept++
+			continue
+		}
+		candidates = append(candidates, dated{item, created})
+	}
+	// Newest first, so "keep the first N" and "drop from the end" both work.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].created.After(candidates[j].created)
+	})
+
+	var kept, removed []dated
+	var reasons []string
+	perPrefix := make(map[string]int)
+	for _, d := range candidates {
+		switch {
+		case p.MaxAge > 0 && now.Sub(d.created) > p.MaxAge:
+			removed, reasons = append(removed, d), append(reasons, "max_age")
+		case p.KeepPerPrefix > 0 && perPrefix[path.Dir(d.item.Key)] >= p.KeepPerPrefix:
+			removed, reasons = append(removed, d), append(reasons, "keep_per_prefix")
+		default:
+			perPrefix[path.Dir(d.item.Key)]++
+			kept = append(kept, d)
+		}
+	}
+	if p.MaxTotalSize > 0 {
+		var total int64
+		for _, d := range kept {
+			total += d.item.Size
+		}
+		for total > p.MaxTotalSize && len(kept) > 0 {
+			oldest := kept[len(kept)-1]
+			kept = kept[:len(kept)-1]
+			total -= oldest.item.Size
+			removed, reasons = append(removed, oldest), append(reasons, "max_total_size")
+		}
+	}
+	report.Kept += len(kept)
+
+	order := make([]int, len(removed))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return removed[order[i]].created.Before(removed[order[j]].created)
+	})
+
+	var errs []error
+	for _, i := range order {
+		item := removed[i].item
+		if !p.DryRun {
+			if err := s.Delete(ctx, item.Key); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", item.Key, err))
+				report.Kept++
+				continue
+			}
+		}
+		report.Removed = append(report.Removed, PrunedItem{item, reasons[i]})
+		report.BytesFreed += item.Size
+	}
+	return report, errors.Join(errs...)
+}
@@ -0,0 +1,345 @@
+package snapapi
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Table is an HTML table flattened into a grid. Cells spanning several
+// columns or rows (colspan/rowspan) are repeated in every position they
+// cover, so each row has one value per column.
+type Table struct {
+	// Index is the position of the table among all <table> elements on the
+	// page, in document order.
+	Index int `json:"index"`
+	// ID is the table's id attribute.
+	ID string `json:"id,omitempty"`
+	// Caption is the text of the <caption> element.
+	Caption string `json:"caption,omitempty"`
+	// Headers holds one label per column, taken from the <thead> rows or,
+	// without a <thead>, from leading rows made only of <th> cells. Labels
+	// from several header rows are joined with " / " ("Price / Monthly").
+	// Headers is nil for tables without header rows.
+	Headers []string `json:"headers,omitempty"`
+	// Rows holds the body rows as whitespace-collapsed cell text.
+	Rows [][]string `json:"rows"`
+}
+
+// ScrapeTables scrapes the rendered HTML of p.URL and returns its tables.
+// When p.Selector is set it selects the tables locally instead of being sent
+// to the API: matching <table> elements, and the tables inside other
+// matching elements, are returned. p.Format is always "html".
+//
+//	tables, err := client.ScrapeTables(ctx, snapapi.ScrapeParams{
+//	    URL:      "https://example.com/pricing",
+//	    Selector: "table.pricing",
+//	})
+//	for _, row := range tables[0].Maps() {
+//	    fmt.Println(row["Plan"], row["Price / Monthly"])
+//	}
+//	tables[0].WriteCSV(os.Stdout)
+func (c *Client) ScrapeTables(ctx context.Context, p ScrapeParams) ([]Table, error) {
+	selector := p.Selector
+	p.Selector = ""
+	p.Format = "html"
+	if selector != "" {
+		if _, err := compileCSS(selector); err != nil {
+			return nil, &APIError{Code: ErrInvalidParams, Message: "invalid Selector: " + err.Error(), StatusCode: 400}
+		}
+	}
+	res, err := c.Scrape(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTables(res.Data, selector)
+}
+
+// ParseTables returns the tables of an HTML document, optionally limited to
+// those matched by selector as in ScrapeTables.
+func ParseTables(htmlStr, selector string) ([]Table, error) {
+	doc := parseHTML(htmlStr)
+	all := doc.findAll("table")
+	var selected []*htmlNode
+	if selector == "" {
+		selected = all
+	} else {
+		sel, err := compileCSS(selector)
+		if err != nil {
+			return nil, &APIError{Code: ErrInvalidParams, Message: "invalid selector: " + err.Error(), StatusCode: 400}
+		}
+		want := make(map[*htmlNode]bool)
+		for _, m := range querySelectorAll(doc, sel) {
+			if m.tag == "table" {
+				want[m] = true
+				continue
+			}
+			for _, t := range m.findAll("table") {
+				want[t] = true
+			}
+		}
+		for _, t := range all {
+			if want[t] {
+				selected = append(selected, t)
+			}
+		}
+	}
+
+	index := make(map[*htmlNode]int, len(all))
+	for i, t := range all {
+		index[t] = i
+	}
+	tables := make([]Table, 0, len(selected))
+	for _, t := range selected {
+		tables = append(tables, parseTable(t, index[t]))
+	}
+	return tables, nil
+}
+
+// maxColspan bounds colspan as browsers do.
+const maxColspan = 1000
+
+func parseTable(t *htmlNode, index int) Table {
+	table := Table{Index: index, ID: t.attr("id")}
+
+	// Collect the table's own rows, not those of nested tables.
+	var rows []*htmlNode
+	var headRows int
+	for _, c := range t.children {
+		switch c.tag {
+		case "caption":
+			if table.Caption == "" {
+				table.Caption = c.innerText()
+			}
+		case "tr":
+			rows = append(rows, c)
+		case "thead", "tbody", "tfoot":
+			for _, r := range c.children {
+				if r.tag == "tr" {
+					rows = append(rows, r)
+					if c.tag == "thead" {
+						headRows++
+					}
+				}
+			}
+		}
+	}
+	if headRows > 0 {
+		// A <thead> written after body rows is still the header.
+		rows = theadFirst(rows)
+	}
+
+	grid := buildGrid(rows)
+	if headRows == 0 {
+		// Without a <thead>, leading rows of <th> cells are headers.
+		for _, r := range rows {
+			if !allHeaderCells(r) {
+				break
+			}
+			headRows++
+		}
+		if headRows == len(rows) {
+			// A table made only of <th> rows has no body; keep it as data.
+			headRows = 0
+		}
+	}
+
+	if headRows > 0 {
+		table.Headers = make([]string, len(grid[0]))
+		for col := range table.Headers {
+			var parts []string
+			for r := 0; r < headRows; r++ {
+				v := grid[r][col]
+				if v != "" && (len(parts) == 0 || parts[len(parts)-1] != v) {
+					parts = append(parts, v)
+				}
+			}
+			table.Headers[col] = strings.Join(parts, " / ")
+		}
+	}
+	table.Rows = grid[headRows:]
+	if table.Rows == nil {
+		table.Rows = [][]string{}
+	}
+	return table
+}
+
+// theadFirst moves the <thead> rows to the front, keeping their order.
+func theadFirst(rows []*htmlNode) []*htmlNode {
+	out := make([]*htmlNode, 0, len(rows))
+	for _, r := range rows {
+		if r.parent.tag == "thead" {
+			out = append(out, r)
+		}
+	}
+	for _, r := range rows {
+		if r.parent.tag != "thead" {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func allHeaderCells(tr *htmlNode) bool {
+	n := 0
+	for _, c := range tr.children {
+		switch c.tag {
+		case "th":
+			n++
+		case "td":
+			return false
+		}
+	}
+	return n > 0
+}
+
+// buildGrid lays out the cells of rows, expanding colspan and rowspan, and
+// pads every row to the same width.
+func buildGrid(rows []*htmlNode) [][]string {
+	grid := make([][]string, len(rows))
+	filled := make([][]bool, len(rows))
+	set := func(r, c int, v string) {
+		for len(grid[r]) <= c {
+			grid[r] = append(grid[r], "")
+			filled[r] = append(filled[r], false)
+		}
+		grid[r][c] = v
+		filled[r][c] = true
+	}
+	for r, tr := range rows {
+		col := 0
+		for _, cell := range tr.children {
+			if cell.tag != "td" && cell.tag != "th" {
+				continue
+			}
+			for col < len(filled[r]) && filled[r][col] {
+				col++
+			}
+			colspan := spanAttr(cell, "colspan", maxColspan)
+			// Rows span only within their group (thead, tbody, tfoot).
+			groupEnd := r + 1
+			for groupEnd < len(rows) && rows[groupEnd].parent == tr.parent {
+				groupEnd++
+			}
+			rowspan := spanAttr(cell, "rowspan", groupEnd-r)
+			if cell.attr("rowspan") == "0" {
+				// rowspan="0" spans the rest of the group.
+				rowspan = groupEnd - r
+			}
+			text := cell.innerText()
+			for dr := 0; dr < rowspan; dr++ {
+				for dc := 0; dc < colspan; dc++ {
+					set(r+dr, col+dc, text)
+				}
+			}
+			col += colspan
+		}
+	}
+	width := 0
+	for _, row := range grid {
+		width = max(width, len(row))
+	}
+	for r := range grid {
+		for len(grid[r]) < width {
+			grid[r] = append(grid[r], "")
+		}
+	}
+	return grid
+}
+
+// spanAttr returns a colspan or rowspan value, clamped to [1, limit].
+func spanAttr(n *htmlNode, name string, limit int) int {
+	v, err := strconv.Atoi(strings.TrimSpace(n.attr(name)))
+	if err != nil || v < 1 {
+		return 1
+	}
+	return min(v, max(limit, 1))
+}
+
+// Keys returns the column names used by Maps and WriteJSONL: the headers,
+// with "column N" for empty ones and a " (N)" suffix on repeats.
+func (t Table) Keys() []string {
+	width := len(t.Headers)
+	for _, row := range t.Rows {
+		width = max(width, len(row))
+	}
+	keys := make([]string, width)
+	seen := make(map[string]int)
+	for i := range keys {
+		k := ""
+		if i < len(t.Headers) {
+			k = t.Headers[i]
+		}
+		if k == "" {
+			k = "column " + strconv.Itoa(i+1)
+		}
+		seen[k]++
+		if n := seen[k]; n > 1 {
+			k += " (" + strconv.Itoa(n) + ")"
+		}
+		keys[i] = k
+	}
+	return keys
+}
+
+// Maps returns the rows as maps from column name (see Keys) to cell text.
+func (t Table) Maps() []map[string]string {
+	keys := t.Keys()
+	out := make([]map[string]string, len(t.Rows))
+	for i, row := range t.Rows {
+		m := make(map[string]string, len(keys))
+		for j, k := range keys {
+			if j < len(row) {
+				m[k] = row[j]
+			} else {
+				m[k] = ""
+			}
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// Number parses the cell at row, col as a number, ignoring currency
+// symbols, units and thousands separators ("$1,299.00/mo" is 1299). It
+// reports false for cells without a number.
+func (t Table) Number(row, col int) (float64, bool) {
+	if row < 0 || row >= len(t.Rows) || col < 0 || col >= len(t.Rows[row]) {
+		return 0, false
+	}
+	s := cleanNumber(t.Rows[row][col])
+	if s == "" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+// WriteCSV writes the table as CSV, with the headers (if any) as the first
+// record.
+func (t Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if t.Headers != nil {
+		if err := cw.Write(t.Headers); err != nil {
+			return err
+		}
+	}
+	if err := cw.WriteAll(t.Rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// WriteJSONL writes one JSON object per row, keyed as in Maps.
+func (t Table) WriteJSONL(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, m := range t.Maps() {
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
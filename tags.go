@@ -0,0 +1,26 @@
+package snapapi
+
+import "context"
+
+// tagsCtx is the context key for caller tags.
+type tagsCtx struct{}
+
+// WithTag returns a context carrying tag in addition to any tags already
+// present. Tags identify the caller (a team, job or feature) and are used by
+// Budget rules to apply per-caller limits.
+//
+//	ctx = snapapi.WithTag(ctx, "team:seo")
+//	img, err := client.Screenshot(ctx, params)
+func WithTag(ctx context.Context, tag string) context.Context {
+	existing := TagsFromContext(ctx)
+	tags := make([]string, len(existing), len(existing)+1)
+	copy(tags, existing)
+	return context.WithValue(ctx, tagsCtx{}, append(tags, tag))
+}
+
+// TagsFromContext returns the tags attached to ctx with WithTag, in the
+// order they were added.
+func TagsFromContext(ctx context.Context) []string {
+	tags, _ := ctx.Value(tagsCtx{}).([]string)
+	return tags
+}
@@ -0,0 +1,160 @@
+package snapapi
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// UsageGranularity is the bucket size for GetUsageHistory.
+type UsageGranularity string
+
+// Supported usage history granularities.
+const (
+	UsageHourly  UsageGranularity = "hour"
+	UsageDaily   UsageGranularity = "day"
+	UsageWeekly  UsageGranularity = "week"
+	UsageMonthly UsageGranularity = "month"
+)
+
+// UsageRecord is one row of usage history: the number of billable requests
+// made with one API key to one endpoint during one period.
+type UsageRecord struct {
+	// Period is the ISO 8601 start of the bucket (e.g. "2026-03-23").
+	Period string `json:"period"`
+	// Endpoint is the billable endpoint: "screenshot", "pdf", "video",
+	// "scrape", "extract" or "analyze".
+	Endpoint string `json:"endpoint"`
+	// APIKeyID is the APIKey.ID the requests were made with.
+	APIKeyID string `json:"api_key_id,omitempty"`
+	// APIKeyName is the key's label at the time of the request, if known.
+	APIKeyName string `json:"api_key_name,omitempty"`
+	// Count is the number of requests.
+	Count int `json:"count"`
+}
+
+// UsageHistory is the response from GetUsageHistory.
+type UsageHistory struct {
+	// From and To echo the requested range (ISO 8601 dates).
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Granularity is the bucket size of Records.
+	Granularity UsageGranularity `json:"granularity"`
+	// Records holds one row per period, endpoint and API key.
+	Records []UsageRecord `json:"records"`
+}
+
+// GetUsageHistory returns billable usage between from and to (inclusive,
+// compared by UTC date), bucketed by granularity and broken down by endpoint
+// and API key. An empty granularity means UsageDaily.
+//
+//	hist, err := client.GetUsageHistory(ctx,
+//	    time.Now().AddDate(0, -1, 0), time.Now(), snapapi.UsageDaily)
+//	for endpoint, n := range hist.ByEndpoint() {
+//	    fmt.Println(endpoint, n)
+//	}
+func (c *Client) GetUsageHistory(ctx context.Context, from, to time.Time, granularity UsageGranularity) (*UsageHistory, error) {
+	if from.IsZero() || to.IsZero() {
+		return nil, &APIError{Code: ErrInvalidParams, Message: "from and to are required", StatusCode: 400}
+	}
+	if to.Before(from) {
+		return nil, &APIError{Code: ErrInvalidParams, Message: "to must not be before from", StatusCode: 400}
+	}
+	if granularity == "" {
+		granularity = UsageDaily
+	}
+	q := url.Values{}
+	q.Set("from", from.UTC().Format("2006-01-02"))
+	q.Set("to", to.UTC().Format("2006-01-02"))
+	q.Set("granularity", string(granularity))
+	var result UsageHistory
+	if err := c.doJSON(ctx, http.MethodGet, "/v1/usage/history?"+q.Encode(), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Total returns the total number of requests in the history.
+func (h *UsageHistory) Total() int {
+	total := 0
+	for _, r := range h.Records {
+		total += r.Count
+	}
+	return total
+}
+
+// ByEndpoint sums request counts per endpoint.
+func (h *UsageHistory) ByEndpoint() map[string]int {
+	out := make(map[string]int)
+	for _, r := range h.Records {
+		out[r.Endpoint] += r.Count
+	}
+	return out
+}
+
+// ByAPIKey sums request counts per APIKeyID, so costs can be attributed to
+// the team owning each key.
+func (h *UsageHistory) ByAPIKey() map[string]int {
+	out := make(map[string]int)
+	for _, r := range h.Records {
+		out[r.APIKeyID] += r.Count
+	}
+	return out
+}
+
+// ByPeriod sums request counts per period.
+func (h *UsageHistory) ByPeriod() map[string]int {
+	out := make(map[string]int)
+	for _, r := range h.Records {
+		out[r.Period] += r.Count
+	}
+	return out
+}
+
+// WriteCSV writes the records as CSV with a header row
+// (period,endpoint,api_key_id,api_key_name,count), sorted by period,
+// endpoint and key.
+func (h *UsageHistory) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"period", "endpoint", "api_key_id", "api_key_name", "count"}); err != nil {
+		return err
+	}
+	for _, r := range h.sortedRecords() {
+		if err := cw.Write([]string{r.Period, r.Endpoint, r.APIKeyID, r.APIKeyName, strconv.Itoa(r.Count)}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the history as indented JSON, with records sorted as in
+// WriteCSV.
+func (h *UsageHistory) WriteJSON(w io.Writer) error {
+	out := *h
+	out.Records = h.sortedRecords()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func (h *UsageHistory) sortedRecords() []UsageRecord {
+	records := append([]UsageRecord(nil), h.Records...)
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Period != b.Period {
+			return a.Period < b.Period
+		}
+		if a.Endpoint != b.Endpoint {
+			return a.Endpoint < b.Endpoint
+		}
+		return a.APIKeyID < b.APIKeyID
+	})
+	return records
+}
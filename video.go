@@ -52,5 +52,5 @@ func (c *Client) Video(ctx context.Context, p VideoParams) ([]byte, error) {
 	if p.URL == "" {
 		return nil, &APIError{Code: ErrInvalidParams, Message: "URL is required", StatusCode: 400}
 	}
-	return c.doRaw(ctx, http.MethodPost, "/v1/video", p)
+	return c.doBillable(ctx, "video", http.MethodPost, "/v1/video", p)
 }